@@ -0,0 +1,61 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+// Set at build time via -ldflags "-X github.com/makalin/pricetrek/internal/buildinfo.Version=..."
+var (
+	Version = "0.1.0"
+	Commit  = ""
+	Date    = ""
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build metadata, falling back to the VCS information
+// embedded by the Go toolchain when ldflags weren't set
+func Get() Info {
+	info := Info{
+		Version:   strings.TrimPrefix(Version, "v"),
+		Commit:    Commit,
+		Date:      Date,
+		GoVersion: runtime.Version(),
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = setting.Value
+				}
+			}
+		}
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.Date == "" {
+		info.Date = "unknown"
+	}
+	return info
+}
+
+// String returns the human readable version line
+func (i Info) String() string {
+	return "PriceTrek v" + i.Version
+}
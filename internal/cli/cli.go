@@ -1,22 +1,40 @@
 package cli
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log/slog"
+	"math"
 	"net/http"
 	"os"
+	"path/filepath"
+	"reflect"
 	"regexp"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/makalin/pricetrek/internal/buildinfo"
 	"github.com/makalin/pricetrek/internal/config"
 	"github.com/makalin/pricetrek/internal/csv"
+	"github.com/makalin/pricetrek/internal/fx"
+	"github.com/makalin/pricetrek/internal/httpclient"
 	"github.com/makalin/pricetrek/internal/logger"
+	"github.com/makalin/pricetrek/internal/notifications"
 	"github.com/makalin/pricetrek/internal/providers"
 	"github.com/makalin/pricetrek/internal/scheduler"
 	"github.com/makalin/pricetrek/internal/storage"
+	"github.com/makalin/pricetrek/internal/telemetry"
 	"github.com/makalin/pricetrek/internal/tools"
 	"github.com/makalin/pricetrek/internal/tracker"
 	"github.com/makalin/pricetrek/internal/utils"
@@ -27,15 +45,68 @@ type CLI struct {
 	logger *logger.Logger
 	storage storage.Storage
 	tracker *tracker.Tracker
+
+	configPath string
+	forceLock  bool
+	paths      config.Paths
+	// maxAge replaces the stale threshold for ls and show --max-age
+	maxAge time.Duration
+}
+
+// writeCommands modify the database and must hold the instance lock
+var writeCommands = map[string]bool{
+	"add":        true,
+	"edit":       true,
+	"clone":      true,
+	"rm":         true,
+	"remove":     true,
+	"track":      true,
+	"alert":      true,
+	"import":     true,
+	"compact":    true,
+	"clean-meta": true,
+	"restore":    true,
+	"sync":       true,
+	// Refreshing or re-fetching expired rates writes the fx_rates cache
+	"rates": true,
+}
+
+// schemaCommands are the commands that read or write the database schema;
+// they fail with a hint (or auto-initialize) on a fresh database
+var schemaCommands = map[string]bool{
+	"add": true, "edit": true, "clone": true, "rm": true, "remove": true, "ls": true, "list": true,
+	"show": true, "track": true, "alert": true, "export": true,
+	"import": true, "monitor": true, "total": true, "compact": true, "clean-meta": true, "events": true,
+	"sync": true, "verify-items": true, "rates": true, "stats": true, "status": true,
 }
 
 func New(cfg *config.Config, log *logger.Logger) *CLI {
+	if cfg != nil {
+		utils.SetCurrencyDecimals(cfg.Defaults.Decimals)
+	}
 	return &CLI{
 		config: cfg,
 		logger: log,
 	}
 }
 
+// SetConfigPath records the config file the CLI was started with
+func (c *CLI) SetConfigPath(path string) {
+	c.configPath = path
+}
+
+// SetPaths records the default config, database and backup locations
+// resolved from the data directory
+func (c *CLI) SetPaths(paths config.Paths) {
+	c.paths = paths
+}
+
+// SetForceLock makes write commands take over the instance lock even when
+// another process appears to hold it
+func (c *CLI) SetForceLock(force bool) {
+	c.forceLock = force
+}
+
 func (c *CLI) Execute(ctx context.Context, args []string) error {
 	command := args[0]
 	
@@ -43,7 +114,42 @@ func (c *CLI) Execute(ctx context.Context, args []string) error {
 	if command == "init" {
 		return c.handleInit(args[1:])
 	}
+	if command == "version" {
+		return c.handleVersion(args[1:])
+	}
+	if command == "config" {
+		return c.handleConfig(args[1:])
+	}
+	if command == "providers" {
+		return c.handleProviders(ctx, args[1:])
+	}
+
+	// Ad-hoc fetches and quick-track runs don't touch storage
+	if command == "fetch" {
+		return c.handleFetch(ctx, args[1:])
+	}
+	if isQuickTrack(args) {
+		fetchArgs, err := quickTrackArgs(args[1:])
+		if err != nil {
+			return err
+		}
+		return c.handleFetch(ctx, fetchArgs)
+	}
 	
+	// Guard against concurrent writers on the same database
+	if writeCommands[command] {
+		lock, err := tools.AcquireLock(c.config.Storage.Path+".lock", c.forceLock)
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+	}
+
+	// Restoring replaces the database files, so it must not hold them open
+	if command == "restore" {
+		return c.handleRestore(args[1:])
+	}
+
 	// Initialize storage
 	var err error
 	c.storage, err = storage.New(c.config.Storage)
@@ -52,18 +158,39 @@ func (c *CLI) Execute(ctx context.Context, args []string) error {
 	}
 	defer c.storage.Close()
 
+	// SQL dumps carry their own schema, so they can load into a fresh database
+	if schemaCommands[command] && !isSQLImport(args) {
+		if err := c.ensureInitialized(ctx); err != nil {
+			return err
+		}
+	}
+
 	// Initialize tracker
 	c.tracker = tracker.New(c.config, c.storage, c.logger)
 
 	switch command {
 	case "add":
 		return c.handleAdd(args[1:])
+	case "edit":
+		return c.handleEdit(args[1:])
+	case "clone":
+		return c.handleClone(args[1:])
 	case "rm", "remove":
 		return c.handleRemove(args[1:])
 	case "ls", "list":
 		return c.handleList(args[1:])
 	case "show":
 		return c.handleShow(args[1:])
+	case "total":
+		return c.handleTotal(ctx, args[1:])
+	case "stats":
+		return c.handleStats(ctx, args[1:])
+	case "status":
+		return c.handleStatus(ctx, args[1:])
+	case "rates":
+		return c.handleRates(ctx, args[1:])
+	case "estimate":
+		return c.handleEstimate(ctx, args[1:])
 	case "track":
 		return c.handleTrack(ctx, args[1:])
 	case "alert":
@@ -72,16 +199,24 @@ func (c *CLI) Execute(ctx context.Context, args []string) error {
 		return c.handleExport(args[1:])
 	case "import":
 		return c.handleImport(args[1:])
+	case "sync":
+		return c.handleSync(ctx, args[1:])
+	case "compact":
+		return c.handleCompact(ctx, args[1:])
+	case "clean-meta":
+		return c.handleCleanMeta(ctx, args[1:])
+	case "events":
+		return c.handleEvents(ctx, args[1:])
 	case "doctor":
-		return c.handleDoctor(args[1:])
+		return c.handleDoctor(ctx, args[1:])
+	case "verify-items":
+		return c.handleVerifyItems(ctx, args[1:])
 	case "schedule":
 		return c.handleSchedule(args[1:])
 	case "backup":
 		return c.handleBackup(args[1:])
-	case "restore":
-		return c.handleRestore(args[1:])
 	case "monitor":
-		return c.handleMonitor(args[1:])
+		return c.handleMonitor(ctx, args[1:])
 	case "help", "-h", "--help":
 		c.Help()
 		return nil
@@ -90,6 +225,226 @@ func (c *CLI) Execute(ctx context.Context, args []string) error {
 	}
 }
 
+// handleRates shows the exchange rates conversions use, optionally
+// refreshing the cached copy of fx.rates_url first
+func (c *CLI) handleRates(ctx context.Context, args []string) error {
+	var (
+		refresh  = flag.Bool("refresh", false, "Fetch fx.rates_url now instead of using the cached rates")
+		jsonFlag = flag.Bool("json", false, "Output in JSON format")
+	)
+	// Listing is what rates does; --list is accepted for clarity
+	flag.Bool("list", false, "List the current rates (the default)")
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
+	if *refresh && c.config.FX.RatesURL == "" {
+		return fmt.Errorf("fx.rates_url is not configured; nothing to refresh")
+	}
+
+	rates, err := fx.LoadCached(ctx, c.config.FX, c.storage, *refresh)
+	if err != nil {
+		return fmt.Errorf("failed to load exchange rates: %w", err)
+	}
+	if *refresh && rates.Stale {
+		return fmt.Errorf("failed to refresh exchange rates from %s; cached rates are from %s", c.config.FX.RatesURL, rates.Fetched.Format(time.RFC3339))
+	}
+	if rates.Stale {
+		c.logger.Warn("Refreshing exchange rates failed, showing stale cached rates", "fetched", rates.Fetched.Format(time.RFC3339))
+	} else if *refresh {
+		c.logger.Info("Exchange rates refreshed", "source", c.config.FX.RatesURL, "currencies", len(rates.Rates))
+	}
+
+	if *jsonFlag {
+		jsonData, err := json.MarshalIndent(rates, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	source := "fx.rates"
+	if c.config.FX.RatesURL != "" {
+		source = c.config.FX.RatesURL
+	}
+	fmt.Printf("Base: %s\n", rates.Base)
+	fmt.Printf("Source: %s\n", source)
+	if !rates.Fetched.IsZero() {
+		age := time.Since(rates.Fetched).Round(time.Minute)
+		note := ""
+		if rates.Stale {
+			note = " (stale)"
+		}
+		fmt.Printf("Fetched: %s (%s ago)%s\n", rates.Fetched.Format(time.RFC3339), age, note)
+	}
+	fmt.Println()
+
+	currencies := make([]string, 0, len(rates.Rates))
+	for currency := range rates.Rates {
+		currencies = append(currencies, currency)
+	}
+	sort.Strings(currencies)
+	for _, currency := range currencies {
+		fmt.Printf("  %-4s %s\n", currency, strconv.FormatFloat(rates.Rates[currency], 'f', -1, 64))
+	}
+	return nil
+}
+
+// handleEstimate reports how many requests per day the tracking loop would
+// send to each host, from the item schedules alone
+func (c *CLI) handleEstimate(ctx context.Context, args []string) error {
+	var (
+		interval = flag.Duration("interval", 1*time.Hour, "Loop interval (default: the shortest item schedule, else 1h, as for track --loop)")
+		limit    = flag.Int("max-per-host", c.config.Defaults.MaxHostRequests, "Flag hosts above this many requests per day")
+		jsonFlag = flag.Bool("json", false, "Output in JSON format")
+	)
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
+	if *interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+
+	// Tick like track --loop without --force: the shortest item schedule,
+	// never below min_interval
+	intervalSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "interval" {
+			intervalSet = true
+		}
+	})
+	tick := *interval
+	if shortest := c.tracker.ShortestInterval(ctx); !intervalSet && shortest > 0 {
+		tick = max(shortest, c.config.Defaults.MinInterval)
+	}
+
+	estimates, err := c.tracker.Estimate(ctx, tick, float64(*limit))
+	if err != nil {
+		return fmt.Errorf("failed to estimate requests: %w", err)
+	}
+
+	var total float64
+	over := 0
+	for _, estimate := range estimates {
+		total += estimate.RequestsPerDay
+		if estimate.OverLimit {
+			over++
+		}
+	}
+
+	if *jsonFlag {
+		jsonData, err := json.MarshalIndent(map[string]interface{}{
+			"interval":         tick.String(),
+			"max_per_host":     *limit,
+			"requests_per_day": total,
+			"hosts":            estimates,
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	if len(estimates) == 0 {
+		c.logger.Info("No items fetch over HTTP")
+		return nil
+	}
+
+	fmt.Printf("Loop interval: %s, limit: %d requests/day per host\n\n", tick, *limit)
+	fmt.Printf("%-32s %6s %12s\n", "HOST", "ITEMS", "REQUESTS/DAY")
+	for _, estimate := range estimates {
+		line := fmt.Sprintf("%-32s %6d %12.1f", estimate.Host, len(estimate.Items), estimate.RequestsPerDay)
+		if estimate.OverLimit {
+			line = utils.Colorize(utils.ColorRed, line+"  over limit")
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("\nTotal: %.1f requests/day to %d hosts (retries not counted)\n", total, len(estimates))
+
+	if over > 0 {
+		c.logger.Warn("Some hosts exceed the politeness limit; lengthen their item schedules to lower the risk of getting blocked",
+			"hosts", over, "max_per_host", *limit)
+	}
+	return nil
+}
+
+// ensureInitialized returns storage.ErrNotInitialized for a database that
+// hasn't been through init, or creates the schema when storage.auto_init is set
+func (c *CLI) ensureInitialized(ctx context.Context) error {
+	initialized, err := c.storage.Initialized(ctx)
+	if err != nil {
+		return err
+	}
+	if initialized {
+		return nil
+	}
+	if !c.config.Storage.AutoInit {
+		return storage.ErrNotInitialized
+	}
+
+	c.logger.Info("Initializing database", "path", c.config.Storage.Path)
+	if err := c.storage.Init(); err != nil {
+		return fmt.Errorf("failed to initialize database: %w", err)
+	}
+	return nil
+}
+
+// RequiresConfig reports whether the command line needs a configuration
+// file; fetch and quick-track (track --url) fall back to built-in defaults
+func RequiresConfig(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	return args[0] != "fetch" && args[0] != "config" && args[0] != "providers" && !isQuickTrack(args)
+}
+
+// isQuickTrack reports whether args is a config-free "track --url ..." run
+func isQuickTrack(args []string) bool {
+	if len(args) == 0 || args[0] != "track" {
+		return false
+	}
+	for _, arg := range args[1:] {
+		if arg == "--url" || arg == "-url" || strings.HasPrefix(arg, "--url=") || strings.HasPrefix(arg, "-url=") {
+			return true
+		}
+	}
+	return false
+}
+
+// quickTrackArgs returns the fetch flags of a quick-track run. It already
+// fetches once, so --once is dropped; --loop needs a config to track.
+func quickTrackArgs(args []string) ([]string, error) {
+	var fetchArgs []string
+	for _, arg := range args {
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		switch {
+		case !strings.HasPrefix(arg, "-"):
+		case name == "once":
+			continue
+		case name == "loop":
+			return nil, fmt.Errorf("track --url fetches once; --loop needs items in a config")
+		}
+		fetchArgs = append(fetchArgs, arg)
+	}
+	return fetchArgs, nil
+}
+
+// isSQLImport reports whether args is an "import --sql ..." run
+func isSQLImport(args []string) bool {
+	if len(args) == 0 || args[0] != "import" {
+		return false
+	}
+	for _, arg := range args[1:] {
+		if arg == "--sql" || arg == "-sql" || strings.HasPrefix(arg, "--sql=") || strings.HasPrefix(arg, "-sql=") {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *CLI) Help() {
 	fmt.Fprintf(os.Stderr, `PriceTrek - A tiny, fast terminal agent to track product prices
 
@@ -97,25 +452,59 @@ USAGE:
     pricetrek <command> [options]
 
 COMMANDS:
-    init                       Scaffold config & DB
+    init [--quiet] [--force]   Scaffold config & DB (--force recreates them, keeping .bak)
     add --name --url ...       Add a product (or use --from yaml/csv)
+    edit <id> --note ...       Change fields of an item (only the flags given)
+    clone <id> --url ...       Copy an item's settings to a new item (no price history)
     rm <id>                    Remove item
-    ls [--json]                List watchlist
-    show <id> [--spark]        Price history with sparkline
-    track [--once|--loop]      Run trackers (respects per-item schedule)
-    alert --dry-run            Re-evaluate rules & send alerts
-    export --csv out.csv       Dump history
-    import --csv in.csv        Import items
+    ls [--json]                List watchlist (--max-age 24h hides items without a newer sample)
+    ls --compact [--no-meta]   Single-line JSON without empty fields (also show; --no-meta drops meta)
+    status [--oneline]         Below-target, stale and failing counts and the last run (exit 1 if stale/failing)
+    show <id> [--spark]        Price history with sparkline (--spark-width N, --ascii, --compare-to 30d, --all)
+    show <id> --calendar       Month-by-day calendar of daily price changes
+    show <id> --max-age 24h    Warn when the latest sample is older than 24h
+    total [--currency USD]     Watchlist value converted to one currency
+    stats --global             Volatility leaderboard over the last 30d (--window 7d, --top N, or stats <id>)
+    rates [--refresh]          List exchange rates (--refresh refetches fx.rates_url into the cache)
+    track [--once|--loop]      Run trackers (--json: per-item JSON lines; --loop fetches items as their schedule comes due)
+    track --retry-failed       Retry failed items once at the end of the run (config: defaults.retry.final_pass)
+    track --export-on-track f  Rewrite a CSV or .ndjson export after each run (config: export.on_track)
+    estimate [--json]          Requests per day each host would get from the item schedules (no network)
+    alert [--dry-run]          Re-evaluate rules & send alerts (items inside their active hours; --test msg)
+    fetch --url --selector     Test extraction against a URL (no config needed)
+    fetch --url --try a,b,c    Report which candidate selectors match
+    providers test [name]      Run providers against bundled fixtures on a local server (offline)
+    track --url --selector     Quick-track: fetch once and print, no config or DB
+    export --csv out.csv       Dump history (--prices --group-by daily|weekly for one row per period)
+    import --csv in.csv        Import items (--dry-run to preview changes)
+    export --sql dump.sql      Portable SQL dump (replay with import --sql)
+    import --merge-history f   Merge another machine's SQL dump or database: add new items and missing prices
+    export --ndjson out.ndjson Stream price history as JSON lines (--id to filter, - for stdout)
+    export --meta-fields a,b   Add meta_<key> columns to a --prices or --ndjson export
+    sync [--prune]             Make the DB items match the config (--dry-run)
+    compact --older-than 90d   Downsample old history (--to daily|weekly)
+    clean-meta --keys a,b      Keep only these meta keys on stored prices (--all strips all but compaction summaries)
+    events [--id] [--since 7d] Audit log of fetches and alerts (storage.events)
     doctor                     Env & provider health check
+    verify-items [--json]      Fetch each item once, flag broken selectors (no writes)
     schedule --hourly|--daily  Print OS-specific scheduler instructions
-    backup --output file       Create backup
-    restore --file backup      Restore backup
-    monitor [--once]           System monitoring
+    backup [--full]            Back up changed files since the last backup (--output file for a full copy)
+    restore --file backup      Restore backup (--dry-run to preview, --force to overwrite)
+    restore --latest           Restore the newest backup (--verify to check it reads back first)
+    monitor [--once] [--json]  System monitoring (--prometheus for text exposition)
+    config schema              Print a JSON Schema for pricetrek.yaml
+    config migrate [--dry-run] Upgrade an older config file to the current format
+    version [--json]           Show version and build information
     help                       Show this help message
 
 OPTIONS:
-    --config string    Path to configuration file (default "pricetrek.yaml")
-    --verbose          Enable verbose logging
+    --config string    Path to configuration file (default: pricetrek.yaml in the data directory)
+    --data-dir string  Data directory (default $PRICETREK_DATA_DIR; on Linux the XDG dirs unless ./pricetrek.yaml exists)
+    --verbose          Enable verbose logging, with DNS/connect/TLS/TTFB timings per request
+    --debug-http       Log HTTP requests/responses (cookies & auth redacted)
+    --debug-http-dump  Directory to dump HTTP response bodies into
+    --force-lock       Run write commands even if another instance holds the lock
+    --no-color         Disable colored output (also NO_COLOR; off when stdout is not a terminal)
     --version          Show version information
 
 EXAMPLES:
@@ -131,6 +520,9 @@ EXAMPLES:
     # Show price history with sparkline
     pricetrek show 990pro-2tb --spark
 
+    # Test a selector before adding an item
+    pricetrek fetch --url "https://example.com" --selector ".price .value"
+
     # Export data
     pricetrek export --csv history.csv
 
@@ -145,20 +537,71 @@ For more information, visit: https://github.com/makalin/pricetrek
 }
 
 func (c *CLI) handleInit(args []string) error {
+	var (
+		quiet = flag.Bool("quiet", false, "Don't print the next-steps summary")
+		force = flag.Bool("force", false, "Recreate the default config and database, keeping the old ones as .bak")
+		yes   = flag.Bool("yes", false, "With --force, reset a database that holds items without asking")
+	)
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
 	c.logger.Info("Initializing PriceTrek workspace")
 	
 	// Create data directory
-	if err := os.MkdirAll("data", 0755); err != nil {
+	if err := os.MkdirAll(c.paths.DataDir, 0755); err != nil {
 		return fmt.Errorf("failed to create data directory: %w", err)
 	}
 
-	// Create default config if it doesn't exist
-	configPath := "pricetrek.yaml"
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+	configPath := c.paths.Config
+	dbPath := c.paths.DB
+
+	// A populated database is only reset after confirmation, before
+	// anything else is touched; an empty one just gets its tables recreated
+	resetDB := false
+	dbBackup := asideName(dbPath)
+	if *force {
+		items, err := countStoredItems(dbPath)
+		if err != nil {
+			return err
+		}
+		resetDB = items > 0
+		if resetDB && !*yes {
+			fmt.Printf("Reset %s holding %d item(s) and their price history? It is kept as %s (y/N): ", dbPath, items, dbBackup)
+			var response string
+			fmt.Scanln(&response)
+
+			if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+				c.logger.Info("Init cancelled, nothing changed")
+				return nil
+			}
+		}
+
+		// A running tracker must not lose its database mid-run
+		lock, err := tools.AcquireLock(dbPath+".lock", c.forceLock)
+		if err != nil {
+			return err
+		}
+		defer lock.Release()
+	}
+
+	// Create default config if it doesn't exist, or replace it with --force
+	createdConfig := false
+	_, statErr := os.Stat(configPath)
+	if statErr == nil && *force {
+		configBackup := asideName(configPath)
+		if err := os.Rename(configPath, configBackup); err != nil {
+			return fmt.Errorf("failed to back up config: %w", err)
+		}
+		c.logger.Info("Backed up existing configuration", "file", configBackup)
+		statErr = os.ErrNotExist
+	}
+	if os.IsNotExist(statErr) {
 		defaultConfig := &config.Config{
+			Version: config.CurrentVersion,
 			Storage: config.StorageConfig{
 				Driver: "sqlite",
-				Path:   "./data/trek.db",
+				Path:   dbPath,
 			},
 			Defaults: config.DefaultsConfig{
 				Currency:  "USD",
@@ -166,11 +609,11 @@ func (c *CLI) handleInit(args []string) error {
 				UserAgent: "PriceTrek/0.1 (+https://github.com/makalin/pricetrek)",
 				Retry: config.RetryConfig{
 					Attempts:  3,
-					BaseDelay: 800,
-					MaxDelay:  7000,
+					BaseDelay: 800 * time.Millisecond,
+					MaxDelay:  7 * time.Second,
 				},
-				HTTPTimeout: 20,
-				CacheTTL:    30,
+				HTTPTimeout: 20 * time.Second,
+				CacheTTL:    30 * time.Minute,
 				Headless: config.HeadlessConfig{
 					Enabled:   false,
 					WaitUntil: "networkidle",
@@ -186,12 +629,20 @@ func (c *CLI) handleInit(args []string) error {
 			return fmt.Errorf("failed to create default config: %w", err)
 		}
 		c.logger.Info("Created default configuration", "file", configPath)
+		createdConfig = true
+	}
+
+	if resetDB {
+		if err := moveAside(dbPath, dbBackup); err != nil {
+			return fmt.Errorf("failed to back up database: %w", err)
+		}
+		c.logger.Info("Backed up existing database", "file", dbBackup)
 	}
 
 	// Initialize storage with default config
 	storageConfig := config.StorageConfig{
 		Driver: "sqlite",
-		Path:   "./data/trek.db",
+		Path:   dbPath,
 	}
 	
 	storage, err := storage.New(storageConfig)
@@ -205,117 +656,426 @@ func (c *CLI) handleInit(args []string) error {
 	}
 
 	c.logger.Info("PriceTrek workspace initialized successfully")
+	if !*quiet {
+		printInitSummary(configPath, createdConfig, storageConfig.Path)
+	}
 	return nil
 }
 
-func (c *CLI) handleAdd(args []string) error {
-	var (
-		name     = flag.String("name", "", "Product name")
-		url      = flag.String("url", "", "Product URL")
-		provider = flag.String("provider", "generic", "Provider type (generic, exec)")
-		selector = flag.String("selector", "", "CSS selector for price extraction")
-		currency = flag.String("currency", "", "Currency code (USD, EUR, TRY, etc.)")
-		target   = flag.Float64("target", 0, "Target price")
-		percent  = flag.Float64("percent", 0, "Percent drop threshold")
-		schedule = flag.String("schedule", "hourly", "Schedule (hourly, daily, cron)")
-		regex    = flag.String("regex", "", "Regex pattern for price cleanup")
-		attr     = flag.String("attr", "", "Attribute to extract (text, content, data-price)")
-		command  = flag.String("command", "", "Command for exec provider")
-		fromFile = flag.String("from", "", "Import from file (yaml, csv)")
-		jsonFlag = flag.Bool("json", false, "Output in JSON format")
-	)
-
-	// Parse flags
-	flag.CommandLine.Parse(args)
-
-	// Handle import from file
-	if *fromFile != "" {
-		return c.handleImportFromFile(*fromFile, *jsonFlag)
+// countStoredItems returns the number of items in the database at path, or
+// zero when there is no initialized database there
+func countStoredItems(path string) (int, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return 0, nil
 	}
 
-	// Validate required fields
-	if *name == "" {
-		return fmt.Errorf("name is required")
+	store, err := storage.New(config.StorageConfig{Driver: "sqlite", Path: path})
+	if err != nil {
+		return 0, fmt.Errorf("failed to open database: %w", err)
 	}
-	if *url == "" {
-		return fmt.Errorf("url is required")
+	defer store.Close()
+
+	ctx := context.Background()
+	initialized, err := store.Initialized(ctx)
+	if err != nil || !initialized {
+		return 0, err
 	}
-	if *provider == "generic" && *selector == "" {
-		return fmt.Errorf("selector is required for generic provider")
+	items, err := store.GetItems(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get items: %w", err)
 	}
-	if *provider == "exec" && *command == "" {
-		return fmt.Errorf("command is required for exec provider")
+	return len(items), nil
+}
+
+// asideName returns where init --force keeps a replaced file: path.bak, or
+// a timestamped name when an earlier backup already has it
+func asideName(path string) string {
+	name := path + ".bak"
+	if _, err := os.Stat(name); err == nil {
+		name = path + ".bak-" + time.Now().Format("20060102-150405")
 	}
+	return name
+}
 
-	// Use defaults from config
-	if *currency == "" {
-		*currency = c.config.Defaults.Currency
+// moveAside renames a SQLite database and its WAL files to backup, refusing
+// to replace an existing database there
+func moveAside(path, backup string) error {
+	if _, err := os.Stat(backup); err == nil {
+		return fmt.Errorf("%s already exists", backup)
 	}
-	if *percent == 0 {
-		*percent = c.config.Rules.PercentDrop
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		// A stale WAL next to the backup would be replayed into it
+		if suffix != "" {
+			if err := os.Remove(backup + suffix); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+		}
+		err := os.Rename(path+suffix, backup+suffix)
+		if err != nil && !(suffix != "" && os.IsNotExist(err)) {
+			return err
+		}
 	}
+	return nil
+}
 
-	// Create item
-	item := storage.Item{
-		ID:          c.generateItemID(*name),
-		Name:        *name,
-		URL:         *url,
-		Provider:    *provider,
-		Selector:    *selector,
-		Currency:    *currency,
-		Schedule:    *schedule,
-		Regex:       *regex,
-		Attr:        *attr,
-		Command:     *command,
+// printInitSummary tells a new user where things live and what to run next
+func printInitSummary(configPath string, createdConfig bool, dbPath string) {
+	configState := "created"
+	if !createdConfig {
+		configState = "existing, left unchanged"
 	}
-
-	if *target > 0 {
-		item.TargetPrice = target
+	if abs, err := filepath.Abs(configPath); err == nil {
+		configPath = abs
 	}
-	if *percent > 0 {
-		item.PercentDrop = percent
+	if abs, err := filepath.Abs(dbPath); err == nil {
+		dbPath = abs
 	}
 
-	// Save item
-	ctx := context.Background()
-	if err := c.storage.SaveItem(ctx, item); err != nil {
-		return fmt.Errorf("failed to save item: %w", err)
+	fmt.Printf(`
+PriceTrek is ready.
+
+  Config:   %s (%s)
+  Database: %s
+
+Next steps:
+  1. Find a selector:  pricetrek fetch --url "https://..." --selector ".price"
+  2. Add the item:     pricetrek add --name "My product" --url "https://..." --selector ".price" --target 99
+  3. Track it:         pricetrek track --once   (then pricetrek show <id>)
+  4. Get alerts:       enable a channel under notifications in the config and
+                       put its secrets in the environment: PRICETREK_TELEGRAM_TOKEN,
+                       PRICETREK_SLACK_WEBHOOK, PRICETREK_NTFY_URL or
+                       PRICETREK_EMAIL_SMTP, _PORT, _USER, _PASS
+  5. Schedule it:      pricetrek schedule --hourly
+
+Run "pricetrek doctor" to check the setup.
+`, configPath, configState, dbPath)
+}
+
+func (c *CLI) handleConfig(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("config subcommand is required (schema, migrate)")
 	}
 
-	if *jsonFlag {
-		// Output JSON
-		jsonData, err := json.MarshalIndent(item, "", "  ")
+	switch args[0] {
+	case "schema":
+		jsonData, err := json.MarshalIndent(config.Schema(), "", "  ")
 		if err != nil {
 			return fmt.Errorf("failed to marshal JSON: %w", err)
 		}
 		fmt.Println(string(jsonData))
-	} else {
-		c.logger.Info("Item added successfully", 
-			"id", item.ID, 
-			"name", item.Name,
-			"url", item.URL,
-			"provider", item.Provider,
-		)
+		return nil
+	case "migrate":
+		return c.handleConfigMigrate(args[1:])
+	default:
+		return fmt.Errorf("unknown config subcommand: %s", args[0])
 	}
-
-	return nil
 }
 
-func (c *CLI) generateItemID(name string) string {
-	// Simple ID generation based on name
-	id := strings.ToLower(name)
-	id = strings.ReplaceAll(id, " ", "-")
-	id = strings.ReplaceAll(id, "_", "-")
-	
-	// Remove special characters
-	reg := regexp.MustCompile(`[^a-z0-9-]`)
-	id = reg.ReplaceAllString(id, "")
-	
-	// Ensure it's not empty
-	if id == "" {
-		id = "item"
-	}
-	
+func (c *CLI) handleConfigMigrate(args []string) error {
+	var (
+		file   = flag.String("file", c.configPath, "Config file to migrate")
+		dryRun = flag.Bool("dry-run", false, "Show the changes without writing the file")
+	)
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
+	data, err := os.ReadFile(*file)
+	if err != nil {
+		return fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	migrated, changes, err := config.Migrate(data)
+	if err != nil {
+		return err
+	}
+
+	diff := utils.LineDiff(string(data), string(migrated))
+	if diff == "" {
+		c.logger.Info("Config is up to date", "file", *file, "version", config.CurrentVersion)
+		return nil
+	}
+
+	if *dryRun {
+		for _, change := range changes {
+			fmt.Printf("* %s\n", change)
+		}
+		if len(changes) > 0 {
+			fmt.Println()
+		}
+		fmt.Print(diff)
+		return nil
+	}
+
+	// Keep the original next to the rewritten file
+	backup := *file + ".bak"
+	if err := os.WriteFile(backup, data, 0644); err != nil {
+		return fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := os.WriteFile(*file, migrated, 0644); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	c.logger.Info("Config migrated",
+		"file", *file,
+		"version", config.CurrentVersion,
+		"changes", len(changes),
+		"backup", backup,
+	)
+	return nil
+}
+
+func (c *CLI) handleProviders(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("providers subcommand is required (test)")
+	}
+
+	switch args[0] {
+	case "test":
+		return c.handleProvidersTest(ctx, args[1:])
+	default:
+		return fmt.Errorf("unknown providers subcommand: %s", args[0])
+	}
+}
+
+// handleProvidersTest checks providers against their bundled fixtures. With
+// no name it runs every provider, skipping headless unless it is enabled.
+func (c *CLI) handleProvidersTest(ctx context.Context, args []string) error {
+	jsonFlag := flag.Bool("json", false, "Output in JSON format")
+
+	// Parse flags
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		flag.CommandLine.Parse(args[1:])
+		args = args[:1]
+	} else {
+		flag.CommandLine.Parse(args)
+		args = flag.Args()
+	}
+
+	names := args
+	if len(names) == 0 {
+		for _, name := range tracker.FixtureProviders() {
+			if name == "headless" && !c.config.Defaults.Headless.Enabled {
+				c.logger.Debug("Skipping headless provider test: headless.enabled is off")
+				continue
+			}
+			names = append(names, name)
+		}
+	}
+
+	// Shares the tracker's fetch path but never touches storage
+	t := tracker.New(c.config, nil, c.logger)
+
+	results := make([]*tracker.ProviderTest, 0, len(names))
+	failed := 0
+	for _, name := range names {
+		result, err := t.TestProvider(ctx, name)
+		if err != nil {
+			return err
+		}
+		if !result.Passed {
+			failed++
+		}
+		results = append(results, result)
+	}
+
+	if *jsonFlag {
+		jsonData, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		fmt.Printf("%-10s %-14s %-6s %s\n", "Provider", "Fixture", "Result", "Detail")
+		fmt.Println(strings.Repeat("-", 80))
+		for _, result := range results {
+			status := utils.Colorize(utils.ColorGreen, fmt.Sprintf("%-6s", "ok"))
+			detail := fmt.Sprintf("%s in %dms", utils.FormatPrice(result.Price, result.Currency), result.DurationMS)
+			if !result.Passed {
+				status = utils.Colorize(utils.ColorRed, fmt.Sprintf("%-6s", "failed"))
+				detail = result.Error
+			}
+			fmt.Printf("%-10s %-14s %s %s\n", result.Provider, result.Fixture, status, detail)
+		}
+		fmt.Printf("\n%d of %d providers passed\n", len(results)-failed, len(results))
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d provider(s) failed their fixture test", failed)
+	}
+	return nil
+}
+
+func (c *CLI) handleVersion(args []string) error {
+	jsonFlag := flag.Bool("json", false, "Output in JSON format")
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
+	info := buildinfo.Get()
+	if *jsonFlag {
+		jsonData, err := json.MarshalIndent(info, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	fmt.Println(info)
+	fmt.Printf("Commit: %s\n", info.Commit)
+	fmt.Printf("Built: %s\n", info.Date)
+	fmt.Printf("Go: %s\n", info.GoVersion)
+	return nil
+}
+
+func (c *CLI) handleAdd(args []string) error {
+	var (
+		name     = flag.String("name", "", "Product name")
+		url      = flag.String("url", "", "Product URL")
+		provider = flag.String("provider", "generic", "Provider type (generic, exec)")
+		chain    = flag.String("providers", "", "Comma-separated providers to try in order, e.g. json,generic,headless")
+		selector = flag.String("selector", "", "CSS selector for price extraction")
+		currency = flag.String("currency", "", "Currency code (USD, EUR, TRY, etc.)")
+		target   = flag.Float64("target", 0, "Target price")
+		percent  = flag.Float64("percent", 0, "Percent drop threshold")
+		schedule = flag.String("schedule", "hourly", "Schedule (hourly, daily, cron)")
+		regex    = flag.String("regex", "", "Regex pattern for price cleanup")
+		attr     = flag.String("attr", "", "Attribute to extract (text, content, data-price)")
+		command  = flag.String("command", "", "Command for exec provider")
+		language = flag.String("accept-language", "", "Accept-Language header to send (e.g. de-DE)")
+		timeout  = flag.Duration("http-timeout", 0, "HTTP timeout for this item (default: defaults.http_timeout_sec)")
+		note     = flag.String("note", "", "Free-form note shown in show and ls --verbose")
+		fetchKey = flag.String("fetch-key", "", "Share one page fetch per run with items using the same key")
+		insecure = flag.Bool("tls-insecure", false, "Skip TLS certificate verification for this item")
+		caFile   = flag.String("tls-ca-file", "", "PEM CA bundle to trust for this item")
+		active   = flag.String("active-hours", "", "Only track during this daily window, e.g. 09:00-22:00")
+		confirm  = flag.Int("confirm-runs", 0, "Consecutive samples that must show a drop before alerting (default: rules.confirm_runs)")
+		fromFile = flag.String("from", "", "Import from file (yaml, csv)")
+		jsonFlag = flag.Bool("json", false, "Output in JSON format")
+
+		allowUnknown = flag.Bool("allow-unknown-currency", false, "Accept a currency code outside ISO 4217 (crypto, custom units)")
+	)
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
+	// Handle import from file
+	if *fromFile != "" {
+		return c.handleImportFromFile(*fromFile, *jsonFlag)
+	}
+
+	// Validate required fields
+	if *name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if *url == "" {
+		return fmt.Errorf("url is required")
+	}
+	providerChain := parseProviders(*chain)
+	if err := validateProviders(providerChain); err != nil {
+		return err
+	}
+	if len(providerChain) > 0 {
+		*provider = providerChain[0]
+	}
+	if (*provider == "generic" || slices.Contains(providerChain, "generic")) && *selector == "" {
+		return fmt.Errorf("selector is required for generic provider")
+	}
+	if (*provider == "exec" || slices.Contains(providerChain, "exec")) && *command == "" {
+		return fmt.Errorf("command is required for exec provider")
+	}
+	if *active != "" {
+		if _, err := scheduler.ParseWindow(*active); err != nil {
+			return err
+		}
+	}
+	if *confirm < 0 {
+		return fmt.Errorf("--confirm-runs must be at least 1")
+	}
+
+	// Use defaults from config
+	if *currency == "" {
+		*currency = c.config.Defaults.Currency
+	}
+	if err := c.checkCurrency(*currency, *allowUnknown); err != nil {
+		return err
+	}
+	if *percent == 0 {
+		*percent = c.config.Rules.PercentDrop
+	}
+
+	// Create item
+	item := storage.Item{
+		ID:          c.generateItemID(*name),
+		Name:        *name,
+		URL:         *url,
+		Provider:    *provider,
+		Selector:    *selector,
+		Currency:    *currency,
+		Schedule:    *schedule,
+		Regex:       *regex,
+		Attr:        *attr,
+		Command:     *command,
+
+		AcceptLanguage: *language,
+		HTTPTimeoutSec: storage.TimeoutSeconds(*timeout),
+		Notes:          *note,
+		FetchKey:       *fetchKey,
+		TLSInsecure:    *insecure,
+		TLSCAFile:      *caFile,
+		ActiveHours:    *active,
+		Providers:      providerChain,
+		ConfirmRuns:    *confirm,
+	}
+
+	if *target > 0 {
+		item.TargetPrice = target
+	}
+	if *percent > 0 {
+		item.PercentDrop = percent
+	}
+
+	// Save item
+	ctx := context.Background()
+	if err := c.storage.SaveItem(ctx, item); err != nil {
+		return fmt.Errorf("failed to save item: %w", err)
+	}
+
+	if *jsonFlag {
+		// Output JSON
+		jsonData, err := json.MarshalIndent(item, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		c.logger.Info("Item added successfully", 
+			"id", item.ID, 
+			"name", item.Name,
+			"url", item.URL,
+			"provider", item.Provider,
+		)
+	}
+
+	return nil
+}
+
+func (c *CLI) generateItemID(name string) string {
+	// Simple ID generation based on name
+	id := strings.ToLower(name)
+	id = strings.ReplaceAll(id, " ", "-")
+	id = strings.ReplaceAll(id, "_", "-")
+	
+	// Remove special characters
+	reg := regexp.MustCompile(`[^a-z0-9-]`)
+	id = reg.ReplaceAllString(id, "")
+	
+	// Ensure it's not empty
+	if id == "" {
+		id = "item"
+	}
+	
 	// Add timestamp to make it unique
 	return fmt.Sprintf("%s-%d", id, time.Now().Unix())
 }
@@ -325,518 +1085,3127 @@ func (c *CLI) handleImportFromFile(filename string, jsonOutput bool) error {
 	return fmt.Errorf("file import not implemented yet")
 }
 
-func (c *CLI) handleRemove(args []string) error {
+func (c *CLI) handleEdit(args []string) error {
 	if len(args) == 0 {
 		return fmt.Errorf("item ID is required")
 	}
-
 	itemID := args[0]
-	
-	// Confirm deletion
-	fmt.Printf("Are you sure you want to delete item '%s'? (y/N): ", itemID)
-	var response string
-	fmt.Scanln(&response)
-	
-	if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
-		c.logger.Info("Deletion cancelled")
-		return nil
-	}
 
-	// Delete item
+	fields := defineItemFlags()
+	jsonFlag := flag.Bool("json", false, "Output in JSON format")
+
+	// Parse flags
+	flag.CommandLine.Parse(args[1:])
+
 	ctx := context.Background()
-	if err := c.storage.DeleteItem(ctx, itemID); err != nil {
-		return fmt.Errorf("failed to delete item: %w", err)
+	item, err := c.storage.GetItem(ctx, itemID)
+	if err != nil {
+		return fmt.Errorf("failed to get item: %w", err)
+	}
+	if item == nil {
+		return fmt.Errorf("item not found: %s", itemID)
+	}
+
+	changed := fields.apply(item)
+	if changed == 0 {
+		return fmt.Errorf("nothing to change; pass at least one field flag (e.g. --note)")
+	}
+	if err := c.checkCurrency(*fields.currency, *fields.allowUnknown); err != nil {
+		return err
+	}
+	if err := validateProviders(item.Providers); err != nil {
+		return err
+	}
+	if item.ActiveHours != "" {
+		if _, err := scheduler.ParseWindow(item.ActiveHours); err != nil {
+			return err
+		}
+	}
+
+	if err := c.storage.SaveItem(ctx, *item); err != nil {
+		return fmt.Errorf("failed to save item: %w", err)
+	}
+
+	if *jsonFlag {
+		jsonData, err := json.MarshalIndent(item, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		c.logger.Info("Item updated", "id", item.ID, "fields", changed)
+	}
+
+	return nil
+}
+
+// itemFlags are the item field flags shared by edit and clone
+type itemFlags struct {
+	name, url, provider, selector, currency *string
+	target, percent                         *float64
+	schedule, regex, attr, command          *string
+	language                                *string
+	timeout                                 *time.Duration
+	note, fetchKey                          *string
+	insecure                                *bool
+	caFile, active                          *string
+	providers                               *string
+	confirm                                 *int
+	allowUnknown                            *bool
+}
+
+// defineItemFlags registers the item field flags on flag.CommandLine
+func defineItemFlags() *itemFlags {
+	return &itemFlags{
+		name:      flag.String("name", "", "Product name"),
+		url:       flag.String("url", "", "Product URL"),
+		provider:  flag.String("provider", "", "Provider type (generic, exec)"),
+		selector:  flag.String("selector", "", "CSS selector for price extraction"),
+		currency:  flag.String("currency", "", "Currency code (USD, EUR, TRY, etc.)"),
+		target:    flag.Float64("target", 0, "Target price (0 clears it)"),
+		percent:   flag.Float64("percent", 0, "Percent drop threshold (0 clears it)"),
+		schedule:  flag.String("schedule", "", "Schedule (hourly, daily, cron)"),
+		regex:     flag.String("regex", "", "Regex pattern for price cleanup"),
+		attr:      flag.String("attr", "", "Attribute to extract (text, content, data-price)"),
+		command:   flag.String("command", "", "Command for exec provider"),
+		language:  flag.String("accept-language", "", "Accept-Language header to send (e.g. de-DE)"),
+		timeout:   flag.Duration("http-timeout", 0, "HTTP timeout for this item (0 uses the default)"),
+		note:      flag.String("note", "", "Free-form note (empty clears it)"),
+		fetchKey:  flag.String("fetch-key", "", "Shared page fetch key (empty clears it)"),
+		insecure:  flag.Bool("tls-insecure", false, "Skip TLS certificate verification for this item"),
+		caFile:    flag.String("tls-ca-file", "", "PEM CA bundle to trust (empty clears it)"),
+		active:    flag.String("active-hours", "", "Daily tracking window, e.g. 09:00-22:00 (empty clears it)"),
+		providers: flag.String("providers", "", "Comma-separated providers to try in order (empty clears the chain)"),
+		confirm:   flag.Int("confirm-runs", 0, "Consecutive samples that must show a drop before alerting (0 uses rules.confirm_runs)"),
+
+		allowUnknown: flag.Bool("allow-unknown-currency", false, "Accept a currency code outside ISO 4217 (crypto, custom units)"),
+	}
+}
+
+// apply copies the field flags given on the command line to item and
+// returns how many there were
+func (f *itemFlags) apply(item *storage.Item) int {
+	changed := 0
+	flag.Visit(func(fl *flag.Flag) {
+		changed++
+		switch fl.Name {
+		case "name":
+			item.Name = *f.name
+		case "url":
+			item.URL = *f.url
+		case "provider":
+			item.Provider = *f.provider
+		case "selector":
+			item.Selector = *f.selector
+		case "currency":
+			item.Currency = *f.currency
+		case "target":
+			item.TargetPrice = nil
+			if *f.target > 0 {
+				item.TargetPrice = f.target
+			}
+		case "percent":
+			item.PercentDrop = nil
+			if *f.percent > 0 {
+				item.PercentDrop = f.percent
+			}
+		case "schedule":
+			item.Schedule = *f.schedule
+		case "regex":
+			item.Regex = *f.regex
+		case "attr":
+			item.Attr = *f.attr
+		case "command":
+			item.Command = *f.command
+		case "accept-language":
+			item.AcceptLanguage = *f.language
+		case "http-timeout":
+			item.HTTPTimeoutSec = storage.TimeoutSeconds(*f.timeout)
+		case "note":
+			item.Notes = *f.note
+		case "fetch-key":
+			item.FetchKey = *f.fetchKey
+		case "tls-insecure":
+			item.TLSInsecure = *f.insecure
+		case "tls-ca-file":
+			item.TLSCAFile = *f.caFile
+		case "active-hours":
+			item.ActiveHours = *f.active
+		case "providers":
+			item.Providers = parseProviders(*f.providers)
+		case "confirm-runs":
+			item.ConfirmRuns = max(*f.confirm, 0)
+		default:
+			changed-- // global or output flags
+		}
+	})
+	return changed
+}
+
+// checkCurrency rejects a currency code given on the command line that
+// config.CheckCurrency doesn't accept, unless --allow-unknown-currency is set
+func (c *CLI) checkCurrency(code string, allowUnknown bool) error {
+	if allowUnknown {
+		return nil
+	}
+	if err := c.config.CheckCurrency(code); err != nil {
+		return fmt.Errorf("%w; pass --allow-unknown-currency for crypto or custom units", err)
+	}
+	return nil
+}
+
+// checkItemCurrencies runs checkCurrency on every item, reporting all the
+// items it rejects at once
+func (c *CLI) checkItemCurrencies(items []storage.Item, allowUnknown bool) error {
+	var errs []error
+	for _, item := range items {
+		if err := c.checkCurrency(item.Currency, allowUnknown); err != nil {
+			errs = append(errs, fmt.Errorf("item %s: %w", item.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// handleClone saves a copy of an item under a new ID with the given field
+// flags applied. Price history stays with the source item.
+func (c *CLI) handleClone(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("source item ID is required")
+	}
+	sourceID := args[0]
+
+	fields := defineItemFlags()
+	newID := flag.String("id", "", "ID for the copy (default: generated from the name)")
+	jsonFlag := flag.Bool("json", false, "Output in JSON format")
+
+	// Parse flags
+	flag.CommandLine.Parse(args[1:])
+
+	ctx := context.Background()
+	source, err := c.storage.GetItem(ctx, sourceID)
+	if err != nil {
+		return fmt.Errorf("failed to get item: %w", err)
+	}
+	if source == nil {
+		return fmt.Errorf("item not found: %s", sourceID)
+	}
+
+	item := *source
+	if source.TargetPrice != nil {
+		target := *source.TargetPrice
+		item.TargetPrice = &target
+	}
+	if source.PercentDrop != nil {
+		percent := *source.PercentDrop
+		item.PercentDrop = &percent
+	}
+	item.Providers = slices.Clone(source.Providers)
+	fields.apply(&item)
+	if err := c.checkCurrency(*fields.currency, *fields.allowUnknown); err != nil {
+		return err
+	}
+	if err := validateProviders(item.Providers); err != nil {
+		return err
+	}
+
+	item.ID = *newID
+	if item.ID == "" {
+		item.ID = c.generateItemID(item.Name)
+	}
+	existing, err := c.storage.GetItem(ctx, item.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get item: %w", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("item %s already exists", item.ID)
+	}
+	if item.URL == source.URL && item.Command == source.Command {
+		c.logger.Warn("The copy fetches the same page as its source; pass --url to point it elsewhere", "source", source.ID)
+	}
+	if item.ActiveHours != "" {
+		if _, err := scheduler.ParseWindow(item.ActiveHours); err != nil {
+			return err
+		}
+	}
+
+	if err := c.storage.SaveItem(ctx, item); err != nil {
+		return fmt.Errorf("failed to save item: %w", err)
+	}
+
+	if *jsonFlag {
+		jsonData, err := json.MarshalIndent(item, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		c.logger.Info("Item cloned", "source", source.ID, "id", item.ID, "name", item.Name, "url", item.URL)
+	}
+
+	return nil
+}
+
+func (c *CLI) handleRemove(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("item ID is required")
+	}
+
+	itemID := args[0]
+	
+	// Confirm deletion
+	fmt.Printf("Are you sure you want to delete item '%s'? (y/N): ", itemID)
+	var response string
+	fmt.Scanln(&response)
+	
+	if strings.ToLower(response) != "y" && strings.ToLower(response) != "yes" {
+		c.logger.Info("Deletion cancelled")
+		return nil
+	}
+
+	// Delete item
+	ctx := context.Background()
+	if err := c.storage.DeleteItem(ctx, itemID); err != nil {
+		return fmt.Errorf("failed to delete item: %w", err)
+	}
+
+	c.logger.Info("Item deleted successfully", "id", itemID)
+	return nil
+}
+
+func (c *CLI) handleList(args []string) error {
+	var (
+		jsonFlag = flag.Bool("json", false, "Output in JSON format")
+		compact  = flag.Bool("compact", false, "Output single-line JSON without empty fields (implies --json)")
+		noMeta   = flag.Bool("no-meta", false, "Leave meta out of --compact output")
+		maxAge   = flag.String("max-age", "", "Hide items without a sample newer than this (e.g. 24h, 7d)")
+	)
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
+	if err := c.setMaxAge(*maxAge); err != nil {
+		return err
+	}
+	if err := jsonOutputFlags(jsonFlag, *compact, *noMeta); err != nil {
+		return err
+	}
+
+	// --verbose is the global flag, so ls --verbose and --verbose ls both work
+	verbose := false
+	if f := flag.Lookup("verbose"); f != nil {
+		verbose = f.Value.String() == "true"
+	}
+
+	// Get all items
+	ctx := context.Background()
+	items, err := c.storage.GetItems(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get items: %w", err)
+	}
+
+	if len(items) == 0 {
+		c.logger.Info("No items found")
+		return nil
+	}
+
+	if c.maxAge > 0 {
+		fresh := items[:0]
+		for _, item := range items {
+			if age, ok := c.sampleAge(item); ok && age <= c.maxAge {
+				fresh = append(fresh, item)
+			}
+		}
+		if hidden := len(items) - len(fresh); hidden > 0 {
+			c.logger.Info("Hiding items without a recent sample", "hidden", hidden, "max_age", *maxAge)
+		}
+		items = fresh
+		if len(items) == 0 && !*jsonFlag {
+			return nil
+		}
+	}
+
+	statuses, err := c.storage.GetFetchStatuses(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get fetch status: %w", err)
+	}
+
+	if *jsonFlag {
+		// Output JSON
+		type listEntry struct {
+			storage.Item
+			LastFetch *storage.FetchStatus `json:"last_fetch,omitempty"`
+		}
+		entries := make([]listEntry, 0, len(items))
+		for _, item := range items {
+			entry := listEntry{Item: item}
+			if status, ok := statuses[item.ID]; ok {
+				entry.LastFetch = &status
+			}
+			entries = append(entries, entry)
+		}
+		jsonData, err := marshalOutput(entries, *compact, *noMeta)
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		// Output table format
+		c.printItemsTable(items, statuses, verbose)
+	}
+
+	return nil
+}
+
+// fetchStatusColor is the color of a status in the ls table
+func fetchStatusColor(status string) string {
+	switch status {
+	case storage.StatusOK:
+		return utils.ColorGreen
+	case storage.StatusError, storage.StatusBlocked:
+		return utils.ColorRed
+	case "stale":
+		return utils.ColorYellow
+	}
+	return ""
+}
+
+func (c *CLI) printItemsTable(items []storage.Item, statuses map[string]storage.FetchStatus, verbose bool) {
+	// Print header
+	fmt.Printf("%-20s %-30s %-15s %-10s %-10s %-10s %-8s\n", 
+		"ID", "Name", "Provider", "Currency", "Target", "Schedule", "Status")
+	fmt.Println(strings.Repeat("-", 104))
+
+	// Print items
+	for _, item := range items {
+		target := "-"
+		if item.TargetPrice != nil {
+			target = utils.FormatAmount(*item.TargetPrice, item.Currency)
+		}
+
+		status := "-"
+		if last, ok := statuses[item.ID]; ok {
+			status = last.Status
+		}
+		if status == "-" || status == storage.StatusOK {
+			if _, stale := c.staleness(item); stale {
+				status = "stale"
+			}
+		}
+
+		fmt.Printf("%-20s %-30s %-15s %-10s %-10s %-10s %s\n",
+			item.ID,
+			truncateString(item.Name, 30),
+			item.Provider,
+			item.Currency,
+			target,
+			item.Schedule,
+			utils.Colorize(fetchStatusColor(status), fmt.Sprintf("%-8s", status)),
+		)
+
+		if verbose {
+			fmt.Printf("  URL: %s\n", item.URL)
+			if item.Selector != "" {
+				fmt.Printf("  Selector: %s\n", item.Selector)
+			}
+			if item.Regex != "" {
+				fmt.Printf("  Regex: %s\n", item.Regex)
+			}
+			if item.Attr != "" {
+				fmt.Printf("  Attribute: %s\n", item.Attr)
+			}
+			if item.Command != "" {
+				fmt.Printf("  Command: %s\n", item.Command)
+			}
+			if item.AcceptLanguage != "" {
+				fmt.Printf("  Accept-Language: %s\n", item.AcceptLanguage)
+			}
+			if item.HTTPTimeoutSec > 0 {
+				fmt.Printf("  HTTP Timeout: %ds\n", item.HTTPTimeoutSec)
+			}
+			if item.FetchKey != "" {
+				fmt.Printf("  Fetch Key: %s\n", item.FetchKey)
+			}
+			if item.TLSInsecure {
+				fmt.Printf("  TLS: certificate verification disabled\n")
+			}
+			if item.TLSCAFile != "" {
+				fmt.Printf("  TLS CA File: %s\n", item.TLSCAFile)
+			}
+			if item.ActiveHours != "" {
+				fmt.Printf("  Active Hours: %s\n", item.ActiveHours)
+			}
+			if item.PercentDrop != nil {
+				fmt.Printf("  Percent Drop: %.1f%%\n", *item.PercentDrop)
+			}
+			if item.Notes != "" {
+				fmt.Printf("  Notes: %s\n", item.Notes)
+			}
+			if last, ok := statuses[item.ID]; ok && last.Detail != "" {
+				fmt.Printf("  Last Fetch: %s at %s (%s)\n", last.Status, last.Time.Format("2006-01-02 15:04"), last.Detail)
+			}
+			fmt.Println()
+		}
+	}
+}
+
+// staleThreshold is how old an item's latest sample may get before it's
+// flagged: defaults.stale_after, stretched to cover two schedule intervals
+// so daily or weekly items aren't reported between runs
+func (c *CLI) staleThreshold(schedule string) time.Duration {
+	if c.maxAge > 0 {
+		return c.maxAge
+	}
+	threshold := c.config.Defaults.StaleAfter
+	if interval := scheduler.Interval(schedule); 2*interval > threshold {
+		threshold = 2 * interval
+	}
+	return threshold
+}
+
+// staleness returns the age of the item's latest sample and whether it is
+// past the stale threshold. Items that were never fetched aren't stale.
+func (c *CLI) staleness(item storage.Item) (time.Duration, bool) {
+	age, ok := c.sampleAge(item)
+	if !ok {
+		return 0, false
+	}
+	return age, age > c.staleThreshold(item.Schedule)
+}
+
+// sampleAge returns the age of the item's latest sample, or false when it
+// has none
+func (c *CLI) sampleAge(item storage.Item) (time.Duration, bool) {
+	latest, err := c.storage.GetLatestPrice(context.Background(), item.ID)
+	if err != nil || latest == nil {
+		return 0, false
+	}
+	return time.Since(latest.Time), true
+}
+
+// setMaxAge applies a --max-age flag value; empty keeps the configured
+// stale threshold
+func (c *CLI) setMaxAge(value string) error {
+	if value == "" {
+		return nil
+	}
+	age, err := utils.ParseAge(value)
+	if err != nil {
+		return err
+	}
+	if age <= 0 {
+		return fmt.Errorf("--max-age must be positive")
+	}
+	c.maxAge = age
+	return nil
+}
+
+func truncateString(s string, maxLen int) string {
+	if len(s) <= maxLen {
+		return s
+	}
+	return s[:maxLen-3] + "..."
+}
+
+// showRows returns how many price points show loads and how many it lists
+// (-1 for all). The table shows the 10 newest points unless --limit or
+// --all ask for more; stats and the sparkline use everything loaded.
+func showRows(limit int, limitSet, all bool) (load, rows int, err error) {
+	if all {
+		return -1, -1, nil // no LIMIT
+	}
+	if limit < 1 {
+		return 0, 0, fmt.Errorf("--limit must be at least 1 (use --all for the full history)")
+	}
+	if limitSet {
+		return limit, limit, nil
+	}
+	return limit, 10, nil
+}
+
+func (c *CLI) handleShow(args []string) error {
+	var (
+		sparkFlag  = flag.Bool("spark", false, "Show sparkline")
+		sparkWidth = flag.Int("spark-width", 0, "Sparkline width in characters (default one per point, at most 50)")
+		asciiFlag  = flag.Bool("ascii", false, "Draw the sparkline with ASCII characters instead of Unicode blocks")
+		limit      = flag.Int("limit", 30, "Number of price points to load; also the number printed when given")
+		allFlag    = flag.Bool("all", false, "Load and print the full price history")
+		compareTo  = flag.String("compare-to", "", "Compare the latest price with this far back (e.g. 30d)")
+		jsonFlag   = flag.Bool("json", false, "Output in JSON format")
+		compact    = flag.Bool("compact", false, "Output single-line JSON without empty fields (implies --json)")
+		noMeta     = flag.Bool("no-meta", false, "Leave price meta out of --compact output")
+		calendar   = flag.Bool("calendar", false, "Show a month-by-day calendar of daily price changes over the full history")
+		maxAge     = flag.String("max-age", "", "Warn when the latest sample is older than this (e.g. 24h, 7d)")
+	)
+
+	// Accept flags before or after the item ID
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		flag.CommandLine.Parse(args[1:])
+		args = args[:1]
+	} else {
+		flag.CommandLine.Parse(args)
+		args = flag.Args()
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("item ID is required")
+	}
+	if err := c.setMaxAge(*maxAge); err != nil {
+		return err
+	}
+	if err := jsonOutputFlags(jsonFlag, *compact, *noMeta); err != nil {
+		return err
+	}
+
+	itemID := args[0]
+
+	// Get item
+	ctx := context.Background()
+	item, err := c.storage.GetItem(ctx, itemID)
+	if err != nil {
+		return fmt.Errorf("failed to get item: %w", err)
+	}
+	if item == nil {
+		return fmt.Errorf("item not found: %s", itemID)
+	}
+
+	limitSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "limit" {
+			limitSet = true
+		}
+	})
+	load, rows, err := showRows(*limit, limitSet, *allFlag)
+	if err != nil {
+		return err
+	}
+
+	// Get price history
+	prices, err := c.storage.GetPrices(ctx, itemID, load)
+	if err != nil {
+		return fmt.Errorf("failed to get price history: %w", err)
+	}
+	if *allFlag && len(prices) > 1000 && !*jsonFlag {
+		c.logger.Warn("Printing the full price history", "points", len(prices), "hint", "pipe through less, or use --json")
+	}
+
+	if len(prices) == 0 {
+		if c.maxAge > 0 {
+			c.logger.Warn("Item has no samples", "id", itemID, "max_age", *maxAge)
+		} else {
+			c.logger.Info("No price history found for item", "id", itemID)
+		}
+		return nil
+	}
+	if age := time.Since(prices[0].Time); c.maxAge > 0 && age > c.maxAge {
+		c.logger.Warn("Latest sample is older than --max-age", "id", itemID, "age", age.Round(time.Minute), "max_age", *maxAge)
+	}
+
+	spark := sparkOptions{show: *sparkFlag || *sparkWidth > 0 || *asciiFlag, width: *sparkWidth, chars: utils.SparklineBlocks}
+	if *asciiFlag {
+		spark.chars = utils.SparklineASCII
+	}
+
+	var comparison *priceComparison
+	if *compareTo != "" {
+		age, err := utils.ParseAge(*compareTo)
+		if err != nil {
+			return err
+		}
+		if comparison, err = c.compareTo(ctx, prices[0], age); err != nil {
+			return err
+		}
+	}
+
+	var daily []storage.Aggregate
+	if *calendar {
+		if daily, err = c.dailyCloses(ctx, itemID, prices[0].Currency); err != nil {
+			return err
+		}
+	}
+
+	if *jsonFlag {
+		// Output JSON
+		response := map[string]interface{}{
+			"item":   item,
+			"prices": prices,
+		}
+		if comparison != nil {
+			response["compare"] = comparison
+		}
+		if *calendar {
+			response["daily"] = daily
+		}
+		jsonData, err := marshalOutput(response, *compact, *noMeta)
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+	} else {
+		// Output formatted display
+		c.printItemDetails(item, prices, spark, rows)
+		if comparison != nil {
+			comparison.print()
+		}
+		if *calendar {
+			fmt.Println()
+			fmt.Println(utils.RenderCalendar(dailyChanges(daily)))
+		}
+	}
+
+	return nil
+}
+
+// dailyCloses aggregates the item's full history in currency into days,
+// oldest first
+func (c *CLI) dailyCloses(ctx context.Context, itemID, currency string) ([]storage.Aggregate, error) {
+	aggregator, err := storage.NewAggregator(storage.GranularityDaily)
+	if err != nil {
+		return nil, err
+	}
+	err = c.storage.EachPrice(ctx, itemID, func(sample storage.PriceSample) error {
+		if sample.Currency == currency {
+			aggregator.Add(sample)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get price history: %w", err)
+	}
+	return aggregator.Aggregates(), nil
+}
+
+// dailyChanges maps each day to the percent change of its close from the
+// previous day with data; the first day counts as unchanged
+func dailyChanges(daily []storage.Aggregate) map[string]float64 {
+	changes := make(map[string]float64, len(daily))
+	for i, day := range daily {
+		if i == 0 {
+			changes[day.Period] = 0
+			continue
+		}
+		changes[day.Period] = utils.CalculatePriceChange(daily[i-1].Close, day.Close)
+	}
+	return changes
+}
+
+// priceComparison is the change from a past baseline to the latest price
+type priceComparison struct {
+	Requested time.Time           `json:"requested"`
+	Baseline  storage.PriceSample `json:"baseline"`
+	Latest    storage.PriceSample `json:"latest"`
+	Change    float64             `json:"change"`
+	Percent   float64             `json:"percent"`
+	// Earliest is set when the history doesn't reach back to Requested and
+	// the earliest sample was used instead
+	Earliest bool `json:"earliest,omitempty"`
+}
+
+func (c *CLI) compareTo(ctx context.Context, latest storage.PriceSample, age time.Duration) (*priceComparison, error) {
+	requested := time.Now().Add(-age)
+	baseline, err := c.storage.GetPriceAt(ctx, latest.ItemID, requested)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get baseline price: %w", err)
+	}
+	if baseline == nil {
+		return nil, nil
+	}
+
+	return &priceComparison{
+		Requested: requested,
+		Baseline:  *baseline,
+		Latest:    latest,
+		Change:    latest.Price - baseline.Price,
+		Percent:   utils.CalculatePriceChange(baseline.Price, latest.Price),
+		Earliest:  baseline.Time.After(requested),
+	}, nil
+}
+
+func (p *priceComparison) print() {
+	fmt.Println()
+	if p.Earliest {
+		fmt.Printf("No data back to %s; comparing with the earliest sample.\n", p.Requested.Format("2006-01-02"))
+	}
+
+	direction := "unchanged"
+	switch {
+	case p.Change < 0:
+		direction = "down"
+	case p.Change > 0:
+		direction = "up"
+	}
+	fmt.Printf("Compared to %s: %s %s (%+.1f%%), %s -> %s\n",
+		p.Baseline.Time.Format("2006-01-02"),
+		direction,
+		utils.FormatPrice(math.Abs(p.Change), p.Latest.Currency),
+		p.Percent,
+		utils.FormatPrice(p.Baseline.Price, p.Baseline.Currency),
+		utils.FormatPrice(p.Latest.Price, p.Latest.Currency),
+	)
+}
+
+// targetDistance describes how far price is above or below target: green
+// at or below it, yellow within 10% above, red further off
+func targetDistance(price, target float64) string {
+	percent := (price - target) / target * 100
+	switch {
+	case percent == 0:
+		return utils.Colorize(utils.ColorGreen, "at target")
+	case percent < 0:
+		return utils.Colorize(utils.ColorGreen, fmt.Sprintf("%.1f%% below target", -percent))
+	case percent <= 10:
+		return utils.Colorize(utils.ColorYellow, fmt.Sprintf("%.1f%% above target", percent))
+	default:
+		return utils.Colorize(utils.ColorRed, fmt.Sprintf("%.1f%% above target", percent))
+	}
+}
+
+// sparkOptions controls the sparkline of show
+type sparkOptions struct {
+	show  bool
+	width int
+	chars []rune
+}
+
+// defaultSparkWidth caps the sparkline when --spark-width is not given
+const defaultSparkWidth = 50
+
+// printItemDetails prints the item and its price history, listing at most
+// rows samples (all of them when rows is negative)
+func (c *CLI) printItemDetails(item *storage.Item, prices []storage.PriceSample, spark sparkOptions, rows int) {
+	fmt.Printf("Item: %s (%s)\n", item.Name, item.ID)
+	fmt.Printf("URL: %s\n", item.URL)
+	if len(item.Providers) > 0 {
+		fmt.Printf("Providers: %s (tried in order)\n", strings.Join(item.Providers, ", "))
+	} else {
+		fmt.Printf("Provider: %s\n", item.Provider)
+	}
+	fmt.Printf("Currency: %s\n", item.Currency)
+	fmt.Printf("Schedule: %s\n", item.Schedule)
+	if len(prices) > 0 {
+		if threshold := c.staleThreshold(item.Schedule); time.Since(prices[0].Time) > threshold {
+			fmt.Printf("Stale: last sample %s ago (threshold %s)\n",
+				time.Since(prices[0].Time).Round(time.Minute), threshold)
+		}
+	}
+	
+	if item.TargetPrice != nil {
+		fmt.Printf("Target Price: %s\n", utils.FormatPrice(*item.TargetPrice, item.Currency))
+		if len(prices) > 0 && *item.TargetPrice > 0 {
+			fmt.Printf("Vs Target: %s\n", targetDistance(prices[0].Price, *item.TargetPrice))
+		}
+	}
+	if item.PercentDrop != nil {
+		fmt.Printf("Percent Drop Alert: %.1f%%\n", *item.PercentDrop)
+	}
+	if item.ConfirmRuns > 1 {
+		fmt.Printf("Drop Confirmed Over: %d runs\n", item.ConfirmRuns)
+	}
+	if item.Notes != "" {
+		fmt.Printf("Notes: %s\n", item.Notes)
+	}
+	
+	fmt.Println()
+
+	// Show price history
+	fmt.Printf("Price History (%d points):\n", len(prices))
+	fmt.Println(strings.Repeat("-", 50))
+
+	// Extract price values for sparkline, oldest first
+	priceValues := make([]float64, len(prices))
+	for i, price := range prices {
+		priceValues[len(prices)-1-i] = price.Price
+	}
+
+	// Show sparkline if requested
+	if spark.show && len(priceValues) > 1 {
+		width := spark.width
+		if width <= 0 {
+			width = min(len(priceValues), defaultSparkWidth)
+		}
+		sparkline := utils.GenerateSparklineChars(priceValues, width, spark.chars)
+		fmt.Printf("Price Trend: %s\n", sparkline)
+		fmt.Println()
+	}
+
+	// Show recent prices
+	for i, price := range prices {
+		if rows >= 0 && i >= rows {
+			fmt.Printf("... %d older point(s) not shown (use --limit N or --all)\n", len(prices)-rows)
+			break
+		}
+		formattedPrice := utils.FormatPrice(price.Price, price.Currency)
+		fmt.Printf("%s: %s", price.Time.Format("2006-01-02 15:04:05"), formattedPrice)
+		
+		// Show price change if we have previous price
+		if i < len(prices)-1 {
+			prevPrice := prices[i+1].Price
+			change := utils.CalculatePriceChange(prevPrice, price.Price)
+			if change > 0 {
+				fmt.Printf(" (+%.1f%%)", change)
+			} else if change < 0 {
+				fmt.Printf(" (%.1f%%)", change)
+			}
+		}
+		fmt.Println()
+	}
+
+	// Show statistics
+	if len(priceValues) > 1 {
+		min, max, avg, median := utils.CalculateStats(priceValues)
+		fmt.Println()
+		fmt.Printf("Statistics:\n")
+		fmt.Printf("  Min: %s\n", utils.FormatPrice(min, item.Currency))
+		fmt.Printf("  Max: %s\n", utils.FormatPrice(max, item.Currency))
+		fmt.Printf("  Avg: %s\n", utils.FormatPrice(avg, item.Currency))
+		fmt.Printf("  Median: %s\n", utils.FormatPrice(median, item.Currency))
+	}
+}
+
+func (c *CLI) handleTrack(ctx context.Context, args []string) error {
+	var (
+		onceFlag     = flag.Bool("once", false, "Run tracking once")
+		loopFlag     = flag.Bool("loop", false, "Run tracking in a loop")
+		itemID       = flag.String("id", "", "Track specific item ID")
+		noCacheFlag  = flag.Bool("no-cache", false, "Disable caching")
+		respectCache = flag.Bool("respect-cache", false, "Skip items whose latest price is younger than defaults.cache_ttl_min")
+		interval     = flag.Duration("interval", 1*time.Hour, "Loop interval (default: the shortest item schedule, else 1h)")
+		jsonFlag     = flag.Bool("json", false, "Print one JSON object per item, then the run summary")
+		minInterval  = flag.Duration("min-interval", c.config.Defaults.MinInterval, "Smallest loop interval allowed without --force")
+		forceFlag    = flag.Bool("force", false, "Allow loop intervals below --min-interval")
+		noStoreFlag  = flag.Bool("no-store", false, "Fetch and evaluate alerts without saving prices")
+		onlyAlerts   = flag.Bool("only-alerts", false, "Print only items whose alert rules triggered; stay silent otherwise")
+		watchFile    = flag.Bool("watch-file", false, "With --loop, reload items when the config file changes")
+		quietFlag    = flag.Bool("quiet", false, "Don't show progress")
+		exportFile   = flag.String("export-on-track", c.config.Export.OnTrack, "Export prices to this CSV or .ndjson file after each run")
+		itemSource   = flag.String("item-source", c.config.ItemSource, "Items to track: merge (config and DB, DB wins), config or db")
+		retryFailed  = flag.Bool("retry-failed", c.config.Defaults.Retry.FinalPass, "Retry failed items once more at the end of each run")
+	)
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
+	// Determine mode
+	if *onceFlag && *loopFlag {
+		return fmt.Errorf("cannot specify both --once and --loop")
+	}
+	if err := checkInterval(*loopFlag, *interval, *minInterval, *forceFlag); err != nil {
+		return err
+	}
+	if *watchFile && !*loopFlag {
+		return fmt.Errorf("--watch-file requires --loop")
+	}
+	if err := tracker.ValidItemSource(*itemSource); err != nil {
+		return err
+	}
+	c.config.ItemSource = *itemSource
+	c.config.Defaults.Retry.FinalPass = *retryFailed
+	if *noStoreFlag {
+		c.logger.Warn("Persistence disabled: fetched prices will not be saved")
+		c.tracker.DisableStore()
+	}
+	if *respectCache {
+		c.tracker.RespectCache(c.config.Defaults.CacheTTL)
+	}
+	if !*onceFlag && !*loopFlag {
+		*onceFlag = true // Default to once
+	}
+	switch {
+	case *onlyAlerts:
+		// Warnings and errors still surface; routine progress does not
+		if f := flag.Lookup("verbose"); f == nil || f.Value.String() != "true" {
+			c.logger.SetLevel(slog.LevelWarn)
+		}
+		// Triggered rules count even when no channel delivered them
+		c.tracker.OnItemResult(func(result tracker.ItemResult) {
+			if len(result.Triggered) == 0 {
+				return
+			}
+			if *jsonFlag {
+				printItemResult(result)
+				return
+			}
+			printAlertLine(result)
+		})
+	case *jsonFlag:
+		c.tracker.OnItemResult(printItemResult)
+	case *onceFlag && !*quietFlag && utils.IsTerminal(os.Stdout) && utils.IsTerminal(os.Stderr):
+		c.tracker.OnProgress(printProgress)
+	}
+
+	c.config.Export.OnTrack = *exportFile
+	if c.config.Export.Items && isNDJSONPath(*exportFile) {
+		return fmt.Errorf("export.items writes CSV; use a .csv path for %s", *exportFile)
+	}
+
+	if *onceFlag {
+		result, err := c.trackOnce(ctx, *itemID, *noCacheFlag, *respectCache)
+		c.exportOnTrack(ctx)
+		if err != nil {
+			return err
+		}
+		if *jsonFlag && !*onlyAlerts {
+			return printRunResult(result)
+		}
+		return nil
+	} else {
+		if *watchFile {
+			if err := c.watchConfig(ctx); err != nil {
+				return err
+			}
+		}
+		// Without --interval the loop ticks at the shortest item schedule
+		intervalSet := false
+		flag.Visit(func(f *flag.Flag) {
+			if f.Name == "interval" {
+				intervalSet = true
+			}
+		})
+		baseTick := func() time.Duration {
+			shortest := c.tracker.ShortestInterval(ctx)
+			if intervalSet || *itemID != "" || shortest <= 0 {
+				return *interval
+			}
+			if shortest < *minInterval && !*forceFlag {
+				return *minInterval
+			}
+			return shortest
+		}
+		if shortest := c.tracker.ShortestInterval(ctx); !intervalSet && shortest > 0 && shortest < *minInterval && !*forceFlag {
+			c.logger.Warn("Shortest item schedule is below the minimum loop interval; ticking at the minimum (use --force to override)",
+				"schedule", shortest, "min_interval", *minInterval)
+		}
+		return c.trackLoop(ctx, *itemID, *noCacheFlag, *respectCache, baseTick, *jsonFlag && !*onlyAlerts)
+	}
+}
+
+// watchConfig applies item additions, removals and edits from the config
+// file to the running tracker whenever the file changes. Invalid edits are
+// logged and the previous items stay in use; settings outside items need a
+// restart.
+func (c *CLI) watchConfig(ctx context.Context) error {
+	settings := *c.config
+	settings.Items = nil
+	items := c.config.Items
+
+	c.logger.Info("Watching config file for changes", "path", c.configPath)
+	return config.Watch(ctx, c.configPath, func(cfg *config.Config, err error) {
+		if err != nil {
+			c.logger.Error("Config reload rejected, keeping the previous items", "path", c.configPath, "error", err)
+			return
+		}
+
+		added, removed, changed := diffItemConfigs(items, cfg.Items)
+		c.tracker.SetItems(cfg.Items)
+		items = cfg.Items
+		c.logger.Info("Config reloaded", "items", len(cfg.Items), "added", added, "removed", removed, "changed", changed)
+
+		reloaded := *cfg
+		reloaded.Items = nil
+		if !reflect.DeepEqual(settings, reloaded) {
+			c.logger.Warn("Config settings outside items changed; restart to apply them", "path", c.configPath)
+		}
+	})
+}
+
+// diffItemConfigs returns the IDs added, removed and changed between two
+// item lists
+func diffItemConfigs(before, after []config.ItemConfig) (added, removed, changed []string) {
+	old := make(map[string]config.ItemConfig, len(before))
+	for _, item := range before {
+		old[item.ID] = item
+	}
+	seen := make(map[string]bool, len(after))
+	for _, item := range after {
+		seen[item.ID] = true
+		prev, ok := old[item.ID]
+		switch {
+		case !ok:
+			added = append(added, item.ID)
+		case !reflect.DeepEqual(prev, item):
+			changed = append(changed, item.ID)
+		}
+	}
+	for _, item := range before {
+		if !seen[item.ID] {
+			removed = append(removed, item.ID)
+		}
+	}
+	return added, removed, changed
+}
+
+// printProgress redraws the "[n/total] tracking name..." line on stderr. The
+// cursor is left at the start of the line so a log record written meanwhile
+// overwrites it instead of being appended to it.
+func printProgress(n, total int, name string) {
+	if name == "" {
+		fmt.Fprint(os.Stderr, "\r\x1b[K")
+		return
+	}
+	fmt.Fprintf(os.Stderr, "\r\x1b[K[%d/%d] tracking %s...\r", n, total, truncateString(name, 50))
+}
+
+// printItemResult writes one item's tracking outcome as a JSON line
+func printItemResult(result tracker.ItemResult) {
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+	fmt.Println(string(jsonData))
+}
+
+// printAlertLine describes an item whose alert rules triggered in one line
+func printAlertLine(result tracker.ItemResult) {
+	line := fmt.Sprintf("%s: %s", result.ID, utils.FormatPrice(result.Price, result.Currency))
+	if result.ChangePct != nil {
+		line += fmt.Sprintf(" (%+.1f%%)", *result.ChangePct)
+	}
+	fmt.Printf("%s [%s]\n", line, strings.Join(result.Triggered, ", "))
+}
+
+func printRunResult(result *tracker.RunResult) error {
+	jsonData, err := json.Marshal(result)
+	if err != nil {
+		return fmt.Errorf("failed to marshal JSON: %w", err)
+	}
+	fmt.Println(string(jsonData))
+	return nil
+}
+
+// checkInterval rejects a loop interval that isn't positive or, without
+// force, is below min. A --once run never ticks, so its --interval and
+// --min-interval go unchecked.
+func checkInterval(loop bool, interval, min time.Duration, force bool) error {
+	if !loop {
+		return nil
+	}
+	if interval <= 0 {
+		return fmt.Errorf("interval must be positive")
+	}
+	if interval < min && !force {
+		return fmt.Errorf("interval %v is below the minimum of %v; frequent requests risk getting blocked (use --force to override)", interval, min)
+	}
+	return nil
+}
+
+func (c *CLI) trackOnce(ctx context.Context, itemID string, noCache, respectCache bool) (*tracker.RunResult, error) {
+	c.logger.Info("Starting one-time price tracking")
+
+	if itemID != "" {
+		// Track specific item, resolved like the full run per item_source
+		itemConfig, ok := c.tracker.Item(ctx, itemID)
+		if !ok {
+			return nil, fmt.Errorf("item not found: %s", itemID)
+		}
+
+		result := c.tracker.TrackItems(ctx, []config.ItemConfig{itemConfig})
+		if result.Failed > 0 {
+			return result, fmt.Errorf("failed to track item: %s", itemID)
+		}
+		return result, nil
+	} else {
+		// Track all items
+		return c.tracker.TrackAll(ctx)
+	}
+}
+
+// trackLoop tracks on every tick of baseTick until ctx is done. Without an
+// item ID only the items whose schedule is due are fetched each tick; the
+// tick is re-read before each run so config reloads can change it.
+func (c *CLI) trackLoop(ctx context.Context, itemID string, noCache, respectCache bool, baseTick func() time.Duration, jsonOutput bool) error {
+	interval := baseTick()
+	c.logger.Info("Starting continuous price tracking", "interval", interval)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// Runs execute in the background so ticks arriving while a pass is
+	// still in progress can be skipped instead of queueing up
+	var (
+		running atomic.Bool
+		wg      sync.WaitGroup
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			c.logger.Info("Tracking stopped")
+			return ctx.Err()
+		case <-ticker.C:
+			if !running.CompareAndSwap(false, true) {
+				c.logger.Warn("Skipping scheduled tracking: previous run still in progress", "interval", interval)
+				continue
+			}
+
+			if next := baseTick(); next != interval {
+				c.logger.Info("Loop interval changed", "from", interval, "to", next)
+				interval = next
+				ticker.Reset(interval)
+			}
+
+			wg.Add(1)
+			go func(interval time.Duration) {
+				defer wg.Done()
+				defer running.Store(false)
+
+				c.logger.Info("Running scheduled tracking")
+				start := time.Now()
+				var (
+					result *tracker.RunResult
+					err    error
+				)
+				if itemID != "" {
+					result, err = c.trackOnce(ctx, itemID, noCache, respectCache)
+				} else {
+					result = c.tracker.TrackDue(ctx, start, interval/2)
+				}
+				if err != nil {
+					c.logger.Error("Tracking failed", "error", err)
+				}
+				c.exportOnTrack(ctx)
+				if elapsed := time.Since(start); elapsed > interval {
+					c.logger.Warn("Tracking run took longer than the interval", "elapsed", elapsed.Round(time.Second), "interval", interval)
+				}
+				if jsonOutput && result != nil {
+					printRunResult(result)
+				}
+			}(interval)
+		}
+	}
+}
+
+// handleAlert re-evaluates the alert rules against the stored history of
+// the items inside their active hours, or sends a test message
+func (c *CLI) handleAlert(ctx context.Context, args []string) error {
+	var (
+		dryRun = flag.Bool("dry-run", false, "Log the alerts that would be sent without sending them")
+		test   = flag.String("test", "", "Send this message to every enabled channel and exit")
+	)
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
+	if *test != "" {
+		notifier := notifications.New(c.config, c.logger)
+		if len(notifier.Channels("test")) == 0 {
+			return fmt.Errorf("no notification channel is enabled")
+		}
+		if err := notifier.Send(ctx, notifications.Alert{Rule: "test", Message: *test, Time: time.Now()}); err != nil {
+			return err
+		}
+		c.logger.Info("Test message sent", "channels", strings.Join(notifier.Channels("test"), ","))
+		return nil
+	}
+
+	if *dryRun {
+		c.tracker.DryRunAlerts()
+	}
+	return c.tracker.CheckAlerts(ctx)
+}
+
+func (c *CLI) handleFetch(ctx context.Context, args []string) error {
+	var (
+		url      = flag.String("url", "", "URL to fetch")
+		provider = flag.String("provider", "generic", "Provider type (generic, exec)")
+		selector = flag.String("selector", "", "CSS selector for price extraction")
+		currency = flag.String("currency", "", "Currency code (USD, EUR, TRY, etc.)")
+		regex    = flag.String("regex", "", "Regex pattern for price cleanup")
+		attr     = flag.String("attr", "", "Attribute to extract (text, content, data-price)")
+		command  = flag.String("command", "", "Command for exec provider")
+		language = flag.String("accept-language", "", "Accept-Language header to send (e.g. de-DE)")
+		timeout  = flag.Duration("http-timeout", 0, "HTTP timeout (default: defaults.http_timeout_sec)")
+		insecure = flag.Bool("tls-insecure", false, "Skip TLS certificate verification")
+		caFile   = flag.String("tls-ca-file", "", "PEM CA bundle to trust")
+		try      = flag.String("try", "", "Comma-separated candidate selectors to test against one fetch")
+		jsonFlag = flag.Bool("json", false, "Output in JSON format")
+	)
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
+	if *url == "" {
+		return fmt.Errorf("url is required")
+	}
+	if *provider == "generic" && *selector == "" && *try == "" {
+		return fmt.Errorf("selector is required for generic provider")
+	}
+	if *provider == "exec" && *command == "" {
+		return fmt.Errorf("command is required for exec provider")
+	}
+	if *currency == "" {
+		*currency = c.config.Defaults.Currency
+	}
+
+	item := config.ItemConfig{
+		ID:       "fetch",
+		Name:     "fetch",
+		URL:      *url,
+		Provider: *provider,
+		Selector: *selector,
+		Currency: *currency,
+		Regex:    *regex,
+		Attr:     *attr,
+		Command:  *command,
+
+		AcceptLanguage: *language,
+		HTTPTimeout:    *timeout,
+	}
+	if *insecure || *caFile != "" {
+		item.TLS = &config.TLSConfig{InsecureSkipVerify: *insecure, CAFile: *caFile}
+	}
+
+	// Shares the tracker's fetch path but never touches storage
+	t := tracker.New(c.config, nil, c.logger)
+
+	if *try != "" {
+		return c.trySelectors(ctx, t, item, splitSelectors(*try), *jsonFlag)
+	}
+
+	start := time.Now()
+	sample, err := t.FetchItem(ctx, item)
+	elapsed := time.Since(start)
+	if err != nil {
+		return fmt.Errorf("fetch failed after %v: %w", elapsed.Round(time.Millisecond), err)
+	}
+
+	if *jsonFlag {
+		response := map[string]interface{}{
+			"url":         item.URL,
+			"provider":    item.Provider,
+			"price":       sample.Price,
+			"currency":    sample.Currency,
+			"meta":        sample.Meta,
+			"duration_ms": elapsed.Milliseconds(),
+		}
+		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	fmt.Printf("URL: %s\n", item.URL)
+	fmt.Printf("Provider: %s\n", item.Provider)
+	if raw, ok := sample.Meta["raw"]; ok {
+		fmt.Printf("Matched: %v\n", raw)
+	}
+	fmt.Printf("Price: %s\n", utils.FormatPrice(sample.Price, sample.Currency))
+	fmt.Printf("Currency: %s\n", sample.Currency)
+	fmt.Printf("Time: %v\n", elapsed.Round(time.Millisecond))
+	if len(sample.Meta) > 0 {
+		fmt.Println("Meta:")
+		keys := make([]string, 0, len(sample.Meta))
+		for key := range sample.Meta {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Printf("  %s: %v\n", key, sample.Meta[key])
+		}
+	}
+
+	return nil
+}
+
+// selectorResult is the outcome of one candidate in fetch --try
+type selectorResult struct {
+	Selector string  `json:"selector"`
+	Matched  bool    `json:"matched"`
+	Raw      string  `json:"raw,omitempty"`
+	Price    float64 `json:"price,omitempty"`
+	Currency string  `json:"currency,omitempty"`
+	Error    string  `json:"error,omitempty"`
+}
+
+// trySelectors runs each candidate selector against the same page. The run
+// cache serves every attempt after the first, so the URL is fetched once.
+func (c *CLI) trySelectors(ctx context.Context, t *tracker.Tracker, item config.ItemConfig, candidates []string, jsonOutput bool) error {
+	if len(candidates) == 0 {
+		return fmt.Errorf("no selectors given to --try")
+	}
+
+	ctx, cache := httpclient.WithRunCache(ctx)
+	defer cache.Clear()
+
+	results := make([]selectorResult, 0, len(candidates))
+	for _, candidate := range candidates {
+		item.Selector = candidate
+		result := selectorResult{Selector: candidate}
+
+		sample, err := t.FetchItem(ctx, item)
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			result.Matched = true
+			result.Price = sample.Price
+			result.Currency = sample.Currency
+			if raw, ok := sample.Meta["raw"]; ok {
+				result.Raw = fmt.Sprint(raw)
+			}
+		}
+		results = append(results, result)
+	}
+
+	if jsonOutput {
+		jsonData, err := json.MarshalIndent(results, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	fmt.Printf("URL: %s\n\n", item.URL)
+	fmt.Printf("%-40s %-8s %s\n", "Selector", "Match", "Value")
+	fmt.Println(strings.Repeat("-", 80))
+	matched := 0
+	for _, result := range results {
+		if !result.Matched {
+			fmt.Printf("%-40s %-8s %s\n", truncateString(result.Selector, 40), "no", result.Error)
+			continue
+		}
+		matched++
+		value := utils.FormatPrice(result.Price, result.Currency)
+		if result.Raw != "" {
+			value = fmt.Sprintf("%s (%q)", value, result.Raw)
+		}
+		fmt.Printf("%-40s %-8s %s\n", truncateString(result.Selector, 40), "yes", value)
+	}
+	fmt.Printf("\n%d of %d selectors matched\n", matched, len(results))
+
+	return nil
+}
+
+// jsonOutputFlags checks --compact and --no-meta, turning on --json for
+// --compact
+func jsonOutputFlags(jsonFlag *bool, compact, noMeta bool) error {
+	if noMeta && !compact {
+		return fmt.Errorf("--no-meta requires --compact")
+	}
+	if compact {
+		*jsonFlag = true
+	}
+	return nil
+}
+
+// marshalOutput renders JSON output indented, or on one line without empty
+// fields for --compact
+func marshalOutput(v interface{}, compact, noMeta bool) ([]byte, error) {
+	if compact {
+		return utils.CompactJSON(v, noMeta)
+	}
+	return json.MarshalIndent(v, "", "  ")
+}
+
+// parseProviders splits a --providers list, dropping empty entries
+func parseProviders(list string) []string {
+	var chain []string
+	for _, name := range strings.Split(list, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			chain = append(chain, name)
+		}
+	}
+	return chain
+}
+
+// validateProviders rejects a provider chain naming an unknown provider, so a
+// typo fails here instead of on every fetch
+func validateProviders(chain []string) error {
+	for _, name := range chain {
+		if !config.ValidProvider(name) {
+			return fmt.Errorf("unknown provider %q in --providers; use generic, json, exec or headless", name)
+		}
+	}
+	return nil
+}
+
+// splitSelectors splits a --try list on commas outside brackets, parentheses
+// and quotes, so attribute selectors like [content="1,99"] stay intact
+func splitSelectors(list string) []string {
+	var selectors []string
+	var current strings.Builder
+	depth := 0
+	var quote rune
+
+	flush := func() {
+		if s := strings.TrimSpace(current.String()); s != "" {
+			selectors = append(selectors, s)
+		}
+		current.Reset()
+	}
+
+	for _, r := range list {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '[' || r == '(':
+			depth++
+		case r == ']' || r == ')':
+			if depth > 0 {
+				depth--
+			}
+		case r == ',' && depth == 0:
+			flush()
+			continue
+		}
+		current.WriteRune(r)
+	}
+	flush()
+
+	return selectors
+}
+
+func (c *CLI) handleExport(args []string) error {
+	var (
+		csvFlag    = flag.String("csv", "", "Export to CSV file")
+		yamlFlag   = flag.String("yaml", "", "Export items to a YAML file usable with import --yaml")
+		sqlFlag    = flag.String("sql", "", "Export schema, items and prices as an SQL dump")
+		ndjsonFlag = flag.String("ndjson", "", "Export price history as one JSON object per line (- for stdout)")
+		itemsFlag  = flag.Bool("items", false, "Export items")
+		pricesFlag = flag.Bool("prices", false, "Export price history")
+		itemID     = flag.String("id", "", "Export specific item")
+		rawPrices  = flag.Bool("raw-prices", false, "Write prices at full precision instead of the currency's decimals")
+		groupBy    = flag.String("group-by", "", "Write one row per item and day or week instead of every sample: daily or weekly")
+		metaFlag   = flag.String("meta-fields", "", "Comma-separated meta keys written as meta_<key> columns (price exports)")
+	)
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
+	metaFields := parseMetaFields(*metaFlag)
+	if len(metaFields) > 0 && (*sqlFlag != "" || *yamlFlag != "" || *groupBy != "" || *itemsFlag) {
+		return fmt.Errorf("--meta-fields only applies to --csv --prices and --ndjson")
+	}
+
+	if *groupBy != "" && (*sqlFlag != "" || *ndjsonFlag != "" || *yamlFlag != "") {
+		return fmt.Errorf("--group-by only applies to --csv --prices")
+	}
+
+	if *sqlFlag != "" {
+		return c.exportSQL(*sqlFlag)
+	}
+
+	if *ndjsonFlag != "" {
+		return c.exportNDJSON(*ndjsonFlag, *itemID, metaFields)
+	}
+
+	if *yamlFlag != "" {
+		if *pricesFlag {
+			return fmt.Errorf("--yaml exports items only; use --csv for price history")
+		}
+		return c.exportItemsYAML(*yamlFlag)
+	}
+
+	if *csvFlag == "" {
+		return fmt.Errorf("CSV filename is required (--csv, --yaml, --ndjson or --sql)")
+	}
+
+	if *groupBy != "" || len(metaFields) > 0 {
+		if *itemsFlag {
+			return fmt.Errorf("--group-by only applies to --prices")
+		}
+		*pricesFlag = true
+	}
+
+	if !*itemsFlag && !*pricesFlag {
+		*itemsFlag = true // Default to items
+	}
+
+	priceFormat := csv.PriceCurrency
+	if *rawPrices {
+		priceFormat = csv.PriceRaw
+	}
+
+	if *groupBy != "" {
+		return c.exportAggregates(*csvFlag, *itemID, *groupBy, priceFormat)
+	}
+
+	ctx := context.Background()
+
+	if *itemsFlag {
+		// Export items
+		items, err := c.storage.GetItems(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get items: %w", err)
+		}
+
+		if err := csv.ExportItems(items, *csvFlag); err != nil {
+			return fmt.Errorf("failed to export items: %w", err)
+		}
+
+		c.logger.Info("Items exported successfully", "file", *csvFlag, "count", len(items))
+	}
+
+	if *pricesFlag {
+		// Export prices
+		if *itemID != "" {
+			// Export specific item's prices
+			prices, err := c.storage.GetPrices(ctx, *itemID, 1000) // Get up to 1000 prices
+			if err != nil {
+				return fmt.Errorf("failed to get prices: %w", err)
+			}
+
+			if err := csv.ExportPrices(prices, *csvFlag, priceFormat, metaFields); err != nil {
+				return fmt.Errorf("failed to export prices: %w", err)
+			}
+
+			c.logger.Info("Prices exported successfully", "file", *csvFlag, "item", *itemID, "count", len(prices))
+		} else {
+			// Export all prices
+			allPrices, err := c.recentPrices(ctx)
+			if err != nil {
+				return err
+			}
+
+			if err := csv.ExportPrices(allPrices, *csvFlag, priceFormat, metaFields); err != nil {
+				return fmt.Errorf("failed to export prices: %w", err)
+			}
+
+			c.logger.Info("All prices exported successfully", "file", *csvFlag, "count", len(allPrices))
+		}
+	}
+
+	return nil
+}
+
+// recentPrices returns up to the 1000 newest samples of every item
+func (c *CLI) recentPrices(ctx context.Context) ([]storage.PriceSample, error) {
+	items, err := c.storage.GetItems(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get items: %w", err)
+	}
+
+	var allPrices []storage.PriceSample
+	for _, item := range items {
+		prices, err := c.storage.GetPrices(ctx, item.ID, 1000)
+		if err != nil {
+			c.logger.Error("Failed to get prices for item", "item", item.ID, "error", err)
+			continue
+		}
+		allPrices = append(allPrices, prices...)
+	}
+	return allPrices, nil
+}
+
+// exportOnTrack writes the export configured by export.on_track after a
+// tracking run. The file is written next to its destination and renamed
+// into place, so readers never see a partial export.
+func (c *CLI) exportOnTrack(ctx context.Context) {
+	cfg := c.config.Export
+	if cfg.OnTrack == "" {
+		return
+	}
+
+	tmp := cfg.OnTrack + ".tmp"
+	var (
+		count int
+		err   error
+	)
+	switch {
+	case cfg.Items:
+		var items []storage.Item
+		if items, err = c.storage.GetItems(ctx); err == nil {
+			count = len(items)
+			err = csv.ExportItems(items, tmp)
+		}
+	case isNDJSONPath(cfg.OnTrack):
+		var file *os.File
+		if file, err = os.Create(tmp); err == nil {
+			count, err = c.writePricesNDJSON(ctx, file, "", cfg.MetaFields)
+			if closeErr := file.Close(); err == nil {
+				err = closeErr
+			}
+		}
+	default:
+		var prices []storage.PriceSample
+		if prices, err = c.recentPrices(ctx); err == nil {
+			count = len(prices)
+			err = csv.ExportPrices(prices, tmp, csv.PriceCurrency, cfg.MetaFields)
+		}
+	}
+	if err == nil {
+		err = os.Rename(tmp, cfg.OnTrack)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		c.logger.Error("Failed to export after tracking", "file", cfg.OnTrack, "error", err)
+		return
+	}
+
+	c.logger.Info("Exported after tracking", "file", cfg.OnTrack, "count", count)
+}
+
+// isNDJSONPath reports whether path names a JSON lines file
+func isNDJSONPath(path string) bool {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".ndjson", ".jsonl":
+		return true
+	}
+	return false
+}
+
+// exportSQL writes a logical dump of the database
+func (c *CLI) exportSQL(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	if err := c.storage.DumpSQL(context.Background(), file); err != nil {
+		return fmt.Errorf("failed to export SQL dump: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	c.logger.Info("SQL dump exported successfully", "file", path)
+	return nil
+}
+
+// exportAggregates writes the full price history, or one item's, as daily or
+// weekly aggregates
+func (c *CLI) exportAggregates(path, itemID, granularity string, format csv.PriceFormat) error {
+	aggregator, err := storage.NewAggregator(granularity)
+	if err != nil {
+		return err
+	}
+	err = c.storage.EachPrice(context.Background(), itemID, func(sample storage.PriceSample) error {
+		aggregator.Add(sample)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to get prices: %w", err)
+	}
+
+	aggregates := aggregator.Aggregates()
+	if err := csv.ExportAggregates(aggregates, path, format); err != nil {
+		return fmt.Errorf("failed to export prices: %w", err)
+	}
+
+	c.logger.Info("Aggregated prices exported successfully", "file", path, "group_by", granularity, "rows", len(aggregates))
+	return nil
+}
+
+// exportNDJSON streams price samples to path, one JSON object per line
+func (c *CLI) exportNDJSON(path, itemID string, metaFields []string) error {
+	out := os.Stdout
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return fmt.Errorf("failed to create file: %w", err)
+		}
+		defer file.Close()
+		out = file
+	}
+
+	count, err := c.writePricesNDJSON(context.Background(), out, itemID, metaFields)
+	if err != nil {
+		return err
+	}
+	if out != os.Stdout {
+		if err := out.Close(); err != nil {
+			return fmt.Errorf("failed to write file: %w", err)
+		}
+	}
+
+	c.logger.Info("Prices exported successfully", "file", path, "count", count)
+	return nil
+}
+
+// writePricesNDJSON streams price samples to out, one JSON object per line,
+// and returns how many were written. With metaFields the meta object is
+// replaced by one meta_<key> field per key, null when absent.
+func (c *CLI) writePricesNDJSON(ctx context.Context, out io.Writer, itemID string, metaFields []string) (int, error) {
+	w := bufio.NewWriter(out)
+	encoder := json.NewEncoder(w)
+	var count int
+	err := c.storage.EachPrice(ctx, itemID, func(sample storage.PriceSample) error {
+		count++
+		if len(metaFields) == 0 {
+			return encoder.Encode(sample)
+		}
+		return encoder.Encode(projectMeta(sample, metaFields))
+	})
+	if err != nil {
+		return count, fmt.Errorf("failed to export prices: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		return count, fmt.Errorf("failed to write file: %w", err)
+	}
+	return count, nil
+}
+
+// projectMeta flattens the selected meta keys of sample into a JSON object
+// next to its item_id, time, price and currency
+func projectMeta(sample storage.PriceSample, metaFields []string) map[string]interface{} {
+	row := map[string]interface{}{
+		"item_id":  sample.ItemID,
+		"time":     sample.Time,
+		"price":    sample.Price,
+		"currency": sample.Currency,
+	}
+	for _, key := range metaFields {
+		row["meta_"+key] = sample.Meta[key]
+	}
+	return row
+}
+
+// parseMetaFields splits a comma-separated --meta-fields list, dropping
+// blanks and repeats
+func parseMetaFields(value string) []string {
+	var fields []string
+	seen := make(map[string]bool)
+	for _, key := range strings.Split(value, ",") {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		fields = append(fields, key)
+	}
+	return fields
+}
+
+// exportItemsYAML writes the stored items as a config items list
+func (c *CLI) exportItemsYAML(path string) error {
+	items, err := c.storage.GetItems(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to get items: %w", err)
+	}
+
+	configs := make([]config.ItemConfig, 0, len(items))
+	for _, item := range items {
+		configs = append(configs, item.Config())
+	}
+
+	if err := config.SaveItems(configs, path); err != nil {
+		return fmt.Errorf("failed to export items: %w", err)
+	}
+
+	c.logger.Info("Items exported successfully", "file", path, "count", len(items))
+	return nil
+}
+
+func (c *CLI) handleImport(args []string) error {
+	var (
+		csvFlag = flag.String("csv", "", "Import from CSV file")
+		yamlFlag = flag.String("yaml", "", "Import from YAML file")
+		sqlFlag  = flag.String("sql", "", "Replay an SQL dump written by export --sql")
+		merge    = flag.String("merge-history", "", "Merge items and prices from another machine's export --sql dump or database file")
+		dryRun   = flag.Bool("dry-run", false, "Show what would be created or overwritten without saving")
+		jsonFlag = flag.Bool("json", false, "Output the dry-run plan or merge counts in JSON format")
+
+		allowUnknown = flag.Bool("allow-unknown-currency", false, "Accept currency codes outside ISO 4217 (crypto, custom units)")
+	)
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
+	ctx := context.Background()
+
+	if *merge != "" {
+		if *csvFlag != "" || *yamlFlag != "" || *sqlFlag != "" || *dryRun {
+			return fmt.Errorf("--merge-history cannot be combined with --csv, --yaml, --sql or --dry-run")
+		}
+		return c.mergeHistory(ctx, *merge, *allowUnknown, *jsonFlag)
+	}
+
+	if *sqlFlag != "" {
+		if *dryRun {
+			return fmt.Errorf("--dry-run is not supported for SQL dumps")
+		}
+
+		// Read the dump's items first to reject typos before anything is
+		// saved
+		source, cleanup, err := openHistorySource(ctx, *sqlFlag)
+		if err != nil {
+			return err
+		}
+		items, err := source.GetItems(ctx)
+		cleanup()
+		if err != nil {
+			return fmt.Errorf("failed to read dump items: %w", err)
+		}
+		if err := c.checkItemCurrencies(items, *allowUnknown); err != nil {
+			return err
+		}
+
+		file, err := os.Open(*sqlFlag)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+
+		if err := c.storage.LoadSQL(ctx, file); err != nil {
+			return err
+		}
+		c.logger.Info("SQL dump imported successfully", "file", *sqlFlag)
+		return nil
+	}
+
+	if *csvFlag == "" && *yamlFlag == "" {
+		return fmt.Errorf("import file is required (--csv, --yaml or --sql)")
+	}
+
+	var items []storage.Item
+	if *csvFlag != "" {
+		// Import from CSV
+		csvItems, err := csv.ImportItems(*csvFlag)
+		if err != nil {
+			return fmt.Errorf("failed to import CSV: %w", err)
+		}
+		items = append(items, csvItems...)
+	}
+
+	if *yamlFlag != "" {
+		// Import from YAML
+		// Items are checked against this config below, not the file's own
+		cfg, err := config.Read(*yamlFlag)
+		if err != nil {
+			return fmt.Errorf("failed to load YAML: %w", err)
+		}
+
+		// Convert config items to storage items
+		for _, itemConfig := range cfg.Items {
+			items = append(items, storage.ItemFromConfig(itemConfig))
+		}
+	}
+
+	// Reject typos before anything is saved
+	if err := c.checkItemCurrencies(items, *allowUnknown); err != nil {
+		return err
+	}
+
+	if *dryRun {
+		plan, err := c.planImport(ctx, items)
+		if err != nil {
+			return err
+		}
+		return printImportPlan("Import preview (dry run, nothing saved):", plan, *jsonFlag)
+	}
+
+	// Save items to storage
+	saved := 0
+	for _, item := range items {
+		if item.ID == "" {
+			c.logger.Warn("Skipping item without an id", "name", item.Name)
+			continue
+		}
+		if err := c.storage.SaveItem(ctx, item); err != nil {
+			c.logger.Error("Failed to save item", "item", item.ID, "error", err)
+			continue
+		}
+		saved++
+	}
+
+	c.logger.Info("Import completed", "csv", *csvFlag, "yaml", *yamlFlag, "count", saved)
+	return nil
+}
+
+// mergeResult summarizes import --merge-history
+type mergeResult struct {
+	ItemsCreated  int `json:"items_created"`
+	ItemsExisting int `json:"items_existing"`
+	storage.MergeResult
+}
+
+// mergeBatchSize caps the samples merged per transaction, so a large
+// history is streamed instead of held in memory
+const mergeBatchSize = 5000
+
+// mergeHistory merges another database's items and price history into
+// storage. Items missing here, in the database or the config, are created;
+// items present on both sides keep their local settings and get the union
+// of both histories.
+func (c *CLI) mergeHistory(ctx context.Context, path string, allowUnknown, jsonOutput bool) error {
+	source, cleanup, err := openHistorySource(ctx, path)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	items, err := source.GetItems(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to read source items: %w", err)
+	}
+
+	configured := make(map[string]bool, len(c.config.Items))
+	for _, item := range c.config.Items {
+		configured[item.ID] = true
+	}
+
+	// Only created items take their settings from the source; reject their
+	// typos before anything is saved
+	var created []storage.Item
+	for _, item := range items {
+		existing, err := c.storage.GetItem(ctx, item.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get item: %w", err)
+		}
+		if existing == nil && !configured[item.ID] {
+			created = append(created, item)
+		}
+	}
+	if err := c.checkItemCurrencies(created, allowUnknown); err != nil {
+		return err
+	}
+
+	result := mergeResult{}
+	batch := make([]storage.PriceSample, 0, mergeBatchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		merged, err := c.storage.MergePrices(ctx, batch)
+		if err != nil {
+			return err
+		}
+		result.Added += merged.Added
+		result.Skipped += merged.Skipped
+		batch = batch[:0]
+		return nil
+	}
+
+	for _, item := range items {
+		existing, err := c.storage.GetItem(ctx, item.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get item: %w", err)
+		}
+		if existing == nil && !configured[item.ID] {
+			if err := c.storage.SaveItem(ctx, item); err != nil {
+				return fmt.Errorf("failed to save item %s: %w", item.ID, err)
+			}
+			result.ItemsCreated++
+		} else {
+			result.ItemsExisting++
+		}
+
+		err = source.EachPrice(ctx, item.ID, func(sample storage.PriceSample) error {
+			batch = append(batch, sample)
+			if len(batch) < mergeBatchSize {
+				return nil
+			}
+			return flush()
+		})
+		if err != nil {
+			return fmt.Errorf("failed to merge prices of item %s: %w", item.ID, err)
+		}
+		if err := flush(); err != nil {
+			return fmt.Errorf("failed to merge prices of item %s: %w", item.ID, err)
+		}
+	}
+
+	if jsonOutput {
+		jsonData, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	c.logger.Info("History merged",
+		"file", path,
+		"items_created", result.ItemsCreated,
+		"items_existing", result.ItemsExisting,
+		"added", result.Added,
+		"skipped_duplicates", result.Skipped,
+	)
+	return nil
+}
+
+// sqliteHeader starts every SQLite database file
+var sqliteHeader = []byte("SQLite format 3\x00")
+
+// openHistorySource loads an export --sql dump, or a copy of a database
+// file, into a temporary database, leaving the source file untouched. The
+// returned cleanup closes and removes it.
+func openHistorySource(ctx context.Context, path string) (storage.Storage, func(), error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read file: %w", err)
+	}
+
+	dir, err := os.MkdirTemp("", "pricetrek-merge-")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	dbPath := filepath.Join(dir, "source.db")
+
+	isDatabase := bytes.HasPrefix(data, sqliteHeader)
+	if isDatabase {
+		if err := os.WriteFile(dbPath, data, 0644); err != nil {
+			os.RemoveAll(dir)
+			return nil, nil, fmt.Errorf("failed to copy database: %w", err)
+		}
+		// Recent writes of a database still in use live in its WAL file
+		if wal, err := os.ReadFile(path + "-wal"); err == nil {
+			if err := os.WriteFile(dbPath+"-wal", wal, 0644); err != nil {
+				os.RemoveAll(dir)
+				return nil, nil, fmt.Errorf("failed to copy database: %w", err)
+			}
+		}
+	}
+
+	source, err := storage.New(config.StorageConfig{Driver: "sqlite", Path: dbPath})
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, nil, err
+	}
+	cleanup := func() {
+		source.Close()
+		os.RemoveAll(dir)
+	}
+
+	if !isDatabase {
+		if err := source.Init(); err != nil {
+			cleanup()
+			return nil, nil, fmt.Errorf("failed to initialize temporary database: %w", err)
+		}
+		if err := source.LoadSQL(ctx, bytes.NewReader(data)); err != nil {
+			cleanup()
+			return nil, nil, err
+		}
+	}
+
+	initialized, err := source.Initialized(ctx)
+	if err != nil || !initialized {
+		cleanup()
+		return nil, nil, fmt.Errorf("%s is not a PriceTrek database or SQL dump", path)
+	}
+	return source, cleanup, nil
+}
+
+// Import plan actions
+const (
+	importCreate    = "create"
+	importOverwrite = "overwrite"
+	importSkip      = "skip"
+	importRemove    = "remove"
+)
+
+// fieldChange is one differing field between a stored and an imported item
+type fieldChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// importAction is what an import would do with a single item
+type importAction struct {
+	Action  string        `json:"action"`
+	ItemID  string        `json:"item_id"`
+	Name    string        `json:"name"`
+	Reason  string        `json:"reason,omitempty"`
+	Changes []fieldChange `json:"changes,omitempty"`
+}
+
+// planImport compares imported items against storage without writing
+func (c *CLI) planImport(ctx context.Context, items []storage.Item) ([]importAction, error) {
+	plan := make([]importAction, 0, len(items))
+	seen := make(map[string]bool)
+
+	for _, item := range items {
+		action := importAction{ItemID: item.ID, Name: item.Name}
+
+		switch {
+		case item.ID == "":
+			action.Action = importSkip
+			action.Reason = "missing id"
+		case seen[item.ID]:
+			action.Action = importOverwrite
+			action.Reason = "duplicate id in import; the last occurrence wins"
+		default:
+			existing, err := c.storage.GetItem(ctx, item.ID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get item: %w", err)
+			}
+			if existing == nil {
+				action.Action = importCreate
+				break
+			}
+			changes, err := diffItems(*existing, item)
+			if err != nil {
+				return nil, err
+			}
+			if len(changes) == 0 {
+				action.Action = importSkip
+				action.Reason = "unchanged"
+				break
+			}
+			action.Action = importOverwrite
+			action.Changes = changes
+		}
+
+		seen[item.ID] = true
+		plan = append(plan, action)
+	}
+
+	return plan, nil
+}
+
+// diffItems lists the fields that differ between two items, by JSON name
+func diffItems(old, new storage.Item) ([]fieldChange, error) {
+	toMap := func(item storage.Item) (map[string]interface{}, error) {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal item: %w", err)
+		}
+		fields := make(map[string]interface{})
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal item: %w", err)
+		}
+		return fields, nil
+	}
+
+	oldFields, err := toMap(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := toMap(new)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(oldFields)+len(newFields))
+	for name := range oldFields {
+		names = append(names, name)
+	}
+	for name := range newFields {
+		if _, ok := oldFields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var changes []fieldChange
+	for _, name := range names {
+		if fmt.Sprint(oldFields[name]) != fmt.Sprint(newFields[name]) {
+			changes = append(changes, fieldChange{Field: name, Old: oldFields[name], New: newFields[name]})
+		}
+	}
+	return changes, nil
+}
+
+func printImportPlan(title string, plan []importAction, jsonOutput bool) error {
+	if jsonOutput {
+		jsonData, err := json.MarshalIndent(plan, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	counts := make(map[string]int)
+	fmt.Println(title)
+	for _, action := range plan {
+		counts[action.Action]++
+
+		fmt.Printf("  %-10s %-20s %s", action.Action, action.ItemID, truncateString(action.Name, 30))
+		if action.Reason != "" {
+			fmt.Printf(" (%s)", action.Reason)
+		}
+		fmt.Println()
+		for _, change := range action.Changes {
+			fmt.Printf("      %s: %s -> %s\n", change.Field, formatField(change.Old), formatField(change.New))
+		}
+	}
+	fmt.Printf("\n%d to create, %d to overwrite, %d skipped",
+		counts[importCreate], counts[importOverwrite], counts[importSkip])
+	if counts[importRemove] > 0 {
+		fmt.Printf(", %d to remove", counts[importRemove])
+	}
+	fmt.Println()
+	return nil
+}
+
+func (c *CLI) handleSync(ctx context.Context, args []string) error {
+	var (
+		prune    = flag.Bool("prune", false, "Remove stored items that are not in the config")
+		dryRun   = flag.Bool("dry-run", false, "Print the plan without applying it")
+		jsonFlag = flag.Bool("json", false, "Output the plan in JSON format")
+	)
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
+	items := make([]storage.Item, 0, len(c.config.Items))
+	configured := make(map[string]bool, len(c.config.Items))
+	for _, itemConfig := range c.config.Items {
+		// Same defaults add applies, so synced items match added ones
+		item := storage.ItemFromConfig(itemConfig)
+		if item.Provider == "" {
+			item.Provider = itemConfig.ProviderChain()[0]
+		}
+		if item.Provider == "" {
+			item.Provider = "generic"
+		}
+		if item.Currency == "" {
+			item.Currency = c.config.Defaults.Currency
+		}
+		if item.Schedule == "" {
+			item.Schedule = "hourly"
+		}
+		items = append(items, item)
+		configured[itemConfig.ID] = true
+	}
+
+	plan, err := c.planImport(ctx, items)
+	if err != nil {
+		return err
+	}
+
+	if *prune {
+		stored, err := c.storage.GetItems(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get items: %w", err)
+		}
+		for _, item := range stored {
+			if !configured[item.ID] {
+				plan = append(plan, importAction{
+					Action: importRemove,
+					ItemID: item.ID,
+					Name:   item.Name,
+					Reason: "not in config",
+				})
+			}
+		}
+	}
+
+	title := "Sync plan:"
+	if *dryRun {
+		title = "Sync plan (dry run, nothing saved):"
+	}
+	if err := printImportPlan(title, plan, *jsonFlag); err != nil {
+		return err
+	}
+	if *dryRun {
+		return nil
+	}
+
+	// Plan entries are in config order, so the last duplicate wins as in import
+	applied := 0
+	for i, action := range plan {
+		var err error
+		switch action.Action {
+		case importCreate, importOverwrite:
+			err = c.storage.SaveItem(ctx, items[i])
+		case importRemove:
+			err = c.storage.DeleteItem(ctx, action.ItemID)
+		default:
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to %s item %s: %w", action.Action, action.ItemID, err)
+		}
+		applied++
+	}
+
+	c.logger.Info("Sync completed", "changes", applied)
+	return nil
+}
+
+func formatField(value interface{}) string {
+	if value == nil {
+		return "(unset)"
+	}
+	return fmt.Sprintf("%q", fmt.Sprint(value))
+}
+
+// ExitError ends a command with a non-zero exit code after it has already
+// printed its result, so nothing more is logged
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode is the process exit status to use
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// watchlistStatus is the summary printed by status
+type watchlistStatus struct {
+	Items       int        `json:"items"`
+	BelowTarget []string   `json:"below_target"`
+	Stale       []string   `json:"stale"`
+	Failing     []string   `json:"failing"`
+	LastRun     *time.Time `json:"last_run,omitempty"`
+}
+
+// handleStatus summarizes the watchlist for prompts and status bars. It
+// exits with status 1 when an item is stale or its last fetch failed.
+func (c *CLI) handleStatus(ctx context.Context, args []string) error {
+	var (
+		oneline  = flag.Bool("oneline", false, "Print a single line: \"PriceTrek: N below target, N stale, N failing, last run AGE ago\"")
+		jsonFlag = flag.Bool("json", false, "Output in JSON format")
+	)
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
+	items, err := c.storage.GetItems(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get items: %w", err)
+	}
+	statuses, err := c.storage.GetFetchStatuses(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get fetch statuses: %w", err)
+	}
+
+	status := watchlistStatus{
+		Items:       len(items),
+		BelowTarget: []string{},
+		Stale:       []string{},
+		Failing:     []string{},
+	}
+	for _, item := range items {
+		latest, err := c.storage.GetLatestPrice(ctx, item.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get latest price for %s: %w", item.ID, err)
+		}
+		if latest != nil && item.TargetPrice != nil && latest.Price <= *item.TargetPrice {
+			status.BelowTarget = append(status.BelowTarget, item.ID)
+		}
+		if latest != nil && time.Since(latest.Time) > c.staleThreshold(item.Schedule) {
+			status.Stale = append(status.Stale, item.ID)
+		}
+		if fetch, ok := statuses[item.ID]; ok {
+			if fetch.Status != storage.StatusOK {
+				status.Failing = append(status.Failing, item.ID)
+			}
+			if status.LastRun == nil || fetch.Time.After(*status.LastRun) {
+				last := fetch.Time
+				status.LastRun = &last
+			}
+		}
+	}
+
+	lastRun := "never"
+	if status.LastRun != nil {
+		lastRun = shortAge(time.Since(*status.LastRun)) + " ago"
+	}
+
+	switch {
+	case *jsonFlag:
+		jsonData, err := json.MarshalIndent(status, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+	case *oneline:
+		// Keep this format stable: prompts and status bars parse it
+		fmt.Printf("PriceTrek: %d below target, %d stale, %d failing, last run %s\n",
+			len(status.BelowTarget), len(status.Stale), len(status.Failing), lastRun)
+	default:
+		fmt.Printf("Items:        %d\n", status.Items)
+		fmt.Printf("Below target: %s\n", countedIDs(status.BelowTarget))
+		fmt.Printf("Stale:        %s\n", countedIDs(status.Stale))
+		fmt.Printf("Failing:      %s\n", countedIDs(status.Failing))
+		fmt.Printf("Last run:     %s\n", lastRun)
+	}
+
+	if len(status.Stale) > 0 || len(status.Failing) > 0 {
+		return &ExitError{Code: 1}
+	}
+	return nil
+}
+
+// countedIDs formats a list of item IDs as "2 (a, b)", or "0"
+func countedIDs(ids []string) string {
+	if len(ids) == 0 {
+		return "0"
+	}
+	return fmt.Sprintf("%d (%s)", len(ids), strings.Join(ids, ", "))
+}
+
+// shortAge formats an age in its largest whole unit: 45s, 12m, 3h or 2d
+func shortAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return fmt.Sprintf("%ds", int(age.Seconds()))
+	case age < time.Hour:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	case age < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(age.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(age.Hours()/24))
 	}
+}
 
-	c.logger.Info("Item deleted successfully", "id", itemID)
-	return nil
+// itemVolatility is one row of the stats leaderboard
+type itemVolatility struct {
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Currency string  `json:"currency"`
+	Samples  int     `json:"samples"`
+	Min      float64 `json:"min"`
+	Max      float64 `json:"max"`
+	Avg      float64 `json:"avg"`
+	StdDev   float64 `json:"stddev"`
+	// Volatility is the standard deviation as a percentage of the average,
+	// so items at different price levels compare
+	Volatility float64 `json:"volatility_pct"`
+	RangePct   float64 `json:"range_pct"`
 }
 
-func (c *CLI) handleList(args []string) error {
+// handleStats ranks items by how much their price moved over a recent
+// window, most volatile first
+func (c *CLI) handleStats(ctx context.Context, args []string) error {
+	var itemID string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		itemID, args = args[0], args[1:]
+	}
+
 	var (
+		global   = flag.Bool("global", false, "Rank every item by volatility")
+		window   = flag.String("window", "30d", "How far back to look, e.g. 7d, 2w, 36h")
+		top      = flag.Int("top", 5, "Items shown at each end of the leaderboard")
 		jsonFlag = flag.Bool("json", false, "Output in JSON format")
-		verbose  = flag.Bool("verbose", false, "Show detailed information")
 	)
 
 	// Parse flags
 	flag.CommandLine.Parse(args)
 
-	// Get all items
-	ctx := context.Background()
-	items, err := c.storage.GetItems(ctx)
+	if itemID == "" && !*global {
+		return fmt.Errorf("item ID or --global is required")
+	}
+	span, err := utils.ParseAge(*window)
 	if err != nil {
-		return fmt.Errorf("failed to get items: %w", err)
+		return err
 	}
-
-	if len(items) == 0 {
-		c.logger.Info("No items found")
-		return nil
+	if span <= 0 {
+		return fmt.Errorf("window must be positive")
+	}
+	if *top < 1 {
+		return fmt.Errorf("--top must be at least 1")
 	}
 
-	if *jsonFlag {
-		// Output JSON
-		jsonData, err := json.MarshalIndent(items, "", "  ")
+	var items []storage.Item
+	if itemID != "" {
+		item, err := c.storage.GetItem(ctx, itemID)
 		if err != nil {
-			return fmt.Errorf("failed to marshal JSON: %w", err)
+			return fmt.Errorf("failed to get item: %w", err)
 		}
-		fmt.Println(string(jsonData))
+		if item == nil {
+			return fmt.Errorf("item not found: %s", itemID)
+		}
+		items = append(items, *item)
 	} else {
-		// Output table format
-		c.printItemsTable(items, *verbose)
+		items, err = c.storage.GetItems(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get items: %w", err)
+		}
 	}
 
-	return nil
-}
-
-func (c *CLI) printItemsTable(items []storage.Item, verbose bool) {
-	// Print header
-	fmt.Printf("%-20s %-30s %-15s %-10s %-10s %-10s\n", 
-		"ID", "Name", "Provider", "Currency", "Target", "Schedule")
-	fmt.Println(strings.Repeat("-", 95))
-
-	// Print items
+	since := time.Now().Add(-span)
+	var ranked []itemVolatility
+	var skipped []string
 	for _, item := range items {
-		target := "-"
-		if item.TargetPrice != nil {
-			target = fmt.Sprintf("%.2f", *item.TargetPrice)
+		prices, err := c.storage.GetPricesSince(ctx, item.ID, since)
+		if err != nil {
+			return fmt.Errorf("failed to get price history for %s: %w", item.ID, err)
 		}
 
-		fmt.Printf("%-20s %-30s %-15s %-10s %-10s %-10s\n",
-			item.ID,
-			truncateString(item.Name, 30),
-			item.Provider,
-			item.Currency,
-			target,
-			item.Schedule,
-		)
-
-		if verbose {
-			fmt.Printf("  URL: %s\n", item.URL)
-			if item.Selector != "" {
-				fmt.Printf("  Selector: %s\n", item.Selector)
-			}
-			if item.Regex != "" {
-				fmt.Printf("  Regex: %s\n", item.Regex)
-			}
-			if item.Attr != "" {
-				fmt.Printf("  Attribute: %s\n", item.Attr)
-			}
-			if item.Command != "" {
-				fmt.Printf("  Command: %s\n", item.Command)
+		// Only samples in the latest currency compare with each other
+		var values []float64
+		currency := ""
+		for i := len(prices) - 1; i >= 0; i-- {
+			if currency == "" {
+				currency = prices[i].Currency
 			}
-			if item.PercentDrop != nil {
-				fmt.Printf("  Percent Drop: %.1f%%\n", *item.PercentDrop)
+			if prices[i].Currency == currency {
+				values = append(values, prices[i].Price)
 			}
-			fmt.Println()
 		}
+		if len(values) < 2 {
+			skipped = append(skipped, item.ID)
+			continue
+		}
+
+		min, max, avg, _ := utils.CalculateStats(values)
+		if avg <= 0 {
+			skipped = append(skipped, item.ID)
+			continue
+		}
+		stddev := utils.CalculateStdDev(values)
+		ranked = append(ranked, itemVolatility{
+			ID:         item.ID,
+			Name:       item.Name,
+			Currency:   currency,
+			Samples:    len(values),
+			Min:        min,
+			Max:        max,
+			Avg:        avg,
+			StdDev:     stddev,
+			Volatility: stddev / avg * 100,
+			RangePct:   (max - min) / avg * 100,
+		})
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Volatility > ranked[j].Volatility
+	})
+
+	if *jsonFlag {
+		jsonData, err := json.MarshalIndent(map[string]interface{}{
+			"window":  *window,
+			"items":   ranked,
+			"skipped": skipped,
+		}, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
+		}
+		fmt.Println(string(jsonData))
+		return nil
+	}
+
+	if len(ranked) == 0 {
+		c.logger.Info("No items with at least two samples in the window", "window", *window)
+		return nil
+	}
+
+	fmt.Printf("Volatility over the last %s (standard deviation as %% of the average price)\n", *window)
+	if len(ranked) <= 2**top {
+		fmt.Println()
+		printVolatility(ranked, 0)
+	} else {
+		fmt.Println("\nMost volatile:")
+		printVolatility(ranked[:*top], 0)
+		fmt.Println("\nLeast volatile:")
+		printVolatility(ranked[len(ranked)-*top:], len(ranked)-*top)
 	}
+	if len(skipped) > 0 {
+		fmt.Printf("\n%d item(s) with fewer than two samples in the window left out: %s\n", len(skipped), strings.Join(skipped, ", "))
+	}
+	return nil
 }
 
-func truncateString(s string, maxLen int) string {
-	if len(s) <= maxLen {
-		return s
+// printVolatility prints leaderboard rows, numbered from offset+1
+func printVolatility(rows []itemVolatility, offset int) {
+	fmt.Printf("  %3s  %-30s %7s %10s %8s %12s %12s\n", "#", "ITEM", "SAMPLES", "VOLATILITY", "RANGE", "MIN", "MAX")
+	for i, row := range rows {
+		fmt.Printf("  %3d  %-30s %7d %9.1f%% %7.1f%% %12s %12s\n",
+			offset+i+1,
+			truncateString(row.ID, 30),
+			row.Samples,
+			row.Volatility,
+			row.RangePct,
+			utils.FormatPrice(row.Min, row.Currency),
+			utils.FormatPrice(row.Max, row.Currency),
+		)
 	}
-	return s[:maxLen-3] + "..."
 }
 
-func (c *CLI) handleShow(args []string) error {
+func (c *CLI) handleTotal(ctx context.Context, args []string) error {
 	var (
-		sparkFlag = flag.Bool("spark", false, "Show sparkline")
-		limit     = flag.Int("limit", 30, "Number of price points to show")
-		jsonFlag  = flag.Bool("json", false, "Output in JSON format")
+		currency = flag.String("currency", "", "Currency to total in (default: fx.base or defaults.currency)")
+		jsonFlag = flag.Bool("json", false, "Output in JSON format")
 	)
 
 	// Parse flags
 	flag.CommandLine.Parse(args)
 
-	if len(args) == 0 {
-		return fmt.Errorf("item ID is required")
+	target := strings.ToUpper(*currency)
+	if target == "" {
+		target = strings.ToUpper(c.config.FX.Base)
+	}
+	if target == "" {
+		target = strings.ToUpper(c.config.Defaults.Currency)
 	}
 
-	itemID := args[0]
-
-	// Get item
-	ctx := context.Background()
-	item, err := c.storage.GetItem(ctx, itemID)
+	items, err := c.storage.GetItems(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get item: %w", err)
+		return fmt.Errorf("failed to get items: %w", err)
 	}
-	if item == nil {
-		return fmt.Errorf("item not found: %s", itemID)
+
+	type subtotal struct {
+		Currency  string  `json:"currency"`
+		Items     int     `json:"items"`
+		Amount    float64 `json:"amount"`
+		Converted float64 `json:"converted"`
 	}
+	type excluded struct {
+		ItemID string `json:"item_id"`
+		Reason string `json:"reason"`
+	}
+	var (
+		subtotals = make(map[string]*subtotal)
+		skipped   []excluded
+		total     float64
+		rates     *fx.Rates
+		ratesErr  error
+	)
 
-	// Get price history
-	prices, err := c.storage.GetPrices(ctx, itemID, *limit)
-	if err != nil {
-		return fmt.Errorf("failed to get price history: %w", err)
+	for _, item := range items {
+		latest, err := c.storage.GetLatestPrice(ctx, item.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get latest price: %w", err)
+		}
+		if latest == nil {
+			skipped = append(skipped, excluded{item.ID, "no price yet"})
+			continue
+		}
+
+		from := strings.ToUpper(latest.Currency)
+		converted := latest.Price
+		if from != target {
+			// Rates are only loaded once a conversion is actually needed
+			if rates == nil && ratesErr == nil {
+				if rates, ratesErr = fx.LoadCached(ctx, c.config.FX, c.storage, false); ratesErr != nil {
+					c.logger.Warn("Failed to load exchange rates", "error", ratesErr)
+				} else if rates.Stale {
+					c.logger.Warn("Refreshing exchange rates failed, converting with stale cached rates", "fetched", rates.Fetched.Format(time.RFC3339))
+				}
+			}
+			if ratesErr != nil {
+				skipped = append(skipped, excluded{item.ID, "no exchange rates: " + ratesErr.Error()})
+				continue
+			}
+			if converted, err = rates.Convert(latest.Price, from, target); err != nil {
+				skipped = append(skipped, excluded{item.ID, err.Error()})
+				continue
+			}
+		}
+
+		sub, ok := subtotals[from]
+		if !ok {
+			sub = &subtotal{Currency: from}
+			subtotals[from] = sub
+		}
+		sub.Items++
+		sub.Amount += latest.Price
+		sub.Converted += converted
+		total += converted
 	}
 
-	if len(prices) == 0 {
-		c.logger.Info("No price history found for item", "id", itemID)
-		return nil
+	currencies := make([]string, 0, len(subtotals))
+	for code := range subtotals {
+		currencies = append(currencies, code)
 	}
+	sort.Strings(currencies)
 
 	if *jsonFlag {
-		// Output JSON
-		response := map[string]interface{}{
-			"item":   item,
-			"prices": prices,
+		result := struct {
+			Currency  string     `json:"currency"`
+			Total     float64    `json:"total"`
+			Subtotals []subtotal `json:"subtotals"`
+			Excluded  []excluded `json:"excluded"`
+		}{Currency: target, Total: total, Subtotals: []subtotal{}, Excluded: skipped}
+		for _, code := range currencies {
+			result.Subtotals = append(result.Subtotals, *subtotals[code])
 		}
-		jsonData, err := json.MarshalIndent(response, "", "  ")
+		if result.Excluded == nil {
+			result.Excluded = []excluded{}
+		}
+		jsonData, err := json.MarshalIndent(result, "", "  ")
 		if err != nil {
 			return fmt.Errorf("failed to marshal JSON: %w", err)
 		}
 		fmt.Println(string(jsonData))
-	} else {
-		// Output formatted display
-		c.printItemDetails(item, prices, *sparkFlag)
-	}
-
-	return nil
-}
-
-func (c *CLI) printItemDetails(item *storage.Item, prices []storage.PriceSample, showSparkline bool) {
-	fmt.Printf("Item: %s (%s)\n", item.Name, item.ID)
-	fmt.Printf("URL: %s\n", item.URL)
-	fmt.Printf("Provider: %s\n", item.Provider)
-	fmt.Printf("Currency: %s\n", item.Currency)
-	fmt.Printf("Schedule: %s\n", item.Schedule)
-	
-	if item.TargetPrice != nil {
-		fmt.Printf("Target Price: %.2f %s\n", *item.TargetPrice, item.Currency)
-	}
-	if item.PercentDrop != nil {
-		fmt.Printf("Percent Drop Alert: %.1f%%\n", *item.PercentDrop)
-	}
-	
-	fmt.Println()
-
-	// Show price history
-	fmt.Printf("Price History (%d points):\n", len(prices))
-	fmt.Println(strings.Repeat("-", 50))
-
-	// Extract price values for sparkline
-	priceValues := make([]float64, len(prices))
-	for i, price := range prices {
-		priceValues[i] = price.Price
+		return nil
 	}
 
-	// Show sparkline if requested
-	if showSparkline && len(priceValues) > 1 {
-		sparkline := utils.GenerateSparkline(priceValues, 50)
-		fmt.Printf("Price Trend: %s\n", sparkline)
-		fmt.Println()
+	fmt.Printf("%-10s %-6s %15s %15s\n", "Currency", "Items", "Subtotal", "In "+target)
+	fmt.Println(strings.Repeat("-", 49))
+	for _, code := range currencies {
+		sub := subtotals[code]
+		fmt.Printf("%-10s %-6d %15s %15s\n",
+			code,
+			sub.Items,
+			utils.FormatAmount(sub.Amount, code),
+			utils.FormatAmount(sub.Converted, target),
+		)
 	}
+	fmt.Println(strings.Repeat("-", 49))
+	fmt.Printf("Total: %s\n", utils.FormatPrice(total, target))
 
-	// Show recent prices
-	for i, price := range prices {
-		if i >= 10 { // Show only last 10
-			break
-		}
-		formattedPrice := utils.FormatPrice(price.Price, price.Currency)
-		fmt.Printf("%s: %s", price.Time.Format("2006-01-02 15:04:05"), formattedPrice)
-		
-		// Show price change if we have previous price
-		if i < len(prices)-1 {
-			prevPrice := prices[i+1].Price
-			change := utils.CalculatePriceChange(prevPrice, price.Price)
-			if change > 0 {
-				fmt.Printf(" (+%.1f%%)", change)
-			} else if change < 0 {
-				fmt.Printf(" (%.1f%%)", change)
-			}
+	if len(skipped) > 0 {
+		fmt.Printf("\nExcluded (%d):\n", len(skipped))
+		for _, ex := range skipped {
+			fmt.Printf("  %s: %s\n", ex.ItemID, ex.Reason)
 		}
-		fmt.Println()
 	}
 
-	// Show statistics
-	if len(priceValues) > 1 {
-		min, max, avg, median := utils.CalculateStats(priceValues)
-		fmt.Println()
-		fmt.Printf("Statistics:\n")
-		fmt.Printf("  Min: %s\n", utils.FormatPrice(min, item.Currency))
-		fmt.Printf("  Max: %s\n", utils.FormatPrice(max, item.Currency))
-		fmt.Printf("  Avg: %s\n", utils.FormatPrice(avg, item.Currency))
-		fmt.Printf("  Median: %s\n", utils.FormatPrice(median, item.Currency))
-	}
+	return nil
 }
 
-func (c *CLI) handleTrack(ctx context.Context, args []string) error {
+func (c *CLI) handleCompact(ctx context.Context, args []string) error {
 	var (
-		onceFlag     = flag.Bool("once", false, "Run tracking once")
-		loopFlag     = flag.Bool("loop", false, "Run tracking in a loop")
-		itemID       = flag.String("id", "", "Track specific item ID")
-		noCacheFlag  = flag.Bool("no-cache", false, "Disable caching")
-		respectCache = flag.Bool("respect-cache", false, "Respect cache TTL")
-		interval     = flag.Duration("interval", 1*time.Hour, "Loop interval")
+		olderThan = flag.String("older-than", "90d", "Compact samples older than this age (e.g. 90d, 12w)")
+		to        = flag.String("to", storage.GranularityDaily, "Target granularity (daily, weekly)")
+		jsonFlag  = flag.Bool("json", false, "Output in JSON format")
 	)
 
 	// Parse flags
 	flag.CommandLine.Parse(args)
 
-	// Determine mode
-	if *onceFlag && *loopFlag {
-		return fmt.Errorf("cannot specify both --once and --loop")
+	age, err := utils.ParseAge(*olderThan)
+	if err != nil {
+		return err
 	}
-	if !*onceFlag && !*loopFlag {
-		*onceFlag = true // Default to once
+	if age <= 0 {
+		return fmt.Errorf("--older-than must be positive")
 	}
 
-	if *onceFlag {
-		return c.trackOnce(ctx, *itemID, *noCacheFlag, *respectCache)
-	} else {
-		return c.trackLoop(ctx, *itemID, *noCacheFlag, *respectCache, *interval)
+	cutoff := time.Now().Add(-age)
+	result, err := c.storage.CompactPrices(ctx, cutoff, *to)
+	if err != nil {
+		return fmt.Errorf("failed to compact prices: %w", err)
 	}
-}
-
-func (c *CLI) trackOnce(ctx context.Context, itemID string, noCache, respectCache bool) error {
-	c.logger.Info("Starting one-time price tracking")
 
-	if itemID != "" {
-		// Track specific item
-		item, err := c.storage.GetItem(ctx, itemID)
+	if *jsonFlag {
+		jsonData, err := json.Marshal(result)
 		if err != nil {
-			return fmt.Errorf("failed to get item: %w", err)
-		}
-		if item == nil {
-			return fmt.Errorf("item not found: %s", itemID)
-		}
-
-		// Convert to config format
-		itemConfig := config.ItemConfig{
-			ID:          item.ID,
-			Name:        item.Name,
-			URL:         item.URL,
-			Provider:    item.Provider,
-			Selector:    item.Selector,
-			Currency:    item.Currency,
-			TargetPrice: item.TargetPrice,
-			PercentDrop: item.PercentDrop,
-			Schedule:    item.Schedule,
-			Regex:       item.Regex,
-			Attr:        item.Attr,
-			Command:     item.Command,
+			return fmt.Errorf("failed to marshal JSON: %w", err)
 		}
-
-		return c.tracker.TrackItem(ctx, itemConfig)
-	} else {
-		// Track all items
-		return c.tracker.TrackAll(ctx)
+		fmt.Println(string(jsonData))
+		return nil
 	}
+
+	c.logger.Info("Price history compacted",
+		"before", cutoff.Format("2006-01-02"),
+		"granularity", *to,
+		"buckets", result.Buckets,
+		"removed", result.Removed,
+		"inserted", result.Inserted,
+	)
+	return nil
 }
 
-func (c *CLI) trackLoop(ctx context.Context, itemID string, noCache, respectCache bool, interval time.Duration) error {
-	c.logger.Info("Starting continuous price tracking", "interval", interval)
+func (c *CLI) handleCleanMeta(ctx context.Context, args []string) error {
+	var (
+		keysFlag = flag.String("keys", "", "Comma-separated meta keys to keep on every stored price")
+		allFlag  = flag.Bool("all", false, "Remove all meta except the summaries of compacted rows")
+		jsonFlag = flag.Bool("json", false, "Output in JSON format")
+	)
 
-	ticker := time.NewTicker(interval)
-	defer ticker.Stop()
+	// Parse flags
+	flag.CommandLine.Parse(args)
 
-	for {
-		select {
-		case <-ctx.Done():
-			c.logger.Info("Tracking stopped")
-			return ctx.Err()
-		case <-ticker.C:
-			c.logger.Info("Running scheduled tracking")
-			if err := c.trackOnce(ctx, itemID, noCache, respectCache); err != nil {
-				c.logger.Error("Tracking failed", "error", err)
-			}
+	keep := parseMetaFields(*keysFlag)
+	if *allFlag == (len(keep) > 0) {
+		return fmt.Errorf("pass either --keys with the meta keys to keep or --all")
+	}
+
+	result, err := c.storage.CleanMeta(ctx, keep)
+	if err != nil {
+		return fmt.Errorf("failed to clean meta: %w", err)
+	}
+
+	if *jsonFlag {
+		jsonData, err := json.Marshal(result)
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
 		}
+		fmt.Println(string(jsonData))
+		return nil
 	}
-}
 
-func (c *CLI) handleAlert(ctx context.Context, args []string) error {
-	// TODO: Implement alert command
-	c.logger.Info("Alert command not yet implemented")
+	c.logger.Info("Price meta cleaned",
+		"kept", strings.Join(keep, ","),
+		"rows", result.Rows,
+		"reclaimed", formatSize(result.Reclaimed),
+	)
 	return nil
 }
 
-func (c *CLI) handleExport(args []string) error {
+// formatSize renders a byte count with a binary unit, e.g. 1.5 MiB
+func formatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	value, exp := float64(bytes)/unit, 0
+	for value >= unit && exp < 3 {
+		value /= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, "KMGT"[exp])
+}
+
+func (c *CLI) handleEvents(ctx context.Context, args []string) error {
 	var (
-		csvFlag    = flag.String("csv", "", "Export to CSV file")
-		itemsFlag  = flag.Bool("items", false, "Export items")
-		pricesFlag = flag.Bool("prices", false, "Export price history")
-		itemID     = flag.String("id", "", "Export specific item")
+		itemID   = flag.String("id", "", "Only show events for this item")
+		since    = flag.String("since", "7d", "Show events newer than this age (e.g. 24h, 7d)")
+		limit    = flag.Int("limit", 100, "Maximum number of events to show")
+		jsonFlag = flag.Bool("json", false, "Output in JSON format")
 	)
 
 	// Parse flags
 	flag.CommandLine.Parse(args)
 
-	if *csvFlag == "" {
-		return fmt.Errorf("CSV filename is required (--csv)")
+	age, err := utils.ParseAge(*since)
+	if err != nil {
+		return err
 	}
 
-	if !*itemsFlag && !*pricesFlag {
-		*itemsFlag = true // Default to items
+	events, err := c.storage.GetEvents(ctx, *itemID, time.Now().Add(-age), *limit)
+	if err != nil {
+		return fmt.Errorf("failed to get events: %w", err)
 	}
 
-	ctx := context.Background()
-
-	if *itemsFlag {
-		// Export items
-		items, err := c.storage.GetItems(ctx)
-		if err != nil {
-			return fmt.Errorf("failed to get items: %w", err)
+	if *jsonFlag {
+		if events == nil {
+			events = []storage.Event{}
 		}
-
-		if err := csv.ExportItems(items, *csvFlag); err != nil {
-			return fmt.Errorf("failed to export items: %w", err)
+		jsonData, err := json.MarshalIndent(events, "", "  ")
+		if err != nil {
+			return fmt.Errorf("failed to marshal JSON: %w", err)
 		}
-
-		c.logger.Info("Items exported successfully", "file", *csvFlag, "count", len(items))
+		fmt.Println(string(jsonData))
+		return nil
 	}
 
-	if *pricesFlag {
-		// Export prices
-		if *itemID != "" {
-			// Export specific item's prices
-			prices, err := c.storage.GetPrices(ctx, *itemID, 1000) // Get up to 1000 prices
-			if err != nil {
-				return fmt.Errorf("failed to get prices: %w", err)
-			}
-
-			if err := csv.ExportPrices(prices, *csvFlag); err != nil {
-				return fmt.Errorf("failed to export prices: %w", err)
-			}
-
-			c.logger.Info("Prices exported successfully", "file", *csvFlag, "item", *itemID, "count", len(prices))
+	if len(events) == 0 {
+		if !c.config.Storage.Events {
+			c.logger.Info("No events found; enable recording with storage.events: true")
 		} else {
-			// Export all prices
-			items, err := c.storage.GetItems(ctx)
-			if err != nil {
-				return fmt.Errorf("failed to get items: %w", err)
-			}
-
-			var allPrices []storage.PriceSample
-			for _, item := range items {
-				prices, err := c.storage.GetPrices(ctx, item.ID, 1000)
-				if err != nil {
-					c.logger.Error("Failed to get prices for item", "item", item.ID, "error", err)
-					continue
-				}
-				allPrices = append(allPrices, prices...)
-			}
-
-			if err := csv.ExportPrices(allPrices, *csvFlag); err != nil {
-				return fmt.Errorf("failed to export prices: %w", err)
-			}
-
-			c.logger.Info("All prices exported successfully", "file", *csvFlag, "count", len(allPrices))
+			c.logger.Info("No events found")
 		}
+		return nil
 	}
 
+	for _, event := range events {
+		fmt.Printf("%s  %-20s %-16s %s\n",
+			event.Time.Local().Format("2006-01-02 15:04:05"),
+			event.ItemID,
+			event.Kind,
+			event.Detail,
+		)
+	}
 	return nil
 }
 
-func (c *CLI) handleImport(args []string) error {
+// itemVerification is the outcome of one item in verify-items
+type itemVerification struct {
+	ID        string   `json:"id"`
+	Status    string   `json:"status"`
+	Price     float64  `json:"price,omitempty"`
+	Currency  string   `json:"currency,omitempty"`
+	LastPrice *float64 `json:"last_price,omitempty"`
+	ChangePct *float64 `json:"change_pct,omitempty"`
+	Issues    []string `json:"issues,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// Verification statuses reported by verify-items
+const (
+	verifyOK         = "ok"
+	verifySuspicious = "suspicious"
+	verifyFailed     = "failed"
+)
+
+// verifyStatusColors colors the verify-items status column
+var verifyStatusColors = map[string]string{
+	verifyOK:         utils.ColorGreen,
+	verifySuspicious: utils.ColorYellow,
+	verifyFailed:     utils.ColorRed,
+}
+
+// handleVerifyItems fetches every stored item once and reports whether its
+// provider still yields a plausible price. Nothing is written to history.
+func (c *CLI) handleVerifyItems(ctx context.Context, args []string) error {
 	var (
-		csvFlag = flag.String("csv", "", "Import from CSV file")
-		yamlFlag = flag.String("yaml", "", "Import from YAML file")
+		itemID      = flag.String("id", "", "Verify a specific item ID")
+		jsonFlag    = flag.Bool("json", false, "Output in JSON format")
+		maxChange   = flag.Float64("max-change", 50, "Flag prices that moved more than this percentage from the last stored price")
+		concurrency = flag.Int("concurrency", 4, "Number of items checked at once")
+		perHost     = flag.Int("per-host", c.config.Defaults.PerHostConcurrency, "Number of items on the same host checked at once")
+		timeout     = flag.Duration("timeout", 30*time.Second, "Give up on a single item after this long")
 	)
 
 	// Parse flags
 	flag.CommandLine.Parse(args)
 
-	if *csvFlag == "" && *yamlFlag == "" {
-		return fmt.Errorf("import file is required (--csv or --yaml)")
-	}
-
-	ctx := context.Background()
-
-	if *csvFlag != "" {
-		// Import from CSV
-		items, err := csv.ImportItems(*csvFlag)
+	var items []storage.Item
+	if *itemID != "" {
+		item, err := c.storage.GetItem(ctx, *itemID)
 		if err != nil {
-			return fmt.Errorf("failed to import CSV: %w", err)
+			return fmt.Errorf("failed to get item: %w", err)
+		}
+		if item == nil {
+			return fmt.Errorf("item not found: %s", *itemID)
+		}
+		items = append(items, *item)
+	} else {
+		var err error
+		items, err = c.storage.GetItems(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to get items: %w", err)
 		}
+	}
 
-		// Save items to storage
-		for _, item := range items {
-			if err := c.storage.SaveItem(ctx, item); err != nil {
-				c.logger.Error("Failed to save item", "item", item.ID, "error", err)
-				continue
-			}
+	// Items sharing a page are fetched once, as in a tracking run
+	ctx, cache := httpclient.WithRunCache(ctx)
+	defer cache.Clear()
+
+	// Hung sites only cost their own timeout; results keep the item order
+	results := make([]itemVerification, len(items))
+	for i, item := range items {
+		results[i] = itemVerification{ID: item.ID, Status: verifyFailed, Error: "not checked"}
+	}
+	// Fetches to one retailer are capped so a large watchlist doesn't
+	// hammer it; items without a host each count on their own
+	hostOf := func(i int) string {
+		if host := httpclient.HostKey(items[i].URL); host != "" {
+			return host
 		}
+		return "\x00" + items[i].ID
+	}
+	utils.ForEachLimited(ctx, len(items), *concurrency, *perHost, hostOf, *timeout, func(ctx context.Context, i int) {
+		results[i] = c.verifyItem(ctx, items[i].Config(), *maxChange)
+	})
 
-		c.logger.Info("CSV import completed", "file", *csvFlag, "count", len(items))
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Status]++
 	}
 
-	if *yamlFlag != "" {
-		// Import from YAML
-		cfg, err := config.Load(*yamlFlag)
+	if *jsonFlag {
+		jsonData, err := json.MarshalIndent(struct {
+			Items      []itemVerification `json:"items"`
+			Total      int                `json:"total"`
+			OK         int                `json:"ok"`
+			Suspicious int                `json:"suspicious"`
+			Failed     int                `json:"failed"`
+		}{results, len(results), counts[verifyOK], counts[verifySuspicious], counts[verifyFailed]}, "", "  ")
 		if err != nil {
-			return fmt.Errorf("failed to load YAML: %w", err)
+			return fmt.Errorf("failed to marshal JSON: %w", err)
 		}
-
-		// Convert config items to storage items
-		for _, itemConfig := range cfg.Items {
-			item := storage.Item{
-				ID:          itemConfig.ID,
-				Name:        itemConfig.Name,
-				URL:         itemConfig.URL,
-				Provider:    itemConfig.Provider,
-				Selector:    itemConfig.Selector,
-				Currency:    itemConfig.Currency,
-				TargetPrice: itemConfig.TargetPrice,
-				PercentDrop: itemConfig.PercentDrop,
-				Schedule:    itemConfig.Schedule,
-				Regex:       itemConfig.Regex,
-				Attr:        itemConfig.Attr,
-				Command:     itemConfig.Command,
+		fmt.Println(string(jsonData))
+	} else {
+		fmt.Printf("%-20s %-11s %-16s %s\n", "ID", "Status", "Price", "Details")
+		fmt.Println(strings.Repeat("-", 80))
+		for _, result := range results {
+			price := "-"
+			if result.Status != verifyFailed {
+				price = utils.FormatPrice(result.Price, result.Currency)
 			}
-
-			if err := c.storage.SaveItem(ctx, item); err != nil {
-				c.logger.Error("Failed to save item", "item", item.ID, "error", err)
-				continue
+			details := result.Error
+			if details == "" {
+				details = strings.Join(result.Issues, "; ")
 			}
+			status := utils.Colorize(verifyStatusColors[result.Status], fmt.Sprintf("%-11s", result.Status))
+			fmt.Printf("%-20s %s %-16s %s\n", truncateString(result.ID, 20), status, price, details)
 		}
-
-		c.logger.Info("YAML import completed", "file", *yamlFlag, "count", len(cfg.Items))
+		fmt.Printf("\n%d item(s): %d ok, %d suspicious, %d failed\n",
+			len(results), counts[verifyOK], counts[verifySuspicious], counts[verifyFailed])
 	}
 
+	if bad := counts[verifySuspicious] + counts[verifyFailed]; bad > 0 {
+		return fmt.Errorf("%d of %d item(s) failed verification", bad, len(results))
+	}
 	return nil
 }
 
-func (c *CLI) handleDoctor(args []string) error {
-	c.logger.Info("Running PriceTrek health check...")
-	
-	var issues []string
-	
-	// Check database connection
-	if err := c.checkDatabase(); err != nil {
-		issues = append(issues, fmt.Sprintf("Database: %v", err))
-	} else {
-		c.logger.Info("✓ Database connection OK")
+// verifyItem fetches one item without storing the sample and flags prices
+// that parsed but look wrong
+func (c *CLI) verifyItem(ctx context.Context, item config.ItemConfig, maxChange float64) itemVerification {
+	result := itemVerification{ID: item.ID}
+
+	sample, err := c.tracker.FetchItem(ctx, item)
+	if err != nil {
+		result.Status = verifyFailed
+		result.Error = err.Error()
+		return result
 	}
-	
-	// Check network connectivity
-	if err := c.checkNetwork(); err != nil {
-		issues = append(issues, fmt.Sprintf("Network: %v", err))
-	} else {
-		c.logger.Info("✓ Network connectivity OK")
+	result.Price = sample.Price
+	result.Currency = sample.Currency
+
+	if sample.Price <= 0 {
+		result.Issues = append(result.Issues, "price is not positive")
 	}
-	
-	// Check providers
-	if err := c.checkProviders(); err != nil {
-		issues = append(issues, fmt.Sprintf("Providers: %v", err))
-	} else {
-		c.logger.Info("✓ Providers OK")
+	if detected, ok := sample.Meta["detected_currency"]; ok {
+		result.Issues = append(result.Issues, fmt.Sprintf("page currency %v differs from configured %s", detected, item.Currency))
 	}
-	
-	// Check notifications
-	if err := c.checkNotifications(); err != nil {
-		issues = append(issues, fmt.Sprintf("Notifications: %v", err))
-	} else {
-		c.logger.Info("✓ Notifications OK")
+
+	last, err := c.storage.GetLatestPrice(ctx, item.ID)
+	if err != nil {
+		c.logger.Warn("Failed to get last price", "item", item.ID, "error", err)
 	}
-	
-	// Check configuration
-	if err := c.checkConfiguration(); err != nil {
-		issues = append(issues, fmt.Sprintf("Configuration: %v", err))
-	} else {
-		c.logger.Info("✓ Configuration OK")
+	if last != nil && last.Price > 0 {
+		change := (sample.Price - last.Price) / last.Price * 100
+		result.LastPrice = &last.Price
+		result.ChangePct = &change
+		if maxChange > 0 && math.Abs(change) > maxChange {
+			result.Issues = append(result.Issues, fmt.Sprintf("price moved %+.1f%% from last stored %s", change, utils.FormatPrice(last.Price, last.Currency)))
+		}
 	}
-	
+
+	result.Status = verifyOK
+	if len(result.Issues) > 0 {
+		result.Status = verifySuspicious
+	}
+	return result
+}
+
+// doctorCheck is one health check run by doctor
+type doctorCheck struct {
+	name  string
+	ok    string
+	check func(ctx context.Context) error
+}
+
+func (c *CLI) handleDoctor(ctx context.Context, args []string) error {
+	var (
+		concurrency = flag.Int("concurrency", 4, "Number of checks run at once")
+		timeout     = flag.Duration("timeout", 10*time.Second, "Give up on a single check after this long")
+	)
+
+	// Parse flags
+	flag.CommandLine.Parse(args)
+
+	info := buildinfo.Get()
+	c.logger.Info("Running PriceTrek health check...",
+		"version", info.Version,
+		"commit", info.Commit,
+		"date", info.Date,
+		"go", info.GoVersion,
+	)
+
+	checks := []doctorCheck{
+		{"Database", "Database connection OK", c.checkDatabase},
+		{"Network", "Network connectivity OK", c.checkNetwork},
+		{"Providers", "Providers OK", c.checkProviders},
+		// Items blocked by anti-bot pages
+		{"Fetches", "No blocked items", c.checkBlocked},
+		// Items that stopped updating
+		{"Freshness", "All items up to date", c.checkStale},
+		{"Notifications", "Notifications OK", c.checkNotifications},
+		{"Configuration", "Configuration OK", c.checkConfiguration},
+	}
+
+	// Slow network checks run side by side; results are reported in order
+	errs := make([]error, len(checks))
+	for i := range errs {
+		errs[i] = fmt.Errorf("not checked")
+	}
+	utils.ForEach(ctx, len(checks), *concurrency, *timeout, func(ctx context.Context, i int) {
+		errs[i] = checks[i].check(ctx)
+		if errs[i] != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			errs[i] = fmt.Errorf("timed out after %v: %w", *timeout, errs[i])
+		}
+	})
+
+	var issues []string
+	for i, check := range checks {
+		if errs[i] != nil {
+			issues = append(issues, fmt.Sprintf("%s: %v", check.name, errs[i]))
+			continue
+		}
+		c.logger.Info("✓ " + check.ok)
+	}
+
 	if len(issues) > 0 {
 		c.logger.Error("Health check found issues:")
 		for _, issue := range issues {
@@ -849,16 +4218,27 @@ func (c *CLI) handleDoctor(args []string) error {
 	return nil
 }
 
-func (c *CLI) checkDatabase() error {
-	ctx := context.Background()
-	_, err := c.storage.GetItems(ctx)
+func (c *CLI) checkDatabase(ctx context.Context) error {
+	initialized, err := c.storage.Initialized(ctx)
+	if err != nil {
+		return err
+	}
+	if !initialized {
+		return storage.ErrNotInitialized
+	}
+	_, err = c.storage.GetItems(ctx)
 	return err
 }
 
-func (c *CLI) checkNetwork() error {
+func (c *CLI) checkNetwork(ctx context.Context) error {
 	// Simple network check by trying to connect to a reliable endpoint
-	client := &http.Client{Timeout: 5 * time.Second}
-	resp, err := client.Get("https://httpbin.org/status/200")
+	// Use the shared transport so proxy settings are checked too
+	client := &http.Client{Transport: httpclient.Client().Transport, Timeout: 5 * time.Second}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://httpbin.org/status/200", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
@@ -866,7 +4246,7 @@ func (c *CLI) checkNetwork() error {
 	return nil
 }
 
-func (c *CLI) checkProviders() error {
+func (c *CLI) checkProviders(ctx context.Context) error {
 	// Test generic provider
 	provider, err := providers.GetProvider("generic", c.config.Defaults)
 	if err != nil {
@@ -881,12 +4261,54 @@ func (c *CLI) checkProviders() error {
 		Currency: "USD",
 	}
 	
-	ctx := context.Background()
 	_, err = provider.Fetch(ctx, testItem)
 	return err
 }
 
-func (c *CLI) checkNotifications() error {
+func (c *CLI) checkBlocked(ctx context.Context) error {
+	if initialized, err := c.storage.Initialized(ctx); err != nil || !initialized {
+		return nil // reported by the database check
+	}
+	statuses, err := c.storage.GetFetchStatuses(ctx)
+	if err != nil {
+		return err
+	}
+
+	var blocked []string
+	for id, status := range statuses {
+		if status.Status == storage.StatusBlocked {
+			blocked = append(blocked, id)
+		}
+	}
+	if len(blocked) > 0 {
+		sort.Strings(blocked)
+		return fmt.Errorf("%d item(s) last served a CAPTCHA/soft-block page: %s", len(blocked), strings.Join(blocked, ", "))
+	}
+	return nil
+}
+
+func (c *CLI) checkStale(ctx context.Context) error {
+	if initialized, err := c.storage.Initialized(ctx); err != nil || !initialized {
+		return nil // reported by the database check
+	}
+	items, err := c.storage.GetItems(ctx)
+	if err != nil {
+		return err
+	}
+
+	var stale []string
+	for _, item := range items {
+		if age, ok := c.staleness(item); ok {
+			stale = append(stale, fmt.Sprintf("%s (%s)", item.ID, age.Round(time.Hour)))
+		}
+	}
+	if len(stale) > 0 {
+		return fmt.Errorf("%d item(s) have no recent samples: %s", len(stale), strings.Join(stale, ", "))
+	}
+	return nil
+}
+
+func (c *CLI) checkNotifications(ctx context.Context) error {
 	// Check if notification services are properly configured
 	if c.config.Notifications.Email.Enabled && c.config.Notifications.Email.From == "" {
 		return fmt.Errorf("email notifications enabled but no 'from' address configured")
@@ -897,10 +4319,13 @@ func (c *CLI) checkNotifications() error {
 	if c.config.Notifications.Slack.Enabled && c.config.Notifications.Slack.Webhook == "" {
 		return fmt.Errorf("slack notifications enabled but no webhook configured")
 	}
+	if c.config.Notifications.Exec.Enabled && c.config.Notifications.Exec.Command == "" {
+		return fmt.Errorf("exec notifications enabled but no command configured")
+	}
 	return nil
 }
 
-func (c *CLI) checkConfiguration() error {
+func (c *CLI) checkConfiguration(ctx context.Context) error {
 	// Check for required configuration values
 	if c.config.Defaults.Currency == "" {
 		return fmt.Errorf("default currency not set")
@@ -908,6 +4333,27 @@ func (c *CLI) checkConfiguration() error {
 	if c.config.Storage.Driver == "" {
 		return fmt.Errorf("storage driver not set")
 	}
+	if data, err := os.ReadFile(c.configPath); err == nil {
+		if _, changes, err := config.Migrate(data); err == nil && len(changes) > 0 {
+			return fmt.Errorf("config format is outdated (%s); run `pricetrek config migrate`", strings.Join(changes, "; "))
+		}
+	}
+	if err := tracker.ValidItemSource(c.config.ItemSource); err != nil {
+		return fmt.Errorf("item_source: %w", err)
+	}
+	if lang := notifications.Language(c.config); !notifications.SupportedLanguage(lang) {
+		// Not fatal: alerts are still sent, in English
+		c.logger.Warn("defaults.language is not supported (en, tr); alerts fall back to English", "language", lang)
+	}
+	if err := c.config.Validate(); err != nil {
+		return err
+	}
+	if err := notifications.ValidateRoutes(c.config.Notifications); err != nil {
+		return fmt.Errorf("notifications: %w", err)
+	}
+	if err := telemetry.Validate(c.config.Defaults.Telemetry); err != nil {
+		return fmt.Errorf("defaults.telemetry: %w", err)
+	}
 	return nil
 }
 
@@ -949,7 +4395,8 @@ func (c *CLI) handleSchedule(args []string) error {
 func (c *CLI) handleBackup(args []string) error {
 	var (
 		outputFlag = flag.String("output", "", "Backup output file")
-		dirFlag    = flag.String("dir", "./backups", "Backup directory")
+		dirFlag    = flag.String("dir", c.paths.Backups, "Backup directory")
+		fullFlag   = flag.Bool("full", false, "Back up every file instead of only those changed since the last backup")
 	)
 
 	// Parse flags
@@ -957,8 +4404,16 @@ func (c *CLI) handleBackup(args []string) error {
 
 	backupManager := tools.NewBackupManager(*dirFlag)
 
-	// Create backup
-	backupFile, err := backupManager.CreateBackup("./data")
+	// Create backup of the directory holding the database. A backup moved
+	// to --output must stand alone, so it is always full.
+	dataDir := filepath.Dir(c.config.Storage.Path)
+	var backupFile string
+	var err error
+	if *fullFlag || *outputFlag != "" {
+		backupFile, err = backupManager.CreateBackup(dataDir)
+	} else {
+		backupFile, err = backupManager.CreateIncrementalBackup(dataDir)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create backup: %w", err)
 	}
@@ -978,17 +4433,80 @@ func (c *CLI) handleBackup(args []string) error {
 func (c *CLI) handleRestore(args []string) error {
 	var (
 		backupFile = flag.String("file", "", "Backup file to restore")
-		targetDir  = flag.String("target", "./data", "Target directory")
+		targetDir  = flag.String("target", filepath.Dir(c.config.Storage.Path), "Target directory")
+		dryRun     = flag.Bool("dry-run", false, "List the files that would be written without restoring")
+		force      = flag.Bool("force", false, "Restore into a target directory that already holds files")
+		latest     = flag.Bool("latest", false, "Restore the newest backup in --dir")
+		dirFlag    = flag.String("dir", c.paths.Backups, "Backup directory searched by --latest")
+		verify     = flag.Bool("verify", false, "Check the backup reads back intact before restoring")
 	)
 
 	// Parse flags
 	flag.CommandLine.Parse(args)
 
+	backupManager := tools.NewBackupManager(*dirFlag)
+
+	if *latest {
+		if *backupFile != "" {
+			return fmt.Errorf("--latest and --file are mutually exclusive")
+		}
+		newest, err := backupManager.LatestBackup()
+		if err != nil {
+			return err
+		}
+		*backupFile = newest
+		c.logger.Info("Using latest backup", "file", newest)
+	}
+
 	if *backupFile == "" {
-		return fmt.Errorf("backup file is required (--file)")
+		return fmt.Errorf("backup file is required (--file or --latest)")
+	}
+
+	if *verify {
+		if err := backupManager.VerifyBackup(*backupFile); err != nil {
+			return fmt.Errorf("backup failed verification: %w", err)
+		}
+		c.logger.Info("Backup verified", "file", *backupFile)
+	}
+
+	entries, err := backupManager.BackupContents(*backupFile)
+	if err != nil {
+		return fmt.Errorf("failed to read backup: %w", err)
+	}
+
+	// The instance lock held for this command lives next to the database
+	lockName := filepath.Base(c.config.Storage.Path) + ".lock"
+	existing, err := dirFiles(*targetDir, lockName)
+	if err != nil {
+		return fmt.Errorf("failed to read target directory: %w", err)
+	}
+
+	if *dryRun {
+		fmt.Printf("Restore plan for %s into %s (dry run, nothing written):\n", *backupFile, *targetDir)
+		if chain, err := backupManager.BackupChain(*backupFile); err == nil && len(chain) > 1 {
+			fmt.Printf("  from %s and %d incremental backup(s)\n", filepath.Base(chain[0]), len(chain)-1)
+		}
+		for _, entry := range entries {
+			action := "create"
+			if existing[filepath.Clean(entry.Name)] {
+				action = "overwrite"
+			}
+			fmt.Printf("  %-9s %s (%d bytes)\n", action, entry.Name, entry.Size)
+		}
+		for _, name := range tools.StaleJournals(entries) {
+			if existing[filepath.Clean(name)] {
+				fmt.Printf("  %-9s %s\n", "remove", name)
+			}
+		}
+		if len(existing) > 0 && !*force {
+			fmt.Printf("\nThe target directory is not empty; restoring needs --force.\n")
+		}
+		return nil
 	}
 
-	backupManager := tools.NewBackupManager("./backups")
+	if len(existing) > 0 && !*force {
+		return fmt.Errorf("target directory %s is not empty; use --force to overwrite it (preview with --dry-run)", *targetDir)
+	}
 
 	// Restore backup
 	if err := backupManager.RestoreBackup(*backupFile, *targetDir); err != nil {
@@ -999,44 +4517,92 @@ func (c *CLI) handleRestore(args []string) error {
 	return nil
 }
 
-func (c *CLI) handleMonitor(args []string) error {
+// dirFiles returns the relative paths of the files under dir, skipping the
+// named file; a missing dir has none
+func dirFiles(dir, skip string) (map[string]bool, error) {
+	files := make(map[string]bool)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && path == dir {
+				return filepath.SkipDir
+			}
+			return err
+		}
+		if d.IsDir() || d.Name() == skip {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		files[rel] = true
+		return nil
+	})
+	return files, err
+}
+
+func (c *CLI) handleMonitor(ctx context.Context, args []string) error {
 	var (
-		intervalFlag = flag.Duration("interval", 5*time.Second, "Monitoring interval")
-		onceFlag     = flag.Bool("once", false, "Show stats once and exit")
+		intervalFlag   = flag.Duration("interval", 5*time.Second, "Monitoring interval")
+		onceFlag       = flag.Bool("once", false, "Show stats once and exit")
+		formatFlag     = flag.String("format", "text", "Output format: text, json or prometheus")
+		jsonFlag       = flag.Bool("json", false, "Output in JSON format (same as --format json)")
+		prometheusFlag = flag.Bool("prometheus", false, "Output in Prometheus text format (same as --format prometheus)")
 	)
 
 	// Parse flags
 	flag.CommandLine.Parse(args)
 
+	format := *formatFlag
+	if *jsonFlag && *prometheusFlag {
+		return fmt.Errorf("--json and --prometheus are mutually exclusive")
+	}
+	if *jsonFlag {
+		format = "json"
+	}
+	if *prometheusFlag {
+		format = "prometheus"
+	}
+	switch format {
+	case "text", "json", "prometheus":
+	default:
+		return fmt.Errorf("unknown monitor format %q (want text, json or prometheus)", format)
+	}
+
 	monitor := tools.NewSystemMonitor()
 
 	if *onceFlag {
 		// Show stats once
 		stats := monitor.GetSystemStats()
-		c.printSystemStats(stats)
-		return nil
+		return c.printSystemStats(stats, format)
 	}
 
 	// Continuous monitoring
 	c.logger.Info("Starting system monitoring", "interval", *intervalFlag)
-	ctx := context.Background()
 
 	monitor.MonitorLoop(ctx, *intervalFlag, func(stats tools.SystemStats) {
-		c.printSystemStats(stats)
+		if err := c.printSystemStats(stats, format); err != nil {
+			c.logger.Error("Failed to print system stats", "error", err)
+		}
 	})
 
 	return nil
 }
 
-func (c *CLI) printSystemStats(stats tools.SystemStats) {
-	fmt.Printf("\n=== System Statistics ===\n")
-	fmt.Printf("Uptime: %v\n", stats.Uptime)
-	fmt.Printf("Go Routines: %d\n", stats.GoRoutines)
-	fmt.Printf("Memory Allocated: %s\n", stats.FormatBytes(stats.MemoryAlloc))
-	fmt.Printf("Memory Total: %s\n", stats.FormatBytes(stats.MemoryTotal))
-	fmt.Printf("Memory System: %s\n", stats.FormatBytes(stats.MemorySys))
-	fmt.Printf("GC Count: %d\n", stats.NumGC)
-	fmt.Printf("GC Pause Total: %v\n", time.Duration(stats.GCPauseTotal))
-	fmt.Printf("Last GC: %v\n", stats.LastGC)
-	fmt.Println("========================\n")
+// printSystemStats writes stats to stdout in the given format; JSON is one
+// object per line so continuous output can be piped
+func (c *CLI) printSystemStats(stats tools.SystemStats, format string) error {
+	switch format {
+	case "json":
+		data, err := json.Marshal(stats)
+		if err != nil {
+			return fmt.Errorf("failed to marshal system stats: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	case "prometheus":
+		return stats.WritePrometheus(os.Stdout)
+	default:
+		return stats.WriteText(os.Stdout)
+	}
 }
\ No newline at end of file
@@ -0,0 +1,262 @@
+package cli
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/config"
+	"github.com/makalin/pricetrek/internal/logger"
+)
+
+func TestCheckInterval(t *testing.T) {
+	tests := []struct {
+		name     string
+		loop     bool
+		interval time.Duration
+		force    bool
+		wantErr  bool
+	}{
+		{name: "loop at the minimum", loop: true, interval: 5 * time.Minute},
+		{name: "loop below the minimum", loop: true, interval: time.Second, wantErr: true},
+		{name: "loop below the minimum with force", loop: true, interval: time.Second, force: true},
+		{name: "loop with a zero interval", loop: true, interval: 0, force: true, wantErr: true},
+		{name: "once below the minimum", interval: time.Second},
+		{name: "once with a zero interval", interval: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkInterval(tt.loop, tt.interval, 5*time.Minute, tt.force)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("checkInterval error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestQuickTrackArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "fetch flags kept",
+			args: []string{"--url", "https://example.com", "--selector", ".price"},
+			want: []string{"--url", "https://example.com", "--selector", ".price"},
+		},
+		{
+			name: "once dropped",
+			args: []string{"--url", "https://example.com", "--once", "--selector", ".price"},
+			want: []string{"--url", "https://example.com", "--selector", ".price"},
+		},
+		{
+			name: "single dash and value forms dropped",
+			args: []string{"-once", "--once=true", "-url=https://example.com"},
+			want: []string{"-url=https://example.com"},
+		},
+		{
+			name:    "loop rejected",
+			args:    []string{"--url", "https://example.com", "--loop"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !isQuickTrack(append([]string{"track"}, tt.args...)) {
+				t.Fatalf("isQuickTrack(track %v) = false", tt.args)
+			}
+			got, err := quickTrackArgs(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("quickTrackArgs error = %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !slices.Equal(got, tt.want) {
+				t.Errorf("quickTrackArgs = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckConfigurationLanguage(t *testing.T) {
+	tests := []struct {
+		name     string
+		language string
+		timezone string
+	}{
+		{name: "default"},
+		{name: "turkish", language: "tr"},
+		{name: "turkish timezone", timezone: "Europe/Istanbul"},
+		// Alerts fall back to English, so doctor only warns
+		{name: "unsupported", language: "de"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Defaults.Language = tt.language
+			cfg.Defaults.Timezone = tt.timezone
+			c := &CLI{config: cfg, logger: logger.New(false)}
+			if err := c.checkConfiguration(context.Background()); err != nil {
+				t.Errorf("checkConfiguration: %v", err)
+			}
+		})
+	}
+}
+
+func TestWriteCommands(t *testing.T) {
+	tests := []struct {
+		command string
+		want    bool
+	}{
+		{"add", true},
+		{"edit", true},
+		{"clone", true},
+		{"rm", true},
+		{"remove", true},
+		{"track", true},
+		{"alert", true},
+		{"import", true},
+		{"compact", true},
+		{"clean-meta", true},
+		{"restore", true},
+		{"sync", true},
+		{"rates", true},
+		{"ls", false},
+		{"show", false},
+		{"export", false},
+		{"doctor", false},
+	}
+
+	for _, tt := range tests {
+		if got := writeCommands[tt.command]; got != tt.want {
+			t.Errorf("writeCommands[%q] = %v, want %v", tt.command, got, tt.want)
+		}
+	}
+}
+
+func TestShowRows(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    int
+		limitSet bool
+		all      bool
+		wantLoad int
+		wantRows int
+		wantErr  bool
+	}{
+		{name: "defaults", limit: 30, wantLoad: 30, wantRows: 10},
+		{name: "explicit limit", limit: 50, limitSet: true, wantLoad: 50, wantRows: 50},
+		{name: "limit below the listing default", limit: 3, limitSet: true, wantLoad: 3, wantRows: 3},
+		{name: "all", limit: 30, all: true, wantLoad: -1, wantRows: -1},
+		{name: "all overrides the limit", limit: 0, limitSet: true, all: true, wantLoad: -1, wantRows: -1},
+		{name: "zero limit", limit: 0, limitSet: true, wantErr: true},
+		{name: "negative limit", limit: -5, limitSet: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			load, rows, err := showRows(tt.limit, tt.limitSet, tt.all)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("showRows error = %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && (load != tt.wantLoad || rows != tt.wantRows) {
+				t.Errorf("showRows = %d, %d; want %d, %d", load, rows, tt.wantLoad, tt.wantRows)
+			}
+		})
+	}
+}
+
+func TestValidateProviders(t *testing.T) {
+	tests := []struct {
+		list    string
+		wantErr bool
+	}{
+		{list: ""},
+		{list: "generic"},
+		{list: "json, generic,headless"},
+		{list: "exec,"},
+		{list: "json,genric", wantErr: true},
+		{list: "Generic", wantErr: true},
+		{list: "browser", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := validateProviders(parseProviders(tt.list))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("validateProviders(%q) error = %v, want error %v", tt.list, err, tt.wantErr)
+		}
+	}
+}
+
+func TestMoveAside(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing map[string]string // files next to trek.db, by suffix
+		wantBak  bool              // whether trek.db.bak is used
+	}{
+		{name: "first reset", existing: map[string]string{"-wal": "log"}, wantBak: true},
+		{name: "stale backup WAL replaced", existing: map[string]string{".bak-wal": "stale"}, wantBak: true},
+		{name: "earlier backup kept", existing: map[string]string{".bak": "older", ".bak-wal": "older log"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := filepath.Join(t.TempDir(), "trek.db")
+			writeTestFile(t, db, "current")
+			for suffix, content := range tt.existing {
+				writeTestFile(t, db+suffix, content)
+			}
+
+			backup := asideName(db)
+			if (backup == db+".bak") != tt.wantBak {
+				t.Fatalf("asideName = %s, want .bak used %v", backup, tt.wantBak)
+			}
+			if err := moveAside(db, backup); err != nil {
+				t.Fatalf("moveAside: %v", err)
+			}
+
+			if data, err := os.ReadFile(backup); err != nil || string(data) != "current" {
+				t.Errorf("%s = %q, %v; want the current database", backup, data, err)
+			}
+			if _, err := os.Stat(db); !os.IsNotExist(err) {
+				t.Errorf("database still in place (err %v)", err)
+			}
+			if wal, ok := tt.existing["-wal"]; ok {
+				if data, err := os.ReadFile(backup + "-wal"); err != nil || string(data) != wal {
+					t.Errorf("WAL not moved with the database: %q, %v", data, err)
+				}
+			}
+			if _, ok := tt.existing["-wal"]; !ok {
+				if _, err := os.Stat(backup + "-wal"); err == nil {
+					t.Errorf("stale WAL left next to %s", backup)
+				}
+			}
+			if !tt.wantBak {
+				for suffix, content := range tt.existing {
+					if data, err := os.ReadFile(db + suffix); err != nil || string(data) != content {
+						t.Errorf("earlier trek.db%s = %q, %v; want it untouched", suffix, data, err)
+					}
+				}
+			}
+
+			// An existing backup is never replaced
+			writeTestFile(t, db, "newer")
+			if err := moveAside(db, backup); err == nil {
+				t.Errorf("moveAside replaced %s", backup)
+			}
+		})
+	}
+}
+
+func writeTestFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,46 +1,156 @@
 package config
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"time"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/makalin/pricetrek/internal/scheduler"
+	"github.com/makalin/pricetrek/internal/utils"
 )
 
 type Config struct {
+	Version      int                `yaml:"version,omitempty"`
 	Storage      StorageConfig      `yaml:"storage"`
 	Defaults     DefaultsConfig     `yaml:"defaults"`
 	Notifications NotificationsConfig `yaml:"notifications"`
 	Rules        RulesConfig        `yaml:"rules"`
+	FX           FXConfig           `yaml:"fx,omitempty"`
+	Export       ExportConfig       `yaml:"export,omitempty"`
+	// ItemSource picks the items track uses: merge (default), config or db
+	ItemSource   string             `yaml:"item_source,omitempty"`
 	Items        []ItemConfig       `yaml:"items"`
 }
 
+// FXConfig configures currency conversion. Rates are units of each currency
+// per one unit of Base.
+type FXConfig struct {
+	Base     string             `yaml:"base,omitempty"`
+	RatesURL string             `yaml:"rates_url,omitempty"`
+	Rates    map[string]float64 `yaml:"rates,omitempty"`
+	// CacheTTL is how long rates fetched from RatesURL are reused (default 12h)
+	CacheTTL time.Duration      `yaml:"cache_ttl,omitempty"`
+	// NormalizeTo stores each tracked sample's price converted to this
+	// currency in meta normalized_price and normalized_currency
+	NormalizeTo      string `yaml:"normalize_to,omitempty"`
+	// NormalizedAlerts evaluates drop and rise rules on normalized prices,
+	// so exchange-rate swings alone don't alert
+	NormalizedAlerts bool   `yaml:"normalized_alerts,omitempty"`
+}
+
+// ExportConfig refreshes an export file after every track run
+type ExportConfig struct {
+	// OnTrack is the file written; .ndjson and .jsonl get JSON lines, any
+	// other name CSV. Empty disables the export.
+	OnTrack string `yaml:"on_track,omitempty"`
+	// Items exports the item list as CSV instead of the price history
+	Items bool `yaml:"items,omitempty"`
+	// MetaFields are sample meta keys written as their own columns
+	MetaFields []string `yaml:"meta_fields,omitempty"`
+}
+
 type StorageConfig struct {
 	Driver string `yaml:"driver"`
 	Path   string `yaml:"path"`
+	// AutoInit creates the schema on first use instead of failing
+	AutoInit bool `yaml:"auto_init,omitempty"`
+	// Events records fetches and alerts in the events table
+	Events   bool `yaml:"events,omitempty"`
 }
 
 type DefaultsConfig struct {
 	Currency      string        `yaml:"currency"`
 	Timezone      string        `yaml:"timezone"`
+	// Language of alert messages (en, tr); empty picks one from Timezone
+	Language      string        `yaml:"language,omitempty"`
 	UserAgent     string        `yaml:"user_agent"`
+	// Proxy is used for all requests; empty falls back to HTTP(S)_PROXY
+	Proxy         string        `yaml:"proxy,omitempty"`
+	TLS           TLSConfig     `yaml:"tls,omitempty"`
+	// ActiveHours limits tracking to a daily window in Timezone, e.g. "09:00-22:00"
+	ActiveHours   string        `yaml:"active_hours,omitempty"`
 	Retry         RetryConfig   `yaml:"retry"`
 	HTTPTimeout   time.Duration `yaml:"http_timeout_sec"`
 	CacheTTL      time.Duration `yaml:"cache_ttl_min"`
 	Headless      HeadlessConfig `yaml:"headless"`
+	Decimals      map[string]int `yaml:"decimals,omitempty"`
+	// AllowUnknownCurrency accepts currency codes outside ISO 4217, for
+	// crypto and custom units; codes listed in Decimals are always accepted
+	AllowUnknownCurrency bool `yaml:"allow_unknown_currency,omitempty"`
+	MaxRedirects  int           `yaml:"max_redirects,omitempty"`
+	MinInterval   time.Duration `yaml:"min_interval,omitempty"`
+	// StaleAfter flags items whose latest sample is older than this
+	StaleAfter    time.Duration `yaml:"stale_after,omitempty"`
+	// BlockMarkers are case-insensitive phrases that identify soft-block and
+	// CAPTCHA pages
+	BlockMarkers  []string      `yaml:"block_markers,omitempty"`
+	// MaxHostRequests is the politeness limit estimate flags hosts above,
+	// in requests per day
+	MaxHostRequests int `yaml:"max_host_requests_per_day,omitempty"`
+	// PerHostConcurrency caps requests running at once against one host
+	PerHostConcurrency int `yaml:"per_host_concurrency,omitempty"`
+	// StoreHTTPTiming stores the DNS/connect/TLS/TTFB breakdown of each
+	// fetch in meta.http_timing
+	StoreHTTPTiming bool `yaml:"store_http_timing,omitempty"`
+	// Validate rejects fetched samples that don't look like a real price
+	Validate      ValidateConfig `yaml:"validate,omitempty"`
+	// Telemetry reports failed fetches to a self-hosted endpoint; opt-in
+	Telemetry     TelemetryConfig `yaml:"telemetry,omitempty"`
 }
 
 type RetryConfig struct {
 	Attempts     int           `yaml:"attempts"`
 	BaseDelay    time.Duration `yaml:"base_delay_ms"`
 	MaxDelay     time.Duration `yaml:"max_delay_ms"`
+	// FinalPass retries items that failed during a run once more after
+	// the other items, BaseDelay after the last fetch
+	FinalPass    bool          `yaml:"final_pass,omitempty"`
+}
+
+// ValidateConfig describes a usable sample. Samples failing it count as a
+// failed fetch: the next provider in the chain is tried and nothing is
+// stored. Prices must always be positive.
+type ValidateConfig struct {
+	MinPrice  *float64 `yaml:"min_price,omitempty" json:"min_price,omitempty"`
+	MaxPrice  *float64 `yaml:"max_price,omitempty" json:"max_price,omitempty"`
+	// Currency rejects samples whose page currency differs from the item's
+	Currency  bool     `yaml:"currency,omitempty" json:"currency,omitempty"`
+	// MaxChange rejects prices that moved more than this percentage from
+	// the last stored sample
+	MaxChange float64  `yaml:"max_change_pct,omitempty" json:"max_change_pct,omitempty"`
+	// AcceptAfter is how many runs in a row a new level rejected by
+	// MaxChange must show up before it is accepted; 0 means 3
+	AcceptAfter int    `yaml:"accept_after,omitempty" json:"accept_after,omitempty"`
+}
+
+// TelemetryConfig posts batches of failed fetches to an endpoint you run.
+// Each failure carries the host, provider chain, selector and an error
+// category; URLs and rejected prices are only sent when included explicitly.
+type TelemetryConfig struct {
+	Enabled       bool   `yaml:"enabled"`
+	Endpoint      string `yaml:"endpoint"`
+	IncludeURLs   bool   `yaml:"include_urls,omitempty"`
+	IncludePrices bool   `yaml:"include_prices,omitempty"`
+}
+
+// TLSConfig adjusts certificate verification for self-hosted targets
+type TLSConfig struct {
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify,omitempty"`
+	CAFile             string `yaml:"ca_file,omitempty"`
 }
 
 type HeadlessConfig struct {
 	Enabled   bool   `yaml:"enabled"`
 	WaitUntil string `yaml:"wait_until"`
+	// OnBlock retries blocked fetches with the headless provider
+	OnBlock   bool   `yaml:"on_block,omitempty"`
 }
 
 type NotificationsConfig struct {
@@ -48,6 +158,16 @@ type NotificationsConfig struct {
 	Telegram TelegramConfig `yaml:"telegram"`
 	Slack    SlackConfig    `yaml:"slack"`
 	Ntfy     NtfyConfig     `yaml:"ntfy"`
+	Exec     ExecConfig     `yaml:"exec"`
+	// Routes maps an alert rule (target, drop) to the channels it is sent
+	// to. Rules without a route are sent to every enabled channel.
+	Routes map[string][]string `yaml:"routes,omitempty"`
+	// LinkTemplate rewrites product links shown in alerts, e.g.
+	// "https://go.example/?u={{urlquery .URL}}"; fetch URLs are unaffected
+	LinkTemplate string `yaml:"link_template,omitempty"`
+	// Templates replace the built-in alert message for a rule (target,
+	// drop, ...) or, under "default", for every rule without its own
+	Templates map[string]string `yaml:"templates,omitempty"`
 }
 
 type EmailConfig struct {
@@ -64,6 +184,8 @@ type TelegramConfig struct {
 type SlackConfig struct {
 	Enabled bool   `yaml:"enabled"`
 	Webhook string `yaml:"webhook"`
+	// Blocks posts Block Kit messages with price fields and a link button
+	Blocks  bool   `yaml:"blocks,omitempty"`
 }
 
 type NtfyConfig struct {
@@ -71,9 +193,28 @@ type NtfyConfig struct {
 	Topic   string `yaml:"topic"`
 }
 
+type ExecConfig struct {
+	Enabled bool          `yaml:"enabled"`
+	Command string        `yaml:"command"`
+	Timeout time.Duration `yaml:"timeout"`
+}
+
 type RulesConfig struct {
 	PercentDrop  float64 `yaml:"percent_drop"`
 	TargetPrice  *float64 `yaml:"target_price"`
+	PercentRise  float64 `yaml:"percent_rise,omitempty"`
+	AboveTarget  bool    `yaml:"above_target,omitempty"`
+	Velocity     VelocityRule `yaml:"velocity,omitempty"`
+	// ConfirmRuns is how many consecutive samples must show the drop
+	// against the sample before them before the drop alert fires
+	ConfirmRuns  int     `yaml:"confirm_runs,omitempty"`
+}
+
+// VelocityRule alerts on a steady decline: the least-squares trend over the
+// last Days days falling faster than PercentPerDay
+type VelocityRule struct {
+	PercentPerDay float64 `yaml:"percent_per_day,omitempty"`
+	Days          int     `yaml:"days,omitempty"`
 }
 
 type ItemConfig struct {
@@ -83,15 +224,58 @@ type ItemConfig struct {
 	Provider     string  `yaml:"provider"`
 	Selector     string  `yaml:"selector"`
 	Currency     string  `yaml:"currency"`
-	TargetPrice  *float64 `yaml:"target_price"`
-	PercentDrop  *float64 `yaml:"percent_drop"`
+	TargetPrice  *float64 `yaml:"target_price,omitempty"`
+	PercentDrop  *float64 `yaml:"percent_drop,omitempty"`
+	PercentRise  *float64 `yaml:"percent_rise,omitempty"`
 	Schedule     string  `yaml:"schedule"`
 	Regex        string  `yaml:"regex,omitempty"`
 	Attr         string  `yaml:"attr,omitempty"`
 	Command      string  `yaml:"command,omitempty"`
+
+	AcceptLanguage string        `yaml:"accept_language,omitempty"`
+	// HTTPTimeout overrides defaults.http_timeout_sec for this item
+	HTTPTimeout    time.Duration `yaml:"http_timeout_sec,omitempty"`
+	Notes          string        `yaml:"notes,omitempty"`
+	// FetchKey groups items that read the same page; items with the same key
+	// and URL fetch it once per run
+	FetchKey       string        `yaml:"fetch_key,omitempty"`
+	// TLS replaces defaults.tls for this item
+	TLS            *TLSConfig    `yaml:"tls,omitempty"`
+	// ActiveHours overrides defaults.active_hours for this item
+	ActiveHours    string        `yaml:"active_hours,omitempty"`
+	// Providers are tried in order until one yields a price; when set it
+	// replaces Provider
+	Providers      []string      `yaml:"providers,omitempty"`
+	// Validate replaces defaults.validate for this item
+	Validate       *ValidateConfig `yaml:"validate,omitempty"`
+	// ConfirmRuns overrides rules.confirm_runs for this item
+	ConfirmRuns    int           `yaml:"confirm_runs,omitempty"`
 }
 
+// ProviderChain returns the providers to try for the item, in order
+func (i ItemConfig) ProviderChain() []string {
+	if len(i.Providers) > 0 {
+		return i.Providers
+	}
+	return []string{i.Provider}
+}
+
+// Load reads the configuration file at path and validates it
 func Load(path string) (*Config, error) {
+	cfg, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config file: %w", err)
+	}
+	return cfg, nil
+}
+
+// Read reads the configuration file at path without validating it, for
+// files whose items are checked against another configuration, like
+// import --yaml
+func Read(path string) (*Config, error) {
 	// Check if config file exists
 	if _, err := os.Stat(path); os.IsNotExist(err) {
 		return nil, fmt.Errorf("configuration file not found: %s", path)
@@ -103,16 +287,143 @@ func Load(path string) (*Config, error) {
 	}
 
 	var cfg Config
-	if err := yaml.Unmarshal(data, &cfg); err != nil {
+	if err := decode(data, &cfg); err != nil {
 		return nil, fmt.Errorf("failed to parse config file: %w", err)
 	}
 
-	// Set defaults
+	cfg.setDefaults()
+
+	return &cfg, nil
+}
+
+// Validate checks the parts of the configuration a running tracker relies
+// on: every item has a unique ID, something to fetch, parseable active
+// hours and a known currency.
+func (cfg *Config) Validate() error {
+	var errs []error
+	if err := cfg.CheckCurrency(cfg.Defaults.Currency); err != nil {
+		errs = append(errs, fmt.Errorf("defaults.currency: %w", err))
+	}
+	if err := cfg.CheckCurrency(cfg.FX.NormalizeTo); err != nil {
+		errs = append(errs, fmt.Errorf("fx.normalize_to: %w", err))
+	}
+	if cfg.Defaults.ActiveHours != "" {
+		if _, err := scheduler.ParseWindow(cfg.Defaults.ActiveHours); err != nil {
+			errs = append(errs, fmt.Errorf("defaults.active_hours: %w", err))
+		}
+	}
+
+	if cfg.Rules.ConfirmRuns < 0 {
+		errs = append(errs, fmt.Errorf("rules.confirm_runs must be at least 1"))
+	}
+
+	seen := make(map[string]bool, len(cfg.Items))
+	for i, item := range cfg.Items {
+		if item.ID == "" {
+			errs = append(errs, fmt.Errorf("items[%d]: id is required", i))
+			continue
+		}
+		if seen[item.ID] {
+			errs = append(errs, fmt.Errorf("items[%d]: duplicate id %q", i, item.ID))
+		}
+		seen[item.ID] = true
+
+		if item.URL == "" && item.Command == "" {
+			errs = append(errs, fmt.Errorf("item %s: url or command is required", item.ID))
+		}
+		if item.ActiveHours != "" {
+			if _, err := scheduler.ParseWindow(item.ActiveHours); err != nil {
+				errs = append(errs, fmt.Errorf("item %s: active_hours: %w", item.ID, err))
+			}
+		}
+		if item.ConfirmRuns < 0 {
+			errs = append(errs, fmt.Errorf("item %s: confirm_runs must be at least 1", item.ID))
+		}
+		if err := cfg.CheckCurrency(item.Currency); err != nil {
+			errs = append(errs, fmt.Errorf("item %s: %w", item.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CheckCurrency rejects a currency code outside ISO 4217, unless it has a
+// defaults.decimals entry or defaults.allow_unknown_currency is set. Empty
+// codes pass.
+func (cfg *Config) CheckCurrency(code string) error {
+	if code == "" || cfg.Defaults.AllowUnknownCurrency {
+		return nil
+	}
+	for custom := range cfg.Defaults.Decimals {
+		if strings.EqualFold(custom, code) {
+			return nil
+		}
+	}
+	return utils.ValidateCurrency(code)
+}
+
+// durationUnits maps the unit-suffixed duration keys to the unit a bare
+// number in them is counted in
+var durationUnits = map[string]time.Duration{
+	"http_timeout_sec": time.Second,
+	"cache_ttl_min":    time.Minute,
+	"base_delay_ms":    time.Millisecond,
+	"max_delay_ms":     time.Millisecond,
+}
+
+// decode parses a config document into cfg. A bare number in a unit-suffixed
+// duration field is read in that unit, so http_timeout_sec: 20 is twenty
+// seconds rather than twenty nanoseconds; duration strings like "20s" are
+// read as written.
+func decode(data []byte, cfg *Config) error {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	if doc.Kind == 0 {
+		// Empty document
+		return nil
+	}
+	applyDurationUnits(&doc)
+	return doc.Decode(cfg)
+}
+
+// applyDurationUnits rewrites integer values of the keys in durationUnits
+// as duration strings, at any depth
+func applyDurationUnits(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			unit, ok := durationUnits[key.Value]
+			if !ok || value.Kind != yaml.ScalarNode || value.Tag != "!!int" {
+				continue
+			}
+			n, err := strconv.ParseInt(value.Value, 0, 64)
+			if err != nil {
+				continue
+			}
+			value.Tag = "!!str"
+			value.Value = (time.Duration(n) * unit).String()
+		}
+	}
+	for _, child := range node.Content {
+		applyDurationUnits(child)
+	}
+}
+
+// Default returns a configuration with all defaults applied, for commands
+// that can run without a configuration file.
+func Default() *Config {
+	cfg := &Config{}
+	cfg.setDefaults()
+	return cfg
+}
+
+func (cfg *Config) setDefaults() {
 	if cfg.Storage.Driver == "" {
 		cfg.Storage.Driver = "sqlite"
 	}
 	if cfg.Storage.Path == "" {
-		cfg.Storage.Path = "./data/trek.db"
+		cfg.Storage.Path = defaultStoragePath
 	}
 	if cfg.Defaults.Currency == "" {
 		cfg.Defaults.Currency = "USD"
@@ -135,17 +446,45 @@ func Load(path string) (*Config, error) {
 	if cfg.Defaults.HTTPTimeout == 0 {
 		cfg.Defaults.HTTPTimeout = 20 * time.Second
 	}
+	if cfg.Defaults.MaxRedirects == 0 {
+		cfg.Defaults.MaxRedirects = 10
+	}
+	if cfg.Defaults.MinInterval == 0 {
+		cfg.Defaults.MinInterval = 5 * time.Minute
+	}
 	if cfg.Defaults.CacheTTL == 0 {
 		cfg.Defaults.CacheTTL = 30 * time.Minute
 	}
+	if cfg.Defaults.StaleAfter == 0 {
+		cfg.Defaults.StaleAfter = 48 * time.Hour
+	}
+	if cfg.Rules.ConfirmRuns == 0 {
+		cfg.Rules.ConfirmRuns = 1
+	}
+	if cfg.Defaults.MaxHostRequests == 0 {
+		cfg.Defaults.MaxHostRequests = 96
+	}
+	if cfg.Defaults.PerHostConcurrency == 0 {
+		cfg.Defaults.PerHostConcurrency = 2
+	}
+	if cfg.Defaults.BlockMarkers == nil {
+		cfg.Defaults.BlockMarkers = []string{
+			"captcha",
+			"access denied",
+			"unusual traffic",
+			"are you a robot",
+			"verify you are human",
+		}
+	}
 	if cfg.Defaults.Headless.WaitUntil == "" {
 		cfg.Defaults.Headless.WaitUntil = "networkidle"
 	}
 	if cfg.Rules.PercentDrop == 0 {
 		cfg.Rules.PercentDrop = 8.0
 	}
-
-	return &cfg, nil
+	if cfg.Rules.Velocity.PercentPerDay > 0 && cfg.Rules.Velocity.Days == 0 {
+		cfg.Rules.Velocity.Days = 7
+	}
 }
 
 func (c *Config) Save(path string) error {
@@ -164,5 +503,35 @@ func (c *Config) Save(path string) error {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
+	return nil
+}
+
+// SaveItems writes items as a config file holding only the items list, so
+// it can be loaded with Load or imported with import --yaml. Nothing else
+// from the configuration, such as notification secrets, is included.
+func SaveItems(items []ItemConfig, path string) error {
+	file := struct {
+		Version int          `yaml:"version"`
+		Items   []ItemConfig `yaml:"items"`
+	}{CurrentVersion, items}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&file); err != nil {
+		return fmt.Errorf("failed to marshal items: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return fmt.Errorf("failed to marshal items: %w", err)
+	}
+
+	if dir := filepath.Dir(path); dir != "." {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create output directory: %w", err)
+		}
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("failed to write items file: %w", err)
+	}
 	return nil
 }
\ No newline at end of file
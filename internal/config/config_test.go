@@ -0,0 +1,121 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDecodeDurationUnits(t *testing.T) {
+	tests := []struct {
+		name  string
+		yaml  string
+		check func(cfg *Config) (got, want time.Duration)
+	}{
+		{
+			name: "http timeout in seconds",
+			yaml: "defaults:\n  http_timeout_sec: 20\n",
+			check: func(cfg *Config) (time.Duration, time.Duration) {
+				return cfg.Defaults.HTTPTimeout, 20 * time.Second
+			},
+		},
+		{
+			name: "cache ttl in minutes",
+			yaml: "defaults:\n  cache_ttl_min: 30\n",
+			check: func(cfg *Config) (time.Duration, time.Duration) {
+				return cfg.Defaults.CacheTTL, 30 * time.Minute
+			},
+		},
+		{
+			name: "retry base delay in milliseconds",
+			yaml: "defaults:\n  retry:\n    base_delay_ms: 800\n",
+			check: func(cfg *Config) (time.Duration, time.Duration) {
+				return cfg.Defaults.Retry.BaseDelay, 800 * time.Millisecond
+			},
+		},
+		{
+			name: "retry max delay in milliseconds",
+			yaml: "defaults:\n  retry:\n    max_delay_ms: 7000\n",
+			check: func(cfg *Config) (time.Duration, time.Duration) {
+				return cfg.Defaults.Retry.MaxDelay, 7 * time.Second
+			},
+		},
+		{
+			name: "duration string read as written",
+			yaml: "defaults:\n  http_timeout_sec: 1m30s\n",
+			check: func(cfg *Config) (time.Duration, time.Duration) {
+				return cfg.Defaults.HTTPTimeout, 90 * time.Second
+			},
+		},
+		{
+			name: "item timeout in seconds",
+			yaml: "items:\n  - id: a\n    http_timeout_sec: 60\n",
+			check: func(cfg *Config) (time.Duration, time.Duration) {
+				return cfg.Items[0].HTTPTimeout, time.Minute
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var cfg Config
+			if err := decode([]byte(tt.yaml), &cfg); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if got, want := tt.check(&cfg); got != want {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadValidates(t *testing.T) {
+	tests := []struct {
+		name    string
+		yaml    string
+		wantErr bool
+	}{
+		{
+			name: "valid",
+			yaml: "defaults:\n  currency: USD\nitems:\n  - id: a\n    url: https://example.com/a\n    currency: EUR\n",
+		},
+		{
+			name:    "unknown default currency",
+			yaml:    "defaults:\n  currency: USDD\n",
+			wantErr: true,
+		},
+		{
+			name:    "unknown item currency",
+			yaml:    "items:\n  - id: a\n    url: https://example.com/a\n    currency: EURO\n",
+			wantErr: true,
+		},
+		{
+			name: "custom unit listed in decimals",
+			yaml: "defaults:\n  decimals:\n    BTC: 8\nitems:\n  - id: a\n    url: https://example.com/a\n    currency: BTC\n",
+		},
+		{
+			name:    "unparseable active hours",
+			yaml:    "defaults:\n  active_hours: 9-5\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "pricetrek.yaml")
+			if err := os.WriteFile(path, []byte(tt.yaml), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := Load(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Load error = %v, want error %v", err, tt.wantErr)
+			}
+			// Read leaves validation to the caller
+			if _, err := Read(path); err != nil {
+				t.Errorf("Read: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CurrentVersion is the config format written by init and config migrate
+const CurrentVersion = 2
+
+// migration upgrades a config document's root mapping from Version-1 to
+// Version and returns a description of each change it made
+type migration struct {
+	Version int
+	Apply   func(root *yaml.Node) []string
+}
+
+var migrations = []migration{
+	{Version: 2, Apply: migrateV2},
+}
+
+// Migrate upgrades a config file's contents to CurrentVersion and returns
+// the upgraded YAML with a list of changes. The document is edited in
+// place, so comments, key order and unknown keys survive. A file without a
+// version is only given one when something in it needed upgrading; when
+// nothing changes the contents are returned as they were.
+func Migrate(data []byte) ([]byte, []string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse config file: %w", err)
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		// Empty file, nothing to upgrade
+		return data, nil, nil
+	}
+	root := doc.Content[0]
+
+	version := 1
+	_, versionNode := mappingEntry(root, "version")
+	if versionNode != nil {
+		v, err := strconv.Atoi(versionNode.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid config version %q", versionNode.Value)
+		}
+		version = v
+	}
+	if version > CurrentVersion {
+		return nil, nil, fmt.Errorf("config version %d is newer than this build supports (%d)", version, CurrentVersion)
+	}
+
+	var changes []string
+	for _, m := range migrations {
+		if m.Version <= version {
+			continue
+		}
+		changes = append(changes, m.Apply(root)...)
+	}
+	if versionNode == nil && len(changes) == 0 {
+		return data, nil, nil
+	}
+	if version < CurrentVersion {
+		setVersion(root, versionNode, CurrentVersion)
+		changes = append(changes, fmt.Sprintf("set version: %d", CurrentVersion))
+	}
+	if len(changes) == 0 {
+		return data, nil, nil
+	}
+
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&doc); err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal config: %w", err)
+	}
+	return out.Bytes(), changes, nil
+}
+
+// setVersion writes version into the version entry, adding one at the top
+// of the file when there is none. A comment heading the file stays on top.
+func setVersion(root, versionNode *yaml.Node, version int) {
+	if versionNode != nil {
+		versionNode.Tag, versionNode.Value = "!!int", strconv.Itoa(version)
+		return
+	}
+
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+	if len(root.Content) > 0 {
+		key.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+	}
+	root.Content = append([]*yaml.Node{key, value}, root.Content...)
+}
+
+// mappingEntry returns the key and value nodes of key in a mapping node, or
+// nils when it has none
+func mappingEntry(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	if mapping == nil || mapping.Kind != yaml.MappingNode {
+		return nil, nil
+	}
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// removeEntry deletes key from a mapping node
+func removeEntry(mapping *yaml.Node, key string) {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			mapping.Content = append(mapping.Content[:i], mapping.Content[i+2:]...)
+			return
+		}
+	}
+}
+
+// migrateV2 moves top-level rule fields under rules and converts the unit
+// suffixed duration fields, which older configs wrote as bare numbers
+// (which older builds read as nanoseconds), into explicit durations
+func migrateV2(root *yaml.Node) []string {
+	var changes []string
+
+	for _, key := range []string{"percent_drop", "target_price"} {
+		keyNode, value := mappingEntry(root, key)
+		if keyNode == nil {
+			continue
+		}
+		removeEntry(root, key)
+
+		_, rules := mappingEntry(root, "rules")
+		if rules == nil || rules.Kind != yaml.MappingNode {
+			removeEntry(root, "rules")
+			rules = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			root.Content = append(root.Content,
+				&yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "rules"}, rules)
+		}
+		if existing, _ := mappingEntry(rules, key); existing == nil {
+			rules.Content = append(rules.Content, keyNode, value)
+		}
+		changes = append(changes, fmt.Sprintf("moved %s to rules.%s", key, key))
+	}
+
+	if _, defaults := mappingEntry(root, "defaults"); defaults != nil {
+		changes = append(changes, convertDuration(defaults, "defaults.", "http_timeout_sec", time.Second)...)
+		changes = append(changes, convertDuration(defaults, "defaults.", "cache_ttl_min", time.Minute)...)
+		if _, retry := mappingEntry(defaults, "retry"); retry != nil {
+			changes = append(changes, convertDuration(retry, "defaults.retry.", "base_delay_ms", time.Millisecond)...)
+			changes = append(changes, convertDuration(retry, "defaults.retry.", "max_delay_ms", time.Millisecond)...)
+		}
+	}
+
+	if _, items := mappingEntry(root, "items"); items != nil && items.Kind == yaml.SequenceNode {
+		for i, item := range items.Content {
+			prefix := fmt.Sprintf("items[%d].", i)
+			changes = append(changes, convertDuration(item, prefix, "http_timeout_sec", time.Second)...)
+		}
+	}
+
+	return changes
+}
+
+// convertDuration rewrites a legacy number of unit (a bare integer, or a
+// sub-millisecond duration written back by older versions) as a duration
+func convertDuration(section *yaml.Node, prefix, key string, unit time.Duration) []string {
+	_, value := mappingEntry(section, key)
+	if value == nil || value.Kind != yaml.ScalarNode {
+		return nil
+	}
+
+	var count int64
+	switch value.Tag {
+	case "!!int":
+		n, err := strconv.ParseInt(value.Value, 10, 64)
+		if err != nil {
+			return nil
+		}
+		count = n
+	case "!!str":
+		d, err := time.ParseDuration(value.Value)
+		if err != nil || d >= time.Millisecond {
+			return nil
+		}
+		count = int64(d)
+	default:
+		return nil
+	}
+	if count <= 0 {
+		return nil
+	}
+
+	converted := time.Duration(count) * unit
+	value.Tag, value.Value, value.Style = "!!str", converted.String(), 0
+	return []string{fmt.Sprintf("%s%s: %d -> %s", prefix, key, count, converted)}
+}
@@ -0,0 +1,74 @@
+package config
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	tests := []struct {
+		name        string
+		in          string
+		want        string
+		wantChanges []string
+	}{
+		{
+			name: "unversioned config needing nothing is left alone",
+			in:   "defaults:\n  currency: USD # dollars\n",
+			want: "defaults:\n  currency: USD # dollars\n",
+		},
+		{
+			name: "current config is left alone",
+			in:   "version: 2\ndefaults:\n  http_timeout_sec: 20s\n",
+			want: "version: 2\ndefaults:\n  http_timeout_sec: 20s\n",
+		},
+		{
+			name:        "old version is bumped",
+			in:          "version: 1 # old\ndefaults:\n  currency: USD\n",
+			want:        "version: 2 # old\ndefaults:\n  currency: USD\n",
+			wantChanges: []string{"set version: 2"},
+		},
+		{
+			name:        "durations converted with comments kept",
+			in:          "# header\n\ndefaults:\n  http_timeout_sec: 20 # slow store\n  retry:\n    base_delay_ms: 500\n",
+			want:        "# header\n\nversion: 2\ndefaults:\n  http_timeout_sec: 20s # slow store\n  retry:\n    base_delay_ms: 500ms\n",
+			wantChanges: []string{"defaults.http_timeout_sec: 20 -> 20s", "defaults.retry.base_delay_ms: 500 -> 500ms", "set version: 2"},
+		},
+		{
+			name:        "rule fields moved under rules",
+			in:          "percent_drop: 10 # alert threshold\nunknown: kept\nrules:\n  confirm_runs: 2\n",
+			want:        "version: 2\nunknown: kept\nrules:\n  confirm_runs: 2\n  percent_drop: 10 # alert threshold\n",
+			wantChanges: []string{"moved percent_drop to rules.percent_drop", "set version: 2"},
+		},
+		{
+			name:        "item timeouts converted",
+			in:          "items:\n  - id: a # first\n    http_timeout_sec: 60\n",
+			want:        "version: 2\nitems:\n  - id: a # first\n    http_timeout_sec: 1m0s\n",
+			wantChanges: []string{"items[0].http_timeout_sec: 60 -> 1m0s", "set version: 2"},
+		},
+		{
+			name: "empty file",
+			in:   "",
+			want: "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changes, err := Migrate([]byte(tt.in))
+			if err != nil {
+				t.Fatalf("Migrate: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Migrate =\n%s\nwant\n%s", got, tt.want)
+			}
+			if !slices.Equal(changes, tt.wantChanges) {
+				t.Errorf("changes = %q, want %q", changes, tt.wantChanges)
+			}
+		})
+	}
+
+	if _, _, err := Migrate([]byte("version: 3\n")); err == nil {
+		t.Error("Migrate accepted a config newer than this build")
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+)
+
+// DataDirEnv is the environment variable that sets the data directory when
+// --data-dir is not given
+const DataDirEnv = "PRICETREK_DATA_DIR"
+
+// defaultStoragePath is used for configs without storage.path
+var defaultStoragePath = "./data/trek.db"
+
+// Paths are the default locations of the config file, database and backups
+type Paths struct {
+	Config  string
+	DataDir string
+	DB      string
+	Backups string
+}
+
+// ResolvePaths picks the data directory from dataDir, then $PRICETREK_DATA_DIR.
+// Without either, a working directory that already holds pricetrek.yaml or
+// data/trek.db keeps the classic ./pricetrek.yaml, ./data, ./backups layout,
+// as does every run outside Linux. On Linux the default is the XDG base
+// directories: $XDG_CONFIG_HOME/pricetrek for the config and
+// $XDG_DATA_HOME/pricetrek for the database and backups.
+func ResolvePaths(dataDir string) Paths {
+	if dataDir == "" {
+		dataDir = os.Getenv(DataDirEnv)
+	}
+	if dataDir != "" {
+		return Paths{
+			Config:  filepath.Join(dataDir, "pricetrek.yaml"),
+			DataDir: dataDir,
+			DB:      filepath.Join(dataDir, "trek.db"),
+			Backups: filepath.Join(dataDir, "backups"),
+		}
+	}
+
+	legacy := Paths{
+		Config:  "pricetrek.yaml",
+		DataDir: "./data",
+		DB:      "./data/trek.db",
+		Backups: "./backups",
+	}
+	if runtime.GOOS != "linux" || exists(legacy.Config) || exists(legacy.DB) {
+		return legacy
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return legacy
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		configHome = filepath.Join(home, ".config")
+	}
+	dataHome := os.Getenv("XDG_DATA_HOME")
+	if dataHome == "" {
+		dataHome = filepath.Join(home, ".local", "share")
+	}
+
+	dataDir = filepath.Join(dataHome, "pricetrek")
+	return Paths{
+		Config:  filepath.Join(configHome, "pricetrek", "pricetrek.yaml"),
+		DataDir: dataDir,
+		DB:      filepath.Join(dataDir, "trek.db"),
+		Backups: filepath.Join(dataDir, "backups"),
+	}
+}
+
+// SetDefaultStoragePath sets the database path used by configs that don't
+// set storage.path
+func SetDefaultStoragePath(path string) {
+	defaultStoragePath = path
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,87 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+)
+
+// schemaEnums lists the allowed values for fields, keyed by YAML path
+var schemaEnums = map[string][]interface{}{
+	"storage.driver":               {"sqlite", "csv"},
+	"defaults.headless.wait_until": {"load", "domcontentloaded", "networkidle"},
+	"items.provider":               {"generic", "json", "exec", "headless"},
+	"items.providers":              {"generic", "json", "exec", "headless"},
+	"item_source":                  {"merge", "config", "db"},
+	"defaults.language":            {"en", "tr"},
+}
+
+// ValidProvider reports whether name is a provider an item can use
+func ValidProvider(name string) bool {
+	return slices.Contains(schemaEnums["items.providers"], interface{}(name))
+}
+
+// Schema returns a JSON Schema (draft 2020-12) for the configuration file,
+// generated from the Config struct and its yaml tags
+func Schema() map[string]interface{} {
+	schema := schemaFor(reflect.TypeOf(Config{}), "")
+	schema["$schema"] = "https://json-schema.org/draft/2020-12/schema"
+	schema["title"] = "PriceTrek configuration"
+	return schema
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func schemaFor(t reflect.Type, path string) map[string]interface{} {
+	if t.Kind() == reflect.Ptr {
+		inner := schemaFor(t.Elem(), path)
+		return map[string]interface{}{"anyOf": []interface{}{inner, map[string]interface{}{"type": "null"}}}
+	}
+
+	schema := make(map[string]interface{})
+	if values, ok := schemaEnums[path]; ok {
+		schema["enum"] = values
+	}
+
+	switch {
+	case t == durationType:
+		// Durations accept Go duration strings ("5m", "48h") or integers
+		schema["type"] = []string{"string", "integer"}
+		schema["pattern"] = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+		return schema
+	case t.Kind() == reflect.String:
+		schema["type"] = "string"
+	case t.Kind() == reflect.Bool:
+		schema["type"] = "boolean"
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		schema["type"] = "integer"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		schema["type"] = "number"
+	case t.Kind() == reflect.Slice:
+		schema["type"] = "array"
+		schema["items"] = schemaFor(t.Elem(), path)
+	case t.Kind() == reflect.Map:
+		schema["type"] = "object"
+		schema["additionalProperties"] = schemaFor(t.Elem(), path+".*")
+	case t.Kind() == reflect.Struct:
+		schema["type"] = "object"
+		schema["additionalProperties"] = false
+		properties := make(map[string]interface{})
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if name == "" || name == "-" || !field.IsExported() {
+				continue
+			}
+			fieldPath := name
+			if path != "" {
+				fieldPath = path + "." + name
+			}
+			properties[name] = schemaFor(field.Type, fieldPath)
+		}
+		schema["properties"] = properties
+	}
+
+	return schema
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// watchDebounce coalesces the burst of events editors produce for one save
+const watchDebounce = 250 * time.Millisecond
+
+// Watch reloads the configuration file at path whenever it changes until ctx
+// is done, passing the loaded and validated config to reload. A file that
+// fails to load or validate is passed as an error instead, so the caller can
+// keep its current config. The parent directory is watched because most
+// editors save by replacing the file.
+func Watch(ctx context.Context, path string, reload func(*Config, error)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to create config watcher: %w", err)
+	}
+	if err := watcher.Add(filepath.Dir(path)); err != nil {
+		watcher.Close()
+		return fmt.Errorf("failed to watch config directory: %w", err)
+	}
+
+	name := filepath.Clean(path)
+	go func() {
+		defer watcher.Close()
+
+		debounce := time.NewTimer(0)
+		if !debounce.Stop() {
+			<-debounce.C
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				debounce.Stop()
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) != name || event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				debounce.Reset(watchDebounce)
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				reload(nil, fmt.Errorf("config watcher: %w", err))
+			case <-debounce.C:
+				cfg, err := Load(path)
+				if err != nil {
+					reload(nil, err)
+					continue
+				}
+				reload(cfg, nil)
+			}
+		}
+	}()
+	return nil
+}
@@ -2,12 +2,14 @@ package csv
 
 import (
 	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strconv"
 	"time"
 
 	"github.com/makalin/pricetrek/internal/storage"
+	"github.com/makalin/pricetrek/internal/utils"
 )
 
 // ExportItems exports items to CSV format
@@ -25,6 +27,7 @@ func ExportItems(items []storage.Item, filename string) error {
 	header := []string{
 		"id", "name", "url", "provider", "selector", "currency",
 		"target_price", "percent_drop", "schedule", "regex", "attr", "command",
+		"notes",
 	}
 	if err := writer.Write(header); err != nil {
 		return fmt.Errorf("failed to write header: %w", err)
@@ -43,7 +46,7 @@ func ExportItems(items []storage.Item, filename string) error {
 
 		// Handle optional fields
 		if item.TargetPrice != nil {
-			record = append(record, fmt.Sprintf("%.2f", *item.TargetPrice))
+			record = append(record, utils.FormatAmount(*item.TargetPrice, item.Currency))
 		} else {
 			record = append(record, "")
 		}
@@ -54,7 +57,7 @@ func ExportItems(items []storage.Item, filename string) error {
 			record = append(record, "")
 		}
 
-		record = append(record, item.Schedule, item.Regex, item.Attr, item.Command)
+		record = append(record, item.Schedule, item.Regex, item.Attr, item.Command, item.Notes)
 
 		if err := writer.Write(record); err != nil {
 			return fmt.Errorf("failed to write record: %w", err)
@@ -125,6 +128,9 @@ func ImportItems(filename string) ([]storage.Item, error) {
 		if len(record) > 11 {
 			item.Command = record[11]
 		}
+		if len(record) > 12 {
+			item.Notes = record[12]
+		}
 
 		items = append(items, item)
 	}
@@ -132,8 +138,19 @@ func ImportItems(filename string) ([]storage.Item, error) {
 	return items, nil
 }
 
-// ExportPrices exports price history to CSV format
-func ExportPrices(prices []storage.PriceSample, filename string) error {
+// PriceFormat selects how ExportPrices writes the price column
+type PriceFormat int
+
+const (
+	// PriceCurrency rounds to the currency's display decimals
+	PriceCurrency PriceFormat = iota
+	// PriceRaw writes the stored value at full precision
+	PriceRaw
+)
+
+// ExportPrices exports price history to CSV format. Each key in metaFields
+// adds a meta_<key> column holding that meta value, empty when absent.
+func ExportPrices(prices []storage.PriceSample, filename string, format PriceFormat, metaFields []string) error {
 	file, err := os.Create(filename)
 	if err != nil {
 		return fmt.Errorf("failed to create file: %w", err)
@@ -145,6 +162,9 @@ func ExportPrices(prices []storage.PriceSample, filename string) error {
 
 	// Write header
 	header := []string{"item_id", "timestamp", "price", "currency", "in_stock"}
+	for _, key := range metaFields {
+		header = append(header, "meta_"+key)
+	}
 	if err := writer.Write(header); err != nil {
 		return fmt.Errorf("failed to write header: %w", err)
 	}
@@ -158,13 +178,21 @@ func ExportPrices(prices []storage.PriceSample, filename string) error {
 			}
 		}
 
+		amount := utils.FormatAmount(price.Price, price.Currency)
+		if format == PriceRaw {
+			amount = strconv.FormatFloat(price.Price, 'f', -1, 64)
+		}
+
 		record := []string{
 			price.ItemID,
 			price.Time.Format(time.RFC3339),
-			fmt.Sprintf("%.2f", price.Price),
+			amount,
 			price.Currency,
 			inStock,
 		}
+		for _, key := range metaFields {
+			record = append(record, metaValue(price.Meta[key]))
+		}
 
 		if err := writer.Write(record); err != nil {
 			return fmt.Errorf("failed to write record: %w", err)
@@ -172,4 +200,74 @@ func ExportPrices(prices []storage.PriceSample, filename string) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
+
+// metaValue formats a meta value for a CSV cell: strings as-is, numbers and
+// booleans in their plain form and anything nested as JSON
+func metaValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// ExportAggregates exports per-period price aggregates to CSV format, one
+// row per item, currency and day or week. The date column is the first day
+// of the period.
+func ExportAggregates(aggregates []storage.Aggregate, filename string, format PriceFormat) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	writer := csv.NewWriter(file)
+
+	// Write header
+	header := []string{"date", "item_id", "currency", "samples", "open", "min", "max", "avg", "close"}
+	if err := writer.Write(header); err != nil {
+		return fmt.Errorf("failed to write header: %w", err)
+	}
+
+	amount := func(price float64, currency string) string {
+		if format == PriceRaw {
+			return strconv.FormatFloat(price, 'f', -1, 64)
+		}
+		return utils.FormatAmount(price, currency)
+	}
+
+	for _, agg := range aggregates {
+		record := []string{
+			agg.Start.Format("2006-01-02"),
+			agg.ItemID,
+			agg.Currency,
+			strconv.Itoa(agg.Samples),
+			amount(agg.Open, agg.Currency),
+			amount(agg.Min, agg.Currency),
+			amount(agg.Max, agg.Currency),
+			amount(agg.Avg, agg.Currency),
+			amount(agg.Close, agg.Currency),
+		}
+
+		if err := writer.Write(record); err != nil {
+			return fmt.Errorf("failed to write record: %w", err)
+		}
+	}
+
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,64 @@
+package csv
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/storage"
+)
+
+func TestExportAggregates(t *testing.T) {
+	week := storage.Aggregate{
+		ItemID:   "a",
+		Currency: "USD",
+		Period:   "weekly",
+		Start:    time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC),
+		Open:     19.99,
+		Close:    17.5,
+		Min:      17.5,
+		Max:      21.125,
+		Avg:      19.5383,
+		Samples:  3,
+	}
+
+	tests := []struct {
+		name       string
+		aggregates []storage.Aggregate
+		format     PriceFormat
+		want       string
+	}{
+		{
+			name:       "currency decimals",
+			aggregates: []storage.Aggregate{week},
+			want:       "date,item_id,currency,samples,open,min,max,avg,close\n2026-10-12,a,USD,3,19.99,17.50,21.13,19.54,17.50\n",
+		},
+		{
+			name:       "raw prices",
+			aggregates: []storage.Aggregate{week},
+			format:     PriceRaw,
+			want:       "date,item_id,currency,samples,open,min,max,avg,close\n2026-10-12,a,USD,3,19.99,17.5,21.125,19.5383,17.5\n",
+		},
+		{
+			name: "no rows",
+			want: "date,item_id,currency,samples,open,min,max,avg,close\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "aggregates.csv")
+			if err := ExportAggregates(tt.aggregates, path, tt.format); err != nil {
+				t.Fatalf("ExportAggregates: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("exported\n%s\nwant\n%s", data, tt.want)
+			}
+		})
+	}
+}
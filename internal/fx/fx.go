@@ -0,0 +1,198 @@
+package fx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/config"
+	"github.com/makalin/pricetrek/internal/httpclient"
+	"github.com/makalin/pricetrek/internal/storage"
+)
+
+// DefaultCacheTTL is how long fetched rates are reused when fx.cache_ttl
+// is not set
+const DefaultCacheTTL = 12 * time.Hour
+
+// Rates holds exchange rates expressed as units of each currency per one
+// unit of Base, the format returned by most public rate APIs
+type Rates struct {
+	Base    string             `json:"base"`
+	Rates   map[string]float64 `json:"rates"`
+	Fetched time.Time          `json:"fetched"`
+	// Stale is set when fetching failed and older cached rates were used
+	Stale bool `json:"stale,omitempty"`
+}
+
+// Cache keeps fetched rates between runs
+type Cache interface {
+	GetCachedRates(ctx context.Context, source string) (*storage.CachedRates, error)
+	SaveCachedRates(ctx context.Context, rates storage.CachedRates) error
+}
+
+// Load builds the rate table from fx.rates_url and the manual fx.rates
+// table; manual entries take precedence over fetched ones
+func Load(ctx context.Context, cfg config.FXConfig) (*Rates, error) {
+	var fetched *Rates
+	if cfg.RatesURL != "" {
+		var err error
+		if fetched, err = Fetch(ctx, cfg.RatesURL); err != nil {
+			return nil, err
+		}
+	}
+	return build(cfg, fetched)
+}
+
+// LoadCached is Load with fetched rates reused from cache until they are
+// older than fx.cache_ttl. refresh fetches regardless of age. When fetching
+// fails, cached rates of any age are used and the result is marked Stale.
+func LoadCached(ctx context.Context, cfg config.FXConfig, cache Cache, refresh bool) (*Rates, error) {
+	if cfg.RatesURL == "" || cache == nil {
+		return Load(ctx, cfg)
+	}
+
+	ttl := cfg.CacheTTL
+	if ttl <= 0 {
+		ttl = DefaultCacheTTL
+	}
+
+	cached, err := cache.GetCachedRates(ctx, cfg.RatesURL)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && !refresh && time.Since(cached.Fetched) < ttl {
+		return build(cfg, fromCache(cached))
+	}
+
+	fetched, err := Fetch(ctx, cfg.RatesURL)
+	if err != nil {
+		if cached == nil {
+			return nil, err
+		}
+		rates, buildErr := build(cfg, fromCache(cached))
+		if buildErr != nil {
+			return nil, buildErr
+		}
+		rates.Stale = true
+		return rates, nil
+	}
+
+	err = cache.SaveCachedRates(ctx, storage.CachedRates{
+		Source:  cfg.RatesURL,
+		Base:    fetched.Base,
+		Rates:   fetched.Rates,
+		Fetched: fetched.Fetched,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return build(cfg, fetched)
+}
+
+// fromCache copies cached rates so rebasing doesn't modify them
+func fromCache(cached *storage.CachedRates) *Rates {
+	rates := &Rates{Base: cached.Base, Rates: make(map[string]float64, len(cached.Rates)), Fetched: cached.Fetched}
+	for currency, rate := range cached.Rates {
+		rates.Rates[currency] = rate
+	}
+	return rates
+}
+
+// build combines fetched rates, if any, with the manual fx.rates table,
+// relative to fx.base
+func build(cfg config.FXConfig, fetched *Rates) (*Rates, error) {
+	rates := &Rates{
+		Base:  strings.ToUpper(cfg.Base),
+		Rates: make(map[string]float64),
+	}
+
+	if fetched != nil {
+		if rates.Base == "" {
+			rates.Base = fetched.Base
+		}
+		if fetched.Base != rates.Base {
+			// Rebase so every entry is relative to the configured base
+			pivot, ok := fetched.Rates[rates.Base]
+			if !ok || pivot <= 0 {
+				return nil, fmt.Errorf("rates from %s have no rate for base %s", cfg.RatesURL, rates.Base)
+			}
+			for currency, rate := range fetched.Rates {
+				fetched.Rates[currency] = rate / pivot
+			}
+			fetched.Rates[fetched.Base] = 1 / pivot
+		}
+		for currency, rate := range fetched.Rates {
+			rates.Rates[currency] = rate
+		}
+		rates.Fetched = fetched.Fetched
+	}
+
+	for currency, rate := range cfg.Rates {
+		rates.Rates[strings.ToUpper(currency)] = rate
+	}
+
+	if rates.Base == "" {
+		return nil, fmt.Errorf("fx.base is not configured")
+	}
+	rates.Rates[rates.Base] = 1
+
+	return rates, nil
+}
+
+// Fetch downloads a {"base": ..., "rates": {...}} document
+func Fetch(ctx context.Context, url string) (*Rates, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rates request: %w", err)
+	}
+
+	resp, err := httpclient.Client().Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("rates source returned status %d", resp.StatusCode)
+	}
+
+	var rates Rates
+	if err := json.NewDecoder(resp.Body).Decode(&rates); err != nil {
+		return nil, fmt.Errorf("failed to decode rates: %w", err)
+	}
+	if rates.Base == "" || len(rates.Rates) == 0 {
+		return nil, fmt.Errorf("rates source returned no rates")
+	}
+	rates.Base = strings.ToUpper(rates.Base)
+	rates.Fetched = time.Now()
+
+	return &rates, nil
+}
+
+// Has reports whether a rate is known for currency
+func (r *Rates) Has(currency string) bool {
+	rate, ok := r.Rates[strings.ToUpper(currency)]
+	return ok && rate > 0
+}
+
+// Convert converts amount between two currencies via the base currency
+func (r *Rates) Convert(amount float64, from, to string) (float64, error) {
+	from, to = strings.ToUpper(from), strings.ToUpper(to)
+	if from == to {
+		return amount, nil
+	}
+
+	fromRate, ok := r.Rates[from]
+	if !ok || fromRate <= 0 {
+		return 0, fmt.Errorf("no exchange rate for %s", from)
+	}
+	toRate, ok := r.Rates[to]
+	if !ok || toRate <= 0 {
+		return 0, fmt.Errorf("no exchange rate for %s", to)
+	}
+
+	return amount / fromRate * toRate, nil
+}
@@ -0,0 +1,92 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// BlockedError reports a fetch that failed on what looks like a soft-block
+// or CAPTCHA page served with a successful status
+type BlockedError struct {
+	URL    string
+	Marker string
+	Err    error
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("blocked by %s (page contains %q): %v", e.URL, e.Marker, e.Err)
+}
+
+func (e *BlockedError) Unwrap() error {
+	return e.Err
+}
+
+// BlockReport collects soft-block markers seen while handling a request
+type BlockReport struct {
+	markers []string
+
+	mu     sync.Mutex
+	url    string
+	marker string
+}
+
+// Blocked returns the URL and marker of the first suspicious page seen
+func (r *BlockReport) Blocked() (url, marker string, ok bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.url, r.marker, r.marker != ""
+}
+
+type blockKey struct{}
+
+// WithBlockReport returns a context whose HTML responses are scanned for the
+// given markers; matches are recorded in the returned report
+func WithBlockReport(ctx context.Context, markers []string) (context.Context, *BlockReport) {
+	report := &BlockReport{markers: markers}
+	return context.WithValue(ctx, blockKey{}, report), report
+}
+
+// BlockDetector scans responses for requests carrying a BlockReport
+type BlockDetector struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (d *BlockDetector) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := d.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	report, _ := req.Context().Value(blockKey{}).(*BlockReport)
+	if report == nil || !strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	lower := bytes.ToLower(body)
+	for _, marker := range report.markers {
+		if marker != "" && bytes.Contains(lower, []byte(strings.ToLower(marker))) {
+			report.mu.Lock()
+			if report.marker == "" {
+				report.url = req.URL.Redacted()
+				report.marker = marker
+			}
+			report.mu.Unlock()
+			break
+		}
+	}
+
+	return resp, nil
+}
@@ -0,0 +1,140 @@
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/logger"
+)
+
+// Options configures the shared HTTP client and transport chain
+type Options struct {
+	Timeout      time.Duration
+	UserAgent    string
+	Proxy        string // proxy URL; empty uses HTTP(S)_PROXY from the environment
+	MaxRedirects int
+	TLS          TLSOptions
+	Logger       *logger.Logger
+	Debug        bool   // log requests and responses
+	DumpDir      string // dump response bodies here when debugging
+	Timing       bool   // trace DNS/connect/TLS/TTFB timings of every request
+}
+
+var (
+	mu     sync.RWMutex
+	shared = &http.Client{Timeout: 20 * time.Second}
+	// service keeps its own transport: Configure replaces
+	// http.DefaultTransport with the fetch chain
+	service = &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone(), Timeout: 20 * time.Second}
+)
+
+// Configure builds the tuned transport chain, installs it as
+// http.DefaultTransport (used by the providers) and as the transport of the
+// shared client returned by Client. The notifiers get ServiceClient, with
+// the proxy and TLS options but none of the fetch or debug transports.
+func Configure(opts Options) error {
+	base, err := NewTransport(opts)
+	if err != nil {
+		return err
+	}
+
+	// Innermost first: the run cache sees the final request headers, and
+	// validation runs on cached responses too
+	if opts.TLS.InsecureSkipVerify && opts.Logger != nil {
+		opts.Logger.Warn("TLS certificate verification is disabled (defaults.tls.insecure_skip_verify)")
+	}
+
+	var rt http.RoundTripper = &TLSTransport{Base: base, Logger: opts.Logger}
+	if opts.Timing {
+		rt = &TimingTransport{Base: rt, Logger: opts.Logger}
+	}
+	rt = &userAgentTransport{Base: rt, UserAgent: opts.UserAgent}
+	rt = &CacheTransport{Base: rt}
+	rt = &HeaderTransport{Base: rt}
+	rt = &ContentTypeTransport{Base: rt}
+	rt = &BlockDetector{Base: rt}
+	if opts.Debug {
+		rt = &DebugTransport{Base: rt, Logger: opts.Logger, DumpDir: opts.DumpDir}
+	}
+	rt = &RedirectGuard{Base: rt, Logger: opts.Logger, MaxRedirects: opts.MaxRedirects}
+
+	mu.Lock()
+	defer mu.Unlock()
+	http.DefaultTransport = rt
+	shared = &http.Client{Transport: rt, Timeout: opts.Timeout}
+	service = &http.Client{Transport: base, Timeout: opts.Timeout}
+	return nil
+}
+
+// Client returns the shared HTTP client
+func Client() *http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return shared
+}
+
+// ServiceClient returns the client for notification and telemetry
+// endpoints. It uses the configured proxy and TLS options but skips the
+// fetch transports and --debug-http logging, whose redaction only hides
+// userinfo: bot tokens and webhook secrets sit in the URL path.
+func ServiceClient() *http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return service
+}
+
+// NewTransport returns an *http.Transport with keep-alives, pooled
+// connections and the configured proxy and TLS options
+func NewTransport(opts Options) (*http.Transport, error) {
+	proxy := http.ProxyFromEnvironment
+	if opts.Proxy != "" {
+		proxyURL, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	tlsConfig, err := opts.TLS.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+
+	return &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSClientConfig:       tlsConfig,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}, nil
+}
+
+// userAgentTransport sets the configured User-Agent on requests without one
+type userAgentTransport struct {
+	Base      http.RoundTripper
+	UserAgent string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *userAgentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.UserAgent == "" || req.Header.Get("User-Agent") != "" {
+		return t.Base.RoundTrip(req)
+	}
+
+	req = req.Clone(req.Context())
+	req.Header.Set("User-Agent", t.UserAgent)
+	return t.Base.RoundTrip(req)
+}
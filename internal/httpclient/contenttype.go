@@ -0,0 +1,87 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+type expectKey struct{}
+
+// ContentTypeError reports a response whose Content-Type doesn't match what
+// the requesting provider can parse
+type ContentTypeError struct {
+	URL      string
+	Got      string
+	Expected []string
+	Snippet  string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("unexpected content type %q from %s (expected %s); body starts with: %q",
+		e.Got, e.URL, strings.Join(e.Expected, " or "), e.Snippet)
+}
+
+// ExpectContentType returns a context whose requests must receive a
+// successful response with one of the given media types
+func ExpectContentType(ctx context.Context, mediaTypes ...string) context.Context {
+	return context.WithValue(ctx, expectKey{}, mediaTypes)
+}
+
+// ContentTypeTransport enforces expectations set with ExpectContentType
+type ContentTypeTransport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *ContentTypeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	expected, _ := req.Context().Value(expectKey{}).([]string)
+	if len(expected) == 0 || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, nil
+	}
+
+	header := resp.Header.Get("Content-Type")
+	if header == "" {
+		return resp, nil
+	}
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		mediaType = header
+	}
+	if matchesMediaType(mediaType, expected) {
+		return resp, nil
+	}
+
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+	resp.Body.Close()
+	return nil, &ContentTypeError{
+		URL:      req.URL.Redacted(),
+		Got:      mediaType,
+		Expected: expected,
+		Snippet:  strings.TrimSpace(string(snippet)),
+	}
+}
+
+// matchesMediaType compares media types case-insensitively; an expected
+// "application/json" also accepts structured suffixes like "application/ld+json"
+func matchesMediaType(mediaType string, expected []string) bool {
+	mediaType = strings.ToLower(mediaType)
+	for _, want := range expected {
+		want = strings.ToLower(want)
+		if mediaType == want {
+			return true
+		}
+		if want == "application/json" && strings.HasSuffix(mediaType, "+json") {
+			return true
+		}
+	}
+	return false
+}
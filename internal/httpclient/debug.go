@@ -0,0 +1,118 @@
+package httpclient
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/logger"
+)
+
+// redactedHeaders are never written to debug logs
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+}
+
+// DebugTransport logs every request and response passing through it and
+// optionally dumps response bodies to files for inspection
+type DebugTransport struct {
+	Base    http.RoundTripper
+	Logger  *logger.Logger
+	DumpDir string
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *DebugTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.Logger.Debug("HTTP request",
+		"method", req.Method,
+		"url", req.URL.Redacted(),
+		"headers", formatHeaders(req.Header),
+	)
+
+	start := time.Now()
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		t.Logger.Debug("HTTP request failed", "url", req.URL.Redacted(), "error", err, "duration", time.Since(start))
+		return nil, err
+	}
+
+	// Buffer the body so its length can be logged and dumped
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	t.Logger.Debug("HTTP response",
+		"url", resp.Request.URL.Redacted(),
+		"status", resp.Status,
+		"content_type", resp.Header.Get("Content-Type"),
+		"body_length", len(body),
+		"duration", time.Since(start),
+	)
+
+	if t.DumpDir != "" {
+		path, err := t.dump(req, body)
+		if err != nil {
+			t.Logger.Warn("Failed to dump HTTP response body", "error", err)
+		} else {
+			t.Logger.Debug("HTTP response body dumped", "file", path)
+		}
+	}
+
+	return resp, nil
+}
+
+func (t *DebugTransport) dump(req *http.Request, body []byte) (string, error) {
+	if err := os.MkdirAll(t.DumpDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create dump directory: %w", err)
+	}
+
+	// Create the file exclusively, adding a counter when another fetch to
+	// the same host already dumped within the same millisecond
+	prefix := fmt.Sprintf("%s_%s", time.Now().Format("20060102-150405.000"), req.URL.Hostname())
+	for seq := 0; ; seq++ {
+		name := prefix + ".body"
+		if seq > 0 {
+			name = fmt.Sprintf("%s-%d.body", prefix, seq)
+		}
+		path := filepath.Join(t.DumpDir, name)
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to create dump file: %w", err)
+		}
+		_, err = file.Write(body)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return "", fmt.Errorf("failed to write dump file: %w", err)
+		}
+		return path, nil
+	}
+}
+
+// formatHeaders renders headers for logging with credentials redacted
+func formatHeaders(header http.Header) string {
+	parts := make([]string, 0, len(header))
+	for key, values := range header {
+		value := strings.Join(values, ", ")
+		if redactedHeaders[http.CanonicalHeaderKey(key)] {
+			value = "[REDACTED]"
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", key, value))
+	}
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,50 @@
+package httpclient
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestDebugTransportDumpKeepsEveryBody(t *testing.T) {
+	dir := t.TempDir()
+	transport := &DebugTransport{DumpDir: dir}
+	req, err := http.NewRequest(http.MethodGet, "https://store.com/p/1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Dumps in a tight loop share a millisecond timestamp, so they must
+	// not overwrite each other
+	const dumps = 50
+	paths := make(map[string]string)
+	for i := 0; i < dumps; i++ {
+		body := fmt.Sprintf("body %d", i)
+		path, err := transport.dump(req, []byte(body))
+		if err != nil {
+			t.Fatalf("dump %d: %v", i, err)
+		}
+		if prev, ok := paths[path]; ok {
+			t.Fatalf("dump %d reused %s from %q", i, path, prev)
+		}
+		paths[path] = body
+	}
+
+	for path, want := range paths {
+		got, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(got) != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != dumps {
+		t.Errorf("got %d dump files, want %d", len(entries), dumps)
+	}
+}
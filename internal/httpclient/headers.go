@@ -0,0 +1,52 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+)
+
+type headersKey struct{}
+
+// WithHeaders returns a context carrying extra headers for outgoing requests.
+// Requests created with this context get the headers added by HeaderTransport
+// unless they already set them.
+func WithHeaders(ctx context.Context, header http.Header) context.Context {
+	merged := http.Header{}
+	if existing, ok := ctx.Value(headersKey{}).(http.Header); ok {
+		for key, values := range existing {
+			merged[key] = values
+		}
+	}
+	for key, values := range header {
+		merged[http.CanonicalHeaderKey(key)] = values
+	}
+	return context.WithValue(ctx, headersKey{}, merged)
+}
+
+// HeadersFromContext returns the extra headers stored by WithHeaders
+func HeadersFromContext(ctx context.Context) http.Header {
+	header, _ := ctx.Value(headersKey{}).(http.Header)
+	return header
+}
+
+// HeaderTransport adds headers carried by the request context
+type HeaderTransport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *HeaderTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	header := HeadersFromContext(req.Context())
+	if len(header) == 0 {
+		return t.Base.RoundTrip(req)
+	}
+
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	for key, values := range header {
+		if req.Header.Get(key) == "" {
+			req.Header[key] = values
+		}
+	}
+	return t.Base.RoundTrip(req)
+}
@@ -0,0 +1,107 @@
+package httpclient
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/publicsuffix"
+
+	"github.com/makalin/pricetrek/internal/logger"
+)
+
+// RedirectError reports a redirect chain that was stopped, either because it
+// left the original host or because it exceeded the configured cap
+type RedirectError struct {
+	From      string
+	To        string
+	Hops      int
+	CrossHost bool
+}
+
+func (e *RedirectError) Error() string {
+	if e.CrossHost {
+		return fmt.Sprintf("unexpected cross-host redirect from %s to %s (likely a login, consent or regional page)", e.From, e.To)
+	}
+	return fmt.Sprintf("stopped after %d redirects from %s (last: %s)", e.Hops, e.From, e.To)
+}
+
+// RedirectGuard logs each redirect hop and rejects redirect chains that leave
+// the original host or exceed MaxRedirects. It works at the transport level so
+// it applies regardless of how individual clients configure CheckRedirect.
+type RedirectGuard struct {
+	Base         http.RoundTripper
+	Logger       *logger.Logger
+	MaxRedirects int
+}
+
+// RoundTrip implements http.RoundTripper
+func (g *RedirectGuard) RoundTrip(req *http.Request) (*http.Response, error) {
+	// req.Response is set by http.Client on requests made to follow a redirect
+	if req.Response != nil {
+		origin := req
+		hops := 0
+		for origin.Response != nil && origin.Response.Request != nil {
+			origin = origin.Response.Request
+			hops++
+		}
+
+		g.Logger.Debug("Following redirect",
+			"from", req.Response.Request.URL.Redacted(),
+			"to", req.URL.Redacted(),
+			"status", req.Response.StatusCode,
+			"hop", hops,
+		)
+
+		if !sameHost(origin.URL.Hostname(), req.URL.Hostname()) {
+			return nil, &RedirectError{
+				From:      origin.URL.Redacted(),
+				To:        req.URL.Redacted(),
+				Hops:      hops,
+				CrossHost: true,
+			}
+		}
+		if g.MaxRedirects > 0 && hops > g.MaxRedirects {
+			return nil, &RedirectError{
+				From: origin.URL.Redacted(),
+				To:   req.URL.Redacted(),
+				Hops: hops,
+			}
+		}
+	}
+
+	return g.Base.RoundTrip(req)
+}
+
+// sameHost reports whether two hosts belong to the same site: the same
+// registrable domain, so www.store.com, m.store.com and store.com match
+// while store.co.uk and other.co.uk don't
+func sameHost(a, b string) bool {
+	return normalizeHost(a) == normalizeHost(b)
+}
+
+// HostKey returns the host of rawURL the way per-host limits count it, as
+// sameHost compares hosts, or "" when rawURL has no host
+func HostKey(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return ""
+	}
+	return normalizeHost(u.Hostname())
+}
+
+// normalizeHost returns the registrable domain of host (its public suffix
+// plus one label), or the lowercased host itself for IP addresses and names
+// like localhost that have none
+func normalizeHost(host string) string {
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	if domain, err := publicsuffix.EffectiveTLDPlusOne(host); err == nil {
+		return domain
+	}
+	return host
+}
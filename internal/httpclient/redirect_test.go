@@ -0,0 +1,49 @@
+package httpclient
+
+import "testing"
+
+func TestSameHost(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"www.store.com", "store.com", true},
+		{"WWW.Store.com", "store.com", true},
+		{"m.store.com", "www.store.com", true},
+		{"shop.store.co.uk", "store.co.uk", true},
+		{"store.co.uk", "other.co.uk", false},
+		{"store.com", "store.com.evil.net", false},
+		{"store.com", "login.example.com", false},
+		{"user.github.io", "other.github.io", false},
+		{"localhost", "localhost", true},
+		{"127.0.0.1", "127.0.0.1", true},
+		{"10.0.0.1", "127.0.0.1", false},
+		{"store.com.", "store.com", true},
+	}
+
+	for _, tt := range tests {
+		if got := sameHost(tt.a, tt.b); got != tt.want {
+			t.Errorf("sameHost(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestHostKey(t *testing.T) {
+	tests := []struct {
+		url  string
+		want string
+	}{
+		{"https://www.store.com/p/1", "store.com"},
+		{"https://m.store.co.uk/p/1", "store.co.uk"},
+		{"http://127.0.0.1:8080/p", "127.0.0.1"},
+		{"http://localhost:8080/p", "localhost"},
+		{"not a url\x7f", ""},
+		{"/relative/path", ""},
+	}
+
+	for _, tt := range tests {
+		if got := HostKey(tt.url); got != tt.want {
+			t.Errorf("HostKey(%q) = %q, want %q", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package httpclient
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// RunCache memoizes successful GET responses for the lifetime of a single
+// run, so items sharing a page don't fetch it more than once
+type RunCache struct {
+	mu        sync.Mutex
+	responses map[string][]byte
+	hits      int
+}
+
+type runCacheKey struct{}
+
+type cacheKeyKey struct{}
+
+// WithCacheKey makes requests made with ctx share cached responses with
+// other requests for the same URL under key, whatever their headers
+func WithCacheKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, cacheKeyKey{}, key)
+}
+
+// WithRunCache returns a context whose GET requests share a new RunCache
+func WithRunCache(ctx context.Context) (context.Context, *RunCache) {
+	cache := &RunCache{responses: make(map[string][]byte)}
+	return context.WithValue(ctx, runCacheKey{}, cache), cache
+}
+
+// Hits returns how many requests were answered from the cache
+func (c *RunCache) Hits() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits
+}
+
+// Clear drops all cached responses
+func (c *RunCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses = make(map[string][]byte)
+}
+
+func (c *RunCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	raw, ok := c.responses[key]
+	if ok {
+		c.hits++
+	}
+	return raw, ok
+}
+
+func (c *RunCache) put(key string, raw []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses[key] = raw
+}
+
+// CacheTransport serves repeated requests from the context's RunCache
+type CacheTransport struct {
+	Base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *CacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	cache, _ := req.Context().Value(runCacheKey{}).(*RunCache)
+	if cache == nil || req.Method != http.MethodGet {
+		return t.Base.RoundTrip(req)
+	}
+
+	key := requestKey(req)
+	if shared, ok := req.Context().Value(cacheKeyKey{}).(string); ok && shared != "" {
+		key = "key:" + shared + " " + req.URL.String()
+	}
+	if raw, ok := cache.get(key); ok {
+		resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(raw)), req)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read cached response: %w", err)
+		}
+		return resp, nil
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil || resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp, err
+	}
+
+	raw, err := httputil.DumpResponse(resp, true)
+	if err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	cache.put(key, raw)
+
+	return resp, nil
+}
+
+// requestKey identifies a request by method, URL and all headers
+func requestKey(req *http.Request) string {
+	var key strings.Builder
+	key.WriteString(req.Method + " " + req.URL.String())
+
+	names := make([]string, 0, len(req.Header))
+	for name := range req.Header {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		key.WriteString("\n" + name + ": " + strings.Join(req.Header[name], ", "))
+	}
+	return key.String()
+}
@@ -0,0 +1,69 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCacheTransportSharedKey(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		io.WriteString(w, r.URL.Path)
+	}))
+	defer server.Close()
+
+	type fetch struct {
+		path, key string
+	}
+	tests := []struct {
+		name         string
+		fetches      []fetch
+		wantRequests int
+	}{
+		{
+			name:         "same key and URL share the page",
+			fetches:      []fetch{{"/a", "page"}, {"/a", "page"}},
+			wantRequests: 1,
+		},
+		{
+			name:         "same key on different URLs",
+			fetches:      []fetch{{"/a", "page"}, {"/b", "page"}},
+			wantRequests: 2,
+		},
+		{
+			name:         "different keys on the same URL",
+			fetches:      []fetch{{"/a", "one"}, {"/a", "two"}},
+			wantRequests: 2,
+		},
+	}
+
+	client := &http.Client{Transport: &CacheTransport{Base: http.DefaultTransport}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests = 0
+			ctx, _ := WithRunCache(context.Background())
+			for _, f := range tt.fetches {
+				req, err := http.NewRequestWithContext(WithCacheKey(ctx, f.key), http.MethodGet, server.URL+f.path, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				resp, err := client.Do(req)
+				if err != nil {
+					t.Fatalf("GET %s: %v", f.path, err)
+				}
+				body, _ := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if string(body) != f.path {
+					t.Errorf("GET %s with key %q returned the page of %s", f.path, f.key, body)
+				}
+			}
+			if requests != tt.wantRequests {
+				t.Errorf("server saw %d requests, want %d", requests, tt.wantRequests)
+			}
+		})
+	}
+}
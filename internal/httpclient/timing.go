@@ -0,0 +1,141 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/logger"
+)
+
+// Timing breaks a request's latency down by phase. Phases skipped because
+// a pooled connection was reused are zero.
+type Timing struct {
+	DNS     time.Duration
+	Connect time.Duration
+	TLS     time.Duration
+	// TTFB runs from writing the request to the first response byte
+	TTFB time.Duration
+	// Total runs until the response headers arrive
+	Total  time.Duration
+	Reused bool
+}
+
+// Millis returns the phases in milliseconds, keyed for sample meta
+func (t Timing) Millis() map[string]interface{} {
+	ms := func(d time.Duration) float64 {
+		return float64(d.Microseconds()) / 1000
+	}
+	return map[string]interface{}{
+		"dns_ms":     ms(t.DNS),
+		"connect_ms": ms(t.Connect),
+		"tls_ms":     ms(t.TLS),
+		"ttfb_ms":    ms(t.TTFB),
+		"total_ms":   ms(t.Total),
+		"reused":     t.Reused,
+	}
+}
+
+// TimingRecorder keeps the timing of the last request made with its context
+type TimingRecorder struct {
+	mu   sync.Mutex
+	last *Timing
+}
+
+// Last returns the most recent timing, or nil if no request was traced
+func (r *TimingRecorder) Last() *Timing {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.last
+}
+
+type timingKey struct{}
+
+// WithTimingRecorder returns a context whose traced requests are recorded
+// in the returned recorder. Requests are only traced when the client was
+// configured with Options.Timing.
+func WithTimingRecorder(ctx context.Context) (context.Context, *TimingRecorder) {
+	recorder := &TimingRecorder{}
+	return context.WithValue(ctx, timingKey{}, recorder), recorder
+}
+
+// TimingTransport traces DNS, connect, TLS and time to first byte of every
+// request that reaches the network, logs them at debug level and hands them
+// to the context's TimingRecorder
+type TimingTransport struct {
+	Base   http.RoundTripper
+	Logger *logger.Logger
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *TimingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// Trace hooks may run on the transport's dial goroutines
+	var (
+		mu                                           sync.Mutex
+		timing                                       Timing
+		dnsStart, connectStart, tlsStart, wroteStart time.Time
+	)
+	locked := func(f func()) {
+		mu.Lock()
+		defer mu.Unlock()
+		f()
+	}
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { locked(func() { dnsStart = time.Now() }) },
+		DNSDone:  func(httptrace.DNSDoneInfo) { locked(func() { timing.DNS = time.Since(dnsStart) }) },
+		ConnectStart: func(string, string) {
+			locked(func() {
+				if connectStart.IsZero() {
+					connectStart = time.Now()
+				}
+			})
+		},
+		ConnectDone:       func(string, string, error) { locked(func() { timing.Connect = time.Since(connectStart) }) },
+		TLSHandshakeStart: func() { locked(func() { tlsStart = time.Now() }) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { locked(func() { timing.TLS = time.Since(tlsStart) }) },
+		GotConn:           func(info httptrace.GotConnInfo) { locked(func() { timing.Reused = info.Reused }) },
+		WroteRequest:      func(httptrace.WroteRequestInfo) { locked(func() { wroteStart = time.Now() }) },
+		GotFirstResponseByte: func() {
+			locked(func() {
+				if !wroteStart.IsZero() {
+					timing.TTFB = time.Since(wroteStart)
+				}
+			})
+		},
+	}
+
+	start := time.Now()
+	resp, err := t.Base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+	if err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	timing.Total = time.Since(start)
+	result := timing
+	mu.Unlock()
+	if result.Reused {
+		// A dial racing the idle connection may still have traced phases
+		result.DNS, result.Connect, result.TLS = 0, 0, 0
+	}
+
+	if t.Logger != nil {
+		t.Logger.Debug("HTTP timing",
+			"url", req.URL.Redacted(),
+			"dns", result.DNS,
+			"connect", result.Connect,
+			"tls", result.TLS,
+			"ttfb", result.TTFB,
+			"total", result.Total,
+			"reused", result.Reused,
+		)
+	}
+	if recorder, ok := req.Context().Value(timingKey{}).(*TimingRecorder); ok {
+		recorder.mu.Lock()
+		recorder.last = &result
+		recorder.mu.Unlock()
+	}
+	return resp, nil
+}
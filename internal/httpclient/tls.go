@@ -0,0 +1,108 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+
+	"github.com/makalin/pricetrek/internal/logger"
+)
+
+// TLSOptions relaxes or extends certificate verification for self-hosted
+// and intranet targets
+type TLSOptions struct {
+	InsecureSkipVerify bool
+	CAFile             string // PEM bundle trusted in addition to the system roots
+}
+
+// IsZero reports whether the options leave verification at Go's defaults
+func (o TLSOptions) IsZero() bool {
+	return !o.InsecureSkipVerify && o.CAFile == ""
+}
+
+// TLSConfig builds the tls.Config for the options, or nil for the defaults
+func (o TLSOptions) TLSConfig() (*tls.Config, error) {
+	if o.IsZero() {
+		return nil, nil
+	}
+
+	config := &tls.Config{InsecureSkipVerify: o.InsecureSkipVerify}
+	if o.CAFile != "" {
+		pem, err := os.ReadFile(o.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", o.CAFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
+
+type tlsKey struct{}
+
+// WithTLS returns a context whose requests use the given TLS options instead
+// of the client's defaults
+func WithTLS(ctx context.Context, opts TLSOptions) context.Context {
+	return context.WithValue(ctx, tlsKey{}, opts)
+}
+
+// TLSTransport sends requests carrying TLSOptions in their context through a
+// transport built for those options, and everything else through Base.
+// Per-option transports are created once and reused, so their connections
+// are pooled like the default one.
+type TLSTransport struct {
+	Base   *http.Transport
+	Logger *logger.Logger
+
+	mu         sync.Mutex
+	transports map[TLSOptions]*http.Transport
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *TLSTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	opts, ok := req.Context().Value(tlsKey{}).(TLSOptions)
+	if !ok {
+		return t.Base.RoundTrip(req)
+	}
+
+	transport, err := t.transport(opts)
+	if err != nil {
+		return nil, err
+	}
+	return transport.RoundTrip(req)
+}
+
+func (t *TLSTransport) transport(opts TLSOptions) (*http.Transport, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if transport, ok := t.transports[opts]; ok {
+		return transport, nil
+	}
+
+	config, err := opts.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if opts.InsecureSkipVerify && t.Logger != nil {
+		t.Logger.Warn("TLS certificate verification disabled for item requests")
+	}
+
+	transport := t.Base.Clone()
+	transport.TLSClientConfig = config
+	if t.transports == nil {
+		t.transports = make(map[TLSOptions]*http.Transport)
+	}
+	t.transports[opts] = transport
+	return transport, nil
+}
@@ -7,12 +7,13 @@ import (
 
 type Logger struct {
 	*slog.Logger
+	level *slog.LevelVar
 }
 
 func New(verbose bool) *Logger {
-	level := slog.LevelInfo
+	level := &slog.LevelVar{}
 	if verbose {
-		level = slog.LevelDebug
+		level.Set(slog.LevelDebug)
 	}
 
 	opts := &slog.HandlerOptions{
@@ -22,9 +23,15 @@ func New(verbose bool) *Logger {
 	handler := slog.NewTextHandler(os.Stderr, opts)
 	return &Logger{
 		Logger: slog.New(handler),
+		level:  level,
 	}
 }
 
+// SetLevel changes the minimum level of records that are written
+func (l *Logger) SetLevel(level slog.Level) {
+	l.level.Set(level)
+}
+
 func (l *Logger) Fatal(msg string, args ...any) {
 	l.Error(msg, args...)
 	os.Exit(1)
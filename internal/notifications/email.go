@@ -3,8 +3,11 @@ package notifications
 import (
 	"context"
 	"fmt"
-	"os"
+	"html"
+	"io"
+	"strings"
 
+	"github.com/makalin/pricetrek/internal/utils"
 	"gopkg.in/gomail.v2"
 )
 
@@ -13,21 +16,33 @@ type EmailNotifier struct {
 	to   []string
 }
 
-func (e *EmailNotifier) Send(ctx context.Context, message string) error {
+func (e *EmailNotifier) Name() string {
+	return "email"
+}
+
+func (e *EmailNotifier) Send(ctx context.Context, alert Alert) error {
 	// Get SMTP configuration from environment
-	smtpHost := os.Getenv("PRICETREK_EMAIL_SMTP")
-	smtpPort := os.Getenv("PRICETREK_EMAIL_PORT")
-	smtpUser := os.Getenv("PRICETREK_EMAIL_USER")
-	smtpPass := os.Getenv("PRICETREK_EMAIL_PASS")
+	settings := make(map[string]string, 4)
+	for _, name := range []string{"PRICETREK_EMAIL_SMTP", "PRICETREK_EMAIL_PORT", "PRICETREK_EMAIL_USER", "PRICETREK_EMAIL_PASS"} {
+		value, err := envSecret(name)
+		if err != nil {
+			return err
+		}
+		settings[name] = value
+	}
+	smtpHost := settings["PRICETREK_EMAIL_SMTP"]
+	smtpPort := settings["PRICETREK_EMAIL_PORT"]
+	smtpUser := settings["PRICETREK_EMAIL_USER"]
+	smtpPass := settings["PRICETREK_EMAIL_PASS"]
 
 	if smtpHost == "" {
-		return fmt.Errorf("PRICETREK_EMAIL_SMTP environment variable not set")
+		return notSetError("PRICETREK_EMAIL_SMTP")
 	}
 	if smtpUser == "" {
-		return fmt.Errorf("PRICETREK_EMAIL_USER environment variable not set")
+		return notSetError("PRICETREK_EMAIL_USER")
 	}
 	if smtpPass == "" {
-		return fmt.Errorf("PRICETREK_EMAIL_PASS environment variable not set")
+		return notSetError("PRICETREK_EMAIL_PASS")
 	}
 
 	// Default port
@@ -35,27 +50,60 @@ func (e *EmailNotifier) Send(ctx context.Context, message string) error {
 		smtpPort = "587"
 	}
 
-	// Create message
+	m, err := e.message(alert)
+	if err != nil {
+		return err
+	}
+
+	// Send email
+	d := gomail.NewDialer(smtpHost, 587, smtpUser, smtpPass)
+	if err := d.DialAndSend(m); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+
+	return nil
+}
+
+// chartFile is the name and Content-ID of the inline price chart
+const chartFile = "chart.png"
+
+// message builds the alert mail: a plain-text part, the HTML alternative
+// and, when the alert carries a price history, an inline PNG chart the HTML
+// shows through a cid: reference
+func (e *EmailNotifier) message(alert Alert) (*gomail.Message, error) {
+	message := alert.Text()
+	msgs := alert.messages()
+
 	m := gomail.NewMessage()
 	m.SetHeader("From", e.from)
 	m.SetHeader("To", e.to...)
-	m.SetHeader("Subject", "PriceTrek Alert")
-	m.SetBody("text/html", fmt.Sprintf(`
+	m.SetHeader("Subject", msgs.title)
+
+	chart, err := utils.PriceChartPNG(alert.History, 480, 160)
+	if err != nil {
+		return nil, fmt.Errorf("failed to render price chart: %w", err)
+	}
+	var img string
+	if chart != nil {
+		m.Embed(chartFile, gomail.SetCopyFunc(func(w io.Writer) error {
+			_, err := w.Write(chart)
+			return err
+		}))
+		img = fmt.Sprintf(`<p><img src="cid:%s" alt="%s" width="480" height="160"></p>`, chartFile, html.EscapeString(msgs.title))
+	}
+
+	// Plain text first so clients without HTML support show it
+	m.SetBody("text/plain", message)
+	m.AddAlternative("text/html", fmt.Sprintf(`
 		<html>
 		<body>
-			<h2>PriceTrek Alert</h2>
+			<h2>%s</h2>
 			<p>%s</p>
+			%s
 			<hr>
-			<p><small>This is an automated message from PriceTrek</small></p>
+			<p><small>%s</small></p>
 		</body>
 		</html>
-	`, message))
-
-	// Send email
-	d := gomail.NewDialer(smtpHost, 587, smtpUser, smtpPass)
-	if err := d.DialAndSend(m); err != nil {
-		return fmt.Errorf("failed to send email: %w", err)
-	}
-
-	return nil
-}
\ No newline at end of file
+	`, html.EscapeString(msgs.title), strings.ReplaceAll(html.EscapeString(message), "\n", "<br>"), img, html.EscapeString(msgs.footer)))
+	return m, nil
+}
@@ -0,0 +1,46 @@
+package notifications
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestEmailMessageChart(t *testing.T) {
+	tests := []struct {
+		name      string
+		history   []float64
+		wantChart bool
+	}{
+		{name: "price history", history: []float64{120, 110, 115, 99}, wantChart: true},
+		{name: "single price", history: []float64{99}},
+		{name: "no history"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := &EmailNotifier{from: "alerts@example.com", to: []string{"me@example.com"}}
+			m, err := e.message(Alert{Rule: RuleDrop, ItemID: "a", ItemName: "Widget", Price: 99, PreviousPrice: 115, Currency: "USD", History: tt.history})
+			if err != nil {
+				t.Fatalf("message: %v", err)
+			}
+			var buf bytes.Buffer
+			if _, err := m.WriteTo(&buf); err != nil {
+				t.Fatalf("WriteTo: %v", err)
+			}
+			mail := buf.String()
+
+			for _, part := range []string{"text/plain", "text/html"} {
+				if !strings.Contains(mail, part) {
+					t.Errorf("mail has no %s part", part)
+				}
+			}
+			if got := strings.Contains(mail, "cid:chart.png"); got != tt.wantChart {
+				t.Errorf("HTML references the chart = %v, want %v", got, tt.wantChart)
+			}
+			if got := strings.Contains(mail, "Content-ID: <chart.png>"); got != tt.wantChart {
+				t.Errorf("chart attached inline = %v, want %v", got, tt.wantChart)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type ExecNotifier struct {
+	command string
+	timeout time.Duration
+}
+
+func (e *ExecNotifier) Name() string {
+	return "exec"
+}
+
+// Send runs the configured command with the alert as JSON on stdin and the
+// key fields as PRICETREK_ALERT_* environment variables
+func (e *ExecNotifier) Send(ctx context.Context, alert Alert) error {
+	if e.command == "" {
+		return permanent(fmt.Errorf("exec notifier command not configured"))
+	}
+
+	payload, err := json.Marshal(alert)
+	if err != nil {
+		return permanent(fmt.Errorf("failed to marshal alert: %w", err))
+	}
+
+	timeout := e.timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", e.command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", e.command)
+	}
+
+	cmd.Stdin = bytes.NewReader(payload)
+	cmd.Env = append(os.Environ(),
+		"PRICETREK_ALERT_RULE="+alert.Rule,
+		"PRICETREK_ALERT_ITEM_ID="+alert.ItemID,
+		"PRICETREK_ALERT_ITEM_NAME="+alert.ItemName,
+		"PRICETREK_ALERT_URL="+alert.URL,
+		"PRICETREK_ALERT_PRICE="+strconv.FormatFloat(alert.Price, 'f', -1, 64),
+		"PRICETREK_ALERT_CURRENCY="+alert.Currency,
+		"PRICETREK_ALERT_MESSAGE="+alert.Text(),
+	)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	// Don't wait on grandchildren holding stderr open after a timeout
+	cmd.WaitDelay = time.Second
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("exec notifier timed out after %v", timeout)
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("exec notifier failed: %w: %s", err, msg)
+		}
+		return fmt.Errorf("exec notifier failed: %w", err)
+	}
+
+	return nil
+}
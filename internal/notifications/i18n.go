@@ -0,0 +1,199 @@
+package notifications
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"text/template"
+
+	"github.com/makalin/pricetrek/internal/config"
+	"github.com/makalin/pricetrek/internal/utils"
+)
+
+// messages is the catalog of alert strings for one language. Rule messages
+// are fmt formats taking, by index: 1 item name, 2 price with currency,
+// 3 previous price, 4 change or trend percent, 5 target price, 6 days.
+type messages struct {
+	target, drop, rise, aboveTarget, velocity, other string
+	// targetSuffix is appended to target messages when the item has one
+	targetSuffix string
+
+	title, footer, open                       string
+	price, previous, change, trend, targetLbl string
+	// trendValue takes the trend percent and the number of days
+	trendValue string
+
+	// decimal and group separate the fraction and the thousands in amounts
+	decimal, group string
+	// percentFirst writes the percent sign before the number, as in "%8,5"
+	percentFirst bool
+}
+
+// catalog holds the built-in languages, keyed by defaults.language
+var catalog = map[string]*messages{
+	"en": {
+		target:       "%[1]s reached target price: %[2]s",
+		drop:         "%[1]s dropped %[4]s to %[2]s (was %[3]s)",
+		rise:         "%[1]s rose %[4]s to %[2]s (was %[3]s)",
+		aboveTarget:  "%[1]s is back above target: %[2]s",
+		velocity:     "%[1]s is falling %[4]s/day over %[6]d days, now %[2]s",
+		other:        "%[1]s is now %[2]s",
+		targetSuffix: " (target %[5]s)",
+		title:        "PriceTrek Alert",
+		footer:       "This is an automated message from PriceTrek",
+		open:         "Open product",
+		price:        "Price",
+		previous:     "Previous",
+		change:       "Change",
+		trend:        "Trend",
+		targetLbl:    "Target",
+		trendValue:   "%[1]s/day over %[2]d days",
+		decimal:      ".",
+	},
+	"tr": {
+		target:       "%[1]s hedef fiyata ulaştı: %[2]s",
+		drop:         "%[1]s fiyatı %[4]s düştü: %[2]s (önceki %[3]s)",
+		rise:         "%[1]s fiyatı %[4]s arttı: %[2]s (önceki %[3]s)",
+		aboveTarget:  "%[1]s yeniden hedefin üzerinde: %[2]s",
+		velocity:     "%[1]s son %[6]d gündür günlük %[4]s düşüyor, şu an %[2]s",
+		other:        "%[1]s şu an %[2]s",
+		targetSuffix: " (hedef %[5]s)",
+		title:        "PriceTrek Uyarısı",
+		footer:       "Bu mesaj PriceTrek tarafından otomatik olarak gönderildi",
+		open:         "Ürüne git",
+		price:        "Fiyat",
+		previous:     "Önceki",
+		change:       "Değişim",
+		trend:        "Eğilim",
+		targetLbl:    "Hedef",
+		trendValue:   "son %[2]d gündür günlük %[1]s",
+		decimal:      ",",
+		group:        ".",
+		percentFirst: true,
+	},
+}
+
+// defaultLanguage is used when none is configured or the timezone doesn't
+// suggest one
+const defaultLanguage = "en"
+
+// timezoneLanguages picks a language from defaults.timezone when
+// defaults.language is empty
+var timezoneLanguages = map[string]string{
+	"Europe/Istanbul": "tr",
+	"Asia/Istanbul":   "tr",
+	"Turkey":          "tr",
+}
+
+// SupportedLanguage reports whether alerts can be written in lang
+func SupportedLanguage(lang string) bool {
+	_, ok := catalog[lang]
+	return ok
+}
+
+// Language returns the alert language for cfg: defaults.language, else the
+// one matching defaults.timezone, else English
+func Language(cfg *config.Config) string {
+	if lang := strings.ToLower(cfg.Defaults.Language); lang != "" {
+		return lang
+	}
+	if lang, ok := timezoneLanguages[cfg.Defaults.Timezone]; ok {
+		return lang
+	}
+	return defaultLanguage
+}
+
+// amount formats a price with the currency's decimals and the language's
+// separators
+func (m *messages) amount(price float64, currency string) string {
+	value := utils.FormatAmount(price, currency)
+	sign := ""
+	if strings.HasPrefix(value, "-") {
+		sign, value = "-", value[1:]
+	}
+	whole, fraction, hasFraction := strings.Cut(value, ".")
+	if m.group != "" {
+		var b strings.Builder
+		for i, digit := range whole {
+			if i > 0 && (len(whole)-i)%3 == 0 {
+				b.WriteString(m.group)
+			}
+			b.WriteRune(digit)
+		}
+		whole = b.String()
+	}
+	if hasFraction {
+		return sign + whole + m.decimal + fraction
+	}
+	return sign + whole
+}
+
+// money formats a price followed by its currency code
+func (m *messages) money(price float64, currency string) string {
+	return m.amount(price, currency) + " " + currency
+}
+
+// percent formats a percentage with one decimal, with a leading + when
+// signed is set and the value is positive
+func (m *messages) percent(value float64, signed bool) string {
+	number := strings.Replace(fmt.Sprintf("%.1f", math.Abs(value)), ".", m.decimal, 1)
+	sign := ""
+	if value < 0 {
+		sign = "-"
+	} else if signed {
+		sign = "+"
+	}
+	if m.percentFirst {
+		return sign + "%" + number
+	}
+	return sign + number + "%"
+}
+
+// text renders the alert message for a rule, followed by the product link
+func (m *messages) text(a Alert) string {
+	name := a.ItemName
+	if name == "" {
+		name = a.ItemID
+	}
+
+	var format, change string
+	switch a.Rule {
+	case RuleTarget:
+		format = m.target
+	case RuleDrop:
+		format, change = m.drop, m.percent(-a.ChangePercent, false)
+	case RuleRise:
+		format, change = m.rise, m.percent(a.ChangePercent, false)
+	case RuleAboveTarget:
+		format = m.aboveTarget
+	case RuleVelocity:
+		format, change = m.velocity, m.percent(-a.Velocity, false)
+	default:
+		format = m.other
+	}
+
+	var target string
+	if a.TargetPrice != nil {
+		target = m.amount(*a.TargetPrice, a.Currency)
+		if a.Rule == RuleTarget || a.Rule == RuleAboveTarget {
+			format += m.targetSuffix
+		}
+	}
+
+	text := fmt.Sprintf(format, name, m.money(a.Price, a.Currency),
+		m.amount(a.PreviousPrice, a.Currency), change, target, a.VelocityDays)
+	if a.URL != "" {
+		text += "\n" + a.URL
+	}
+	return text
+}
+
+// templateFuncs are available in notifications.templates, formatting like
+// the built-in messages of the configured language
+func templateFuncs(m *messages) template.FuncMap {
+	return template.FuncMap{
+		"amount":  m.amount,
+		"money":   m.money,
+		"percent": func(value float64) string { return m.percent(value, false) },
+	}
+}
@@ -2,21 +2,124 @@ package notifications
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"os"
+	"maps"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
 
 	"github.com/makalin/pricetrek/internal/config"
+	"github.com/makalin/pricetrek/internal/logger"
 )
 
+// Alert rule names used for routing notifications to channels
+const (
+	RuleTarget      = "target"
+	RuleDrop        = "drop"
+	RuleRise        = "rise"
+	RuleAboveTarget = "above_target"
+	RuleVelocity    = "velocity"
+)
+
+// rules lists every alert rule, for validating routes
+var rules = []string{RuleTarget, RuleDrop, RuleRise, RuleAboveTarget, RuleVelocity}
+
+// channelsEnabled reports which notification channels cfg enables, by name
+func channelsEnabled(cfg config.NotificationsConfig) map[string]bool {
+	return map[string]bool{
+		"email":    cfg.Email.Enabled,
+		"telegram": cfg.Telegram.Enabled,
+		"slack":    cfg.Slack.Enabled,
+		"ntfy":     cfg.Ntfy.Enabled,
+		"exec":     cfg.Exec.Enabled,
+	}
+}
+
+// ValidateRoutes checks that every notifications.routes entry names a known
+// alert rule and only channels that are enabled, since alerts routed
+// anywhere else are silently never delivered
+func ValidateRoutes(cfg config.NotificationsConfig) error {
+	enabled := channelsEnabled(cfg)
+
+	var errs []error
+	for _, rule := range slices.Sorted(maps.Keys(cfg.Routes)) {
+		if !slices.Contains(rules, rule) {
+			errs = append(errs, fmt.Errorf("routes.%s: unknown alert rule (known: %s)", rule, strings.Join(rules, ", ")))
+			continue
+		}
+		for _, channel := range cfg.Routes[rule] {
+			on, known := enabled[strings.ToLower(channel)]
+			switch {
+			case !known:
+				errs = append(errs, fmt.Errorf("routes.%s: unknown channel %q (email, telegram, slack, ntfy, exec)", rule, channel))
+			case !on:
+				errs = append(errs, fmt.Errorf("routes.%s: channel %s is not enabled", rule, channel))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Alert is a structured price alert handed to every notifier
+type Alert struct {
+	Rule          string    `json:"rule"`
+	ItemID        string    `json:"item_id"`
+	ItemName      string    `json:"item_name"`
+	URL           string    `json:"url,omitempty"`
+	Price         float64   `json:"price"`
+	PreviousPrice float64   `json:"previous_price,omitempty"`
+	TargetPrice   *float64  `json:"target_price,omitempty"`
+	ChangePercent float64   `json:"change_percent,omitempty"`
+	Currency      string    `json:"currency"`
+	Time          time.Time `json:"time"`
+	Message       string    `json:"message,omitempty"`
+	// Velocity is the trend in percent per day over VelocityDays, for
+	// velocity alerts
+	Velocity      float64   `json:"velocity_pct_per_day,omitempty"`
+	VelocityDays  int       `json:"velocity_days,omitempty"`
+	// History is the item's recent prices, oldest first, charted in email
+	// alerts
+	History []float64 `json:"-"`
+
+	// msgs is set by the notification manager to localize the message
+	msgs *messages
+}
+
+// Text returns the human readable alert message, in the language the
+// notification manager was configured with
+func (a Alert) Text() string {
+	if a.Message != "" {
+		return a.Message
+	}
+	return a.messages().text(a)
+}
+
+// messages returns the catalog the alert is written in
+func (a Alert) messages() *messages {
+	if a.msgs == nil {
+		return catalog[defaultLanguage]
+	}
+	return a.msgs
+}
+
 type Notifier interface {
-	Send(ctx context.Context, message string) error
+	Name() string
+	Send(ctx context.Context, alert Alert) error
 }
 
 type NotificationManager struct {
 	notifiers []Notifier
+	routes    map[string][]string
+	retry     config.RetryConfig
+	logger    *logger.Logger
+	link      *template.Template
+	msgs      *messages
+	templates map[string]*template.Template
 }
 
-func New(cfg *config.Config) *NotificationManager {
+func New(cfg *config.Config, log *logger.Logger) *NotificationManager {
 	var notifiers []Notifier
 
 	// Email notifier
@@ -38,6 +141,7 @@ func New(cfg *config.Config) *NotificationManager {
 	if cfg.Notifications.Slack.Enabled {
 		notifiers = append(notifiers, &SlackNotifier{
 			webhook: cfg.Notifications.Slack.Webhook,
+			blocks:  cfg.Notifications.Slack.Blocks,
 		})
 	}
 
@@ -48,54 +152,132 @@ func New(cfg *config.Config) *NotificationManager {
 		})
 	}
 
-	return &NotificationManager{
+	// Exec notifier
+	if cfg.Notifications.Exec.Enabled {
+		notifiers = append(notifiers, &ExecNotifier{
+			command: cfg.Notifications.Exec.Command,
+			timeout: cfg.Notifications.Exec.Timeout,
+		})
+	}
+
+	nm := &NotificationManager{
 		notifiers: notifiers,
+		routes:    cfg.Notifications.Routes,
+		retry:     cfg.Defaults.Retry,
+		logger:    log,
 	}
-}
 
-func (nm *NotificationManager) Send(ctx context.Context, message string) error {
-	for _, notifier := range nm.notifiers {
-		if err := notifier.Send(ctx, message); err != nil {
-			// Log error but continue with other notifiers
-			fmt.Fprintf(os.Stderr, "Failed to send notification: %v\n", err)
+	if err := ValidateRoutes(cfg.Notifications); err != nil {
+		log.Warn("Invalid notifications.routes, some alerts won't be delivered", "error", err)
+	}
+
+	if linkTemplate := cfg.Notifications.LinkTemplate; linkTemplate != "" {
+		link, err := template.New("link").Parse(linkTemplate)
+		if err != nil {
+			log.Error("Invalid notifications.link_template, links are sent unchanged", "error", err)
+		} else {
+			nm.link = link
 		}
 	}
-	return nil
-}
 
-type EmailNotifier struct {
-	from string
-	to   []string
-}
+	lang := Language(cfg)
+	nm.msgs = catalog[lang]
+	if nm.msgs == nil {
+		log.Warn("Unsupported defaults.language, alerts are sent in English", "language", lang)
+		nm.msgs = catalog[defaultLanguage]
+	}
 
-func (e *EmailNotifier) Send(ctx context.Context, message string) error {
-	// TODO: Implement email notification
-	return fmt.Errorf("email notification not implemented yet")
-}
+	for rule, text := range cfg.Notifications.Templates {
+		tmpl, err := template.New(rule).Funcs(templateFuncs(nm.msgs)).Parse(text)
+		if err != nil {
+			log.Error("Invalid notifications.templates entry, the built-in message is used", "rule", rule, "error", err)
+			continue
+		}
+		if nm.templates == nil {
+			nm.templates = make(map[string]*template.Template)
+		}
+		nm.templates[rule] = tmpl
+	}
 
-type TelegramNotifier struct {
-	chatID string
+	return nm
 }
 
-func (t *TelegramNotifier) Send(ctx context.Context, message string) error {
-	// TODO: Implement telegram notification
-	return fmt.Errorf("telegram notification not implemented yet")
+// Send delivers the alert to every notifier routed for its rule. Rules
+// without a configured route go to all enabled notifiers. A failing notifier
+// doesn't stop the others; all failures are returned together.
+func (nm *NotificationManager) Send(ctx context.Context, alert Alert) error {
+	alert.URL = nm.rewriteLink(alert)
+	alert.msgs = nm.msgs
+	if alert.Message == "" {
+		alert.Message = nm.render(alert)
+	}
+
+	var errs []error
+	for _, notifier := range nm.route(alert.Rule) {
+		if err := nm.sendWithRetry(ctx, notifier, alert); err != nil {
+			errs = append(errs, fmt.Errorf("failed to send %s notification: %w", notifier.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
 }
 
-type SlackNotifier struct {
-	webhook string
+// rewriteLink applies the link template to the alert's product URL
+func (nm *NotificationManager) rewriteLink(alert Alert) string {
+	if nm.link == nil || alert.URL == "" {
+		return alert.URL
+	}
+
+	var b strings.Builder
+	if err := nm.link.Execute(&b, alert); err != nil {
+		nm.logger.Warn("Failed to apply link template, sending original link", "item", alert.ItemID, "error", err)
+		return alert.URL
+	}
+	return b.String()
 }
 
-func (s *SlackNotifier) Send(ctx context.Context, message string) error {
-	// TODO: Implement slack notification
-	return fmt.Errorf("slack notification not implemented yet")
+// render applies the user template for the alert's rule, or the "default"
+// one. It returns "" when neither is configured or the template fails, so
+// the built-in message is used.
+func (nm *NotificationManager) render(alert Alert) string {
+	tmpl, ok := nm.templates[alert.Rule]
+	if !ok {
+		if tmpl, ok = nm.templates["default"]; !ok {
+			return ""
+		}
+	}
+
+	var b strings.Builder
+	if err := tmpl.Execute(&b, alert); err != nil {
+		nm.logger.Warn("Failed to apply message template, sending the built-in message", "item", alert.ItemID, "rule", alert.Rule, "error", err)
+		return ""
+	}
+	return b.String()
 }
 
-type NtfyNotifier struct {
-	topic string
+// Channels returns the names of the notifiers an alert for rule goes to
+func (nm *NotificationManager) Channels(rule string) []string {
+	var names []string
+	for _, notifier := range nm.route(rule) {
+		names = append(names, notifier.Name())
+	}
+	return names
 }
 
-func (n *NtfyNotifier) Send(ctx context.Context, message string) error {
-	// TODO: Implement ntfy notification
-	return fmt.Errorf("ntfy notification not implemented yet")
-}
\ No newline at end of file
+// route returns the notifiers enabled for the given rule
+func (nm *NotificationManager) route(rule string) []Notifier {
+	channels, ok := nm.routes[rule]
+	if !ok {
+		return nm.notifiers
+	}
+
+	var selected []Notifier
+	for _, notifier := range nm.notifiers {
+		for _, channel := range channels {
+			if strings.EqualFold(channel, notifier.Name()) {
+				selected = append(selected, notifier)
+				break
+			}
+		}
+	}
+	return selected
+}
@@ -0,0 +1,37 @@
+package notifications
+
+import (
+	"testing"
+
+	"github.com/makalin/pricetrek/internal/config"
+)
+
+func TestValidateRoutes(t *testing.T) {
+	enabled := config.NotificationsConfig{
+		Email: config.EmailConfig{Enabled: true},
+		Slack: config.SlackConfig{Enabled: true},
+	}
+
+	tests := []struct {
+		name    string
+		routes  map[string][]string
+		wantErr bool
+	}{
+		{name: "no routes"},
+		{name: "enabled channels", routes: map[string][]string{RuleTarget: {"email"}, RuleDrop: {"Slack", "email"}}},
+		{name: "route muting a rule", routes: map[string][]string{RuleRise: {}}},
+		{name: "unknown rule", routes: map[string][]string{"drops": {"email"}}, wantErr: true},
+		{name: "unknown channel", routes: map[string][]string{RuleDrop: {"sms"}}, wantErr: true},
+		{name: "disabled channel", routes: map[string][]string{RuleTarget: {"telegram"}}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := enabled
+			cfg.Routes = tt.routes
+			if err := ValidateRoutes(cfg); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateRoutes error = %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+}
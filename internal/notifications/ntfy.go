@@ -3,19 +3,28 @@ package notifications
 import (
 	"context"
 	"fmt"
+	"mime"
 	"net/http"
-	"net/url"
-	"os"
 	"strings"
-	"time"
+
+	"github.com/makalin/pricetrek/internal/httpclient"
 )
 
 type NtfyNotifier struct {
 	topic string
 }
 
-func (n *NtfyNotifier) Send(ctx context.Context, message string) error {
-	ntfyURL := os.Getenv("PRICETREK_NTFY_URL")
+func (n *NtfyNotifier) Name() string {
+	return "ntfy"
+}
+
+func (n *NtfyNotifier) Send(ctx context.Context, alert Alert) error {
+	message := alert.Text()
+
+	ntfyURL, err := envSecret("PRICETREK_NTFY_URL")
+	if err != nil {
+		return err
+	}
 	if ntfyURL == "" {
 		ntfyURL = "https://ntfy.sh"
 	}
@@ -23,19 +32,18 @@ func (n *NtfyNotifier) Send(ctx context.Context, message string) error {
 	// Create URL
 	apiURL := fmt.Sprintf("%s/%s", strings.TrimSuffix(ntfyURL, "/"), n.topic)
 
-	// Create HTTP client
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+	// Service client: the global proxy settings, but never logged by
+	// --debug-http, since the URL carries the secret
+	client := httpclient.ServiceClient()
 
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, strings.NewReader(message))
 	if err != nil {
-		return fmt.Errorf("failed to create ntfy request: %w", err)
+		return permanent(fmt.Errorf("failed to create ntfy request: %w", err))
 	}
 
 	// Set headers
-	req.Header.Set("Title", "PriceTrek Alert")
+	req.Header.Set("Title", mime.BEncoding.Encode("UTF-8", alert.messages().title))
 	req.Header.Set("Priority", "default")
 	req.Header.Set("Tags", "price,alert")
 
@@ -47,7 +55,7 @@ func (n *NtfyNotifier) Send(ctx context.Context, message string) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("ntfy returned status %d", resp.StatusCode)
+		return &StatusError{Service: "ntfy", Code: resp.StatusCode}
 	}
 
 	return nil
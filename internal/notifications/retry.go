@@ -0,0 +1,117 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/config"
+)
+
+// StatusError is a non-success HTTP response from a notification service
+type StatusError struct {
+	Service string
+	Code    int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.Service, e.Code)
+}
+
+// PermanentError marks a delivery failure that retrying can't fix, such as
+// missing credentials
+type PermanentError struct {
+	Err error
+}
+
+func (e *PermanentError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *PermanentError) Unwrap() error {
+	return e.Err
+}
+
+func permanent(err error) error {
+	return &PermanentError{Err: err}
+}
+
+// isPermanent reports whether err should not be retried: configuration
+// errors, 4xx responses other than timeouts and rate limits, and 5xx SMTP
+// replies such as rejected credentials
+func isPermanent(err error) bool {
+	var permanentErr *PermanentError
+	if errors.As(err, &permanentErr) {
+		return true
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code >= 400 && statusErr.Code < 500 &&
+			statusErr.Code != http.StatusRequestTimeout &&
+			statusErr.Code != http.StatusTooManyRequests
+	}
+
+	var smtpErr *textproto.Error
+	if errors.As(err, &smtpErr) {
+		return smtpErr.Code >= 500
+	}
+
+	return false
+}
+
+// minRetryDelay keeps a zero or sub-millisecond base_delay_ms, such as a
+// bare number older builds read as nanoseconds, from retrying in a hot loop
+const minRetryDelay = 100 * time.Millisecond
+
+// retryDelay returns the wait before retry number attempt (starting at 1):
+// BaseDelay doubled per earlier retry, at least minRetryDelay and at most
+// MaxDelay when that is set
+func retryDelay(retry config.RetryConfig, attempt int) time.Duration {
+	delay := max(retry.BaseDelay, minRetryDelay)
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if retry.MaxDelay > 0 && delay >= retry.MaxDelay {
+			break
+		}
+	}
+	if retry.MaxDelay > 0 && delay > retry.MaxDelay {
+		delay = max(retry.MaxDelay, minRetryDelay)
+	}
+	return delay
+}
+
+// sendWithRetry retries transient failures with exponential backoff using
+// the defaults.retry settings
+func (nm *NotificationManager) sendWithRetry(ctx context.Context, notifier Notifier, alert Alert) error {
+	attempts := nm.retry.Attempts
+	if attempts < 1 {
+		attempts = 1
+	}
+	for attempt := 1; ; attempt++ {
+		err := notifier.Send(ctx, alert)
+		if err == nil || isPermanent(err) || attempt >= attempts {
+			return err
+		}
+		delay := retryDelay(nm.retry, attempt)
+
+		if nm.logger != nil {
+			nm.logger.Warn("Notification attempt failed, retrying",
+				"notifier", notifier.Name(),
+				"attempt", attempt,
+				"of", attempts,
+				"retry_in", delay,
+				"error", err,
+			)
+		}
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package notifications
+
+import (
+	"testing"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/config"
+)
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name    string
+		retry   config.RetryConfig
+		attempt int
+		want    time.Duration
+	}{
+		{"first retry waits base delay", config.RetryConfig{BaseDelay: 800 * time.Millisecond, MaxDelay: 7 * time.Second}, 1, 800 * time.Millisecond},
+		{"doubles per retry", config.RetryConfig{BaseDelay: 800 * time.Millisecond, MaxDelay: 7 * time.Second}, 3, 3200 * time.Millisecond},
+		{"capped at max delay", config.RetryConfig{BaseDelay: 800 * time.Millisecond, MaxDelay: 7 * time.Second}, 5, 7 * time.Second},
+		{"no cap without max delay", config.RetryConfig{BaseDelay: time.Second}, 4, 8 * time.Second},
+		{"zero base delay uses minimum", config.RetryConfig{}, 1, minRetryDelay},
+		{"nanosecond base delay uses minimum", config.RetryConfig{BaseDelay: 800}, 2, 2 * minRetryDelay},
+		{"tiny max delay uses minimum", config.RetryConfig{BaseDelay: 800, MaxDelay: 7000}, 3, minRetryDelay},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryDelay(tt.retry, tt.attempt); got != tt.want {
+				t.Errorf("retryDelay(%+v, %d) = %v, want %v", tt.retry, tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package notifications
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// secretFileSuffix marks an environment variable holding the path of a file
+// with the secret, as used by Docker and Kubernetes secrets
+const secretFileSuffix = "_FILE"
+
+// envSecret returns the value of the environment variable name. When
+// name_FILE is set, the secret is read from that file instead and takes
+// precedence; surrounding whitespace such as a trailing newline is trimmed.
+func envSecret(name string) (string, error) {
+	path := os.Getenv(name + secretFileSuffix)
+	if path == "" {
+		return os.Getenv(name), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", permanent(fmt.Errorf("failed to read %s%s: %w", name, secretFileSuffix, err))
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// notSetError reports a missing required secret
+func notSetError(name string) error {
+	return permanent(fmt.Errorf("%s (or %s%s) environment variable not set", name, name, secretFileSuffix))
+}
@@ -4,55 +4,154 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"os"
-	"time"
+	"strings"
+
+	"github.com/makalin/pricetrek/internal/httpclient"
 )
 
 type SlackNotifier struct {
 	webhook string
+	blocks  bool
 }
 
 type SlackMessage struct {
+	Text   string       `json:"text"`
+	Blocks []slackBlock `json:"blocks,omitempty"`
+}
+
+type slackText struct {
+	Type string `json:"type"`
 	Text string `json:"text"`
 }
 
-func (s *SlackNotifier) Send(ctx context.Context, message string) error {
+type slackElement struct {
+	Type string    `json:"type"`
+	Text slackText `json:"text"`
+	URL  string    `json:"url,omitempty"`
+}
+
+type slackBlock struct {
+	Type     string         `json:"type"`
+	Text     *slackText     `json:"text,omitempty"`
+	Fields   []slackText    `json:"fields,omitempty"`
+	Elements []slackElement `json:"elements,omitempty"`
+}
+
+func (s *SlackNotifier) Name() string {
+	return "slack"
+}
+
+func (s *SlackNotifier) Send(ctx context.Context, alert Alert) error {
+	message := alert.Text()
+
 	webhookURL := s.webhook
 	if webhookURL == "" {
-		webhookURL = os.Getenv("PRICETREK_SLACK_WEBHOOK")
+		var err error
+		webhookURL, err = envSecret("PRICETREK_SLACK_WEBHOOK")
+		if err != nil {
+			return err
+		}
 	}
 	if webhookURL == "" {
-		return fmt.Errorf("slack webhook URL not configured")
+		return permanent(fmt.Errorf("slack webhook URL not configured"))
 	}
 
-	// Create message
+	// Create message; text doubles as the notification fallback for blocks
 	slackMsg := SlackMessage{
-		Text: fmt.Sprintf("🔔 *PriceTrek Alert*\n%s", message),
+		Text: fmt.Sprintf("🔔 *%s*\n%s", alert.messages().title, message),
+	}
+	if s.blocks {
+		slackMsg.Blocks = slackBlocks(alert)
+	}
+
+	err := s.post(ctx, webhookURL, slackMsg)
+
+	// Webhooks that reject blocks get the plain text message instead
+	var status *StatusError
+	if slackMsg.Blocks != nil && errors.As(err, &status) && status.Code == http.StatusBadRequest {
+		slackMsg.Blocks = nil
+		err = s.post(ctx, webhookURL, slackMsg)
 	}
+	return err
+}
 
+func (s *SlackNotifier) post(ctx context.Context, webhookURL string, slackMsg SlackMessage) error {
 	// Marshal to JSON
 	jsonData, err := json.Marshal(slackMsg)
 	if err != nil {
-		return fmt.Errorf("failed to marshal slack message: %w", err)
+		return permanent(fmt.Errorf("failed to marshal slack message: %w", err))
 	}
 
-	// Create HTTP client
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	// Service client: the global proxy settings, but never logged by
+	// --debug-http, since the URL carries the secret
+	client := httpclient.ServiceClient()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", webhookURL, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return permanent(fmt.Errorf("failed to create slack request: %w", err))
 	}
+	req.Header.Set("Content-Type", "application/json")
 
 	// Send request
-	resp, err := client.Post(webhookURL, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send slack message: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("slack webhook returned status %d", resp.StatusCode)
+		return &StatusError{Service: "slack webhook", Code: resp.StatusCode}
 	}
 
 	return nil
+}
+
+// slackBlocks renders an alert as a Block Kit header, price fields and a
+// link button
+func slackBlocks(alert Alert) []slackBlock {
+	name := alert.ItemName
+	if name == "" {
+		name = alert.ItemID
+	}
+
+	msgs := alert.messages()
+	field := func(label, value string) slackText {
+		return slackText{Type: "mrkdwn", Text: fmt.Sprintf("*%s*\n%s", label, value)}
+	}
+
+	fields := []slackText{field(msgs.price, msgs.money(alert.Price, alert.Currency))}
+	if alert.PreviousPrice != 0 {
+		fields = append(fields, field(msgs.previous, msgs.money(alert.PreviousPrice, alert.Currency)))
+	}
+	if alert.ChangePercent != 0 {
+		fields = append(fields, field(msgs.change, msgs.percent(alert.ChangePercent, true)))
+	}
+	if alert.Velocity != 0 {
+		fields = append(fields, field(msgs.trend, fmt.Sprintf(msgs.trendValue, msgs.percent(alert.Velocity, true), alert.VelocityDays)))
+	}
+	if alert.TargetPrice != nil {
+		fields = append(fields, field(msgs.targetLbl, msgs.money(*alert.TargetPrice, alert.Currency)))
+	}
+
+	// Text without the trailing URL, which the button replaces
+	summary, _, _ := strings.Cut(alert.Text(), "\n")
+
+	blocks := []slackBlock{
+		{Type: "header", Text: &slackText{Type: "plain_text", Text: "🔔 " + name}},
+		{Type: "section", Text: &slackText{Type: "mrkdwn", Text: summary}, Fields: fields},
+	}
+	if alert.URL != "" {
+		blocks = append(blocks, slackBlock{
+			Type: "actions",
+			Elements: []slackElement{{
+				Type: "button",
+				Text: slackText{Type: "plain_text", Text: msgs.open},
+				URL:  alert.URL,
+			}},
+		})
+	}
+	return blocks
 }
\ No newline at end of file
@@ -0,0 +1,67 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/makalin/pricetrek/internal/httpclient"
+	"github.com/makalin/pricetrek/internal/logger"
+)
+
+func TestWebhookSecretNotInDebugLog(t *testing.T) {
+	var log bytes.Buffer
+	debug := &logger.Logger{Logger: slog.New(slog.NewTextHandler(&log, &slog.HandlerOptions{Level: slog.LevelDebug}))}
+	if err := httpclient.Configure(httpclient.Options{Logger: debug, Debug: true}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	const secret = "T000-B000-s3cr3tw3bh00k"
+
+	tests := []struct {
+		name       string
+		send       func() error
+		wantLogged bool
+	}{
+		{
+			name: "slack webhook",
+			send: func() error {
+				s := &SlackNotifier{webhook: server.URL + "/services/" + secret}
+				return s.Send(context.Background(), Alert{Rule: RuleDrop, ItemID: "a", Price: 9, Currency: "USD"})
+			},
+		},
+		{
+			// The fetch client is the one --debug-http is for
+			name: "fetch request",
+			send: func() error {
+				resp, err := httpclient.Client().Get(server.URL + "/product/" + secret)
+				if err == nil {
+					resp.Body.Close()
+				}
+				return err
+			},
+			wantLogged: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			log.Reset()
+			if err := tt.send(); err != nil {
+				t.Fatalf("send: %v", err)
+			}
+			if logged := strings.Contains(log.String(), secret); logged != tt.wantLogged {
+				t.Errorf("secret in debug log = %v, want %v; log:\n%s", logged, tt.wantLogged, log.String())
+			}
+		})
+	}
+}
@@ -2,21 +2,32 @@ package notifications
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
-	"time"
+	"strings"
+
+	"github.com/makalin/pricetrek/internal/httpclient"
 )
 
 type TelegramNotifier struct {
 	chatID string
 }
 
-func (t *TelegramNotifier) Send(ctx context.Context, message string) error {
-	token := os.Getenv("PRICETREK_TELEGRAM_TOKEN")
+func (t *TelegramNotifier) Name() string {
+	return "telegram"
+}
+
+func (t *TelegramNotifier) Send(ctx context.Context, alert Alert) error {
+	message := alert.Text()
+
+	token, err := envSecret("PRICETREK_TELEGRAM_TOKEN")
+	if err != nil {
+		return err
+	}
 	if token == "" {
-		return fmt.Errorf("PRICETREK_TELEGRAM_TOKEN environment variable not set")
+		return notSetError("PRICETREK_TELEGRAM_TOKEN")
 	}
 
 	// Create API URL
@@ -28,21 +39,55 @@ func (t *TelegramNotifier) Send(ctx context.Context, message string) error {
 	data.Set("text", message)
 	data.Set("parse_mode", "HTML")
 
-	// Create HTTP client
-	client := &http.Client{
-		Timeout: 10 * time.Second,
+	// One-tap link to the product; plain text when there is no URL
+	if alert.URL != "" {
+		markup, err := telegramURLButton(alert.messages().open, alert.URL)
+		if err != nil {
+			return permanent(fmt.Errorf("failed to marshal telegram reply markup: %w", err))
+		}
+		data.Set("reply_markup", markup)
 	}
 
+	// Service client: the global proxy settings, but never logged by
+	// --debug-http, since the URL carries the secret
+	client := httpclient.ServiceClient()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", apiURL, strings.NewReader(data.Encode()))
+	if err != nil {
+		return permanent(fmt.Errorf("failed to create telegram request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
 	// Send request
-	resp, err := client.PostForm(apiURL, data)
+	resp, err := client.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to send telegram message: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("telegram API returned status %d", resp.StatusCode)
+		return &StatusError{Service: "telegram API", Code: resp.StatusCode}
 	}
 
 	return nil
+}
+
+type telegramButton struct {
+	Text string `json:"text"`
+	URL  string `json:"url"`
+}
+
+// telegramURLButton returns an inline keyboard with a single URL button,
+// encoded for the reply_markup parameter
+func telegramURLButton(text, link string) (string, error) {
+	markup := struct {
+		InlineKeyboard [][]telegramButton `json:"inline_keyboard"`
+	}{
+		InlineKeyboard: [][]telegramButton{{{Text: text, URL: link}}},
+	}
+	data, err := json.Marshal(markup)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
 }
\ No newline at end of file
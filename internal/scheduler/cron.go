@@ -0,0 +1,198 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Cron is a parsed five-field cron expression: minute, hour, day of month,
+// month and day of week. Fields take *, numbers, ranges (1-5), lists (1,15)
+// and steps (*/15, 0-30/5); Sunday is 0 or 7.
+type Cron struct {
+	minute, hour, dom, month, dow uint64
+	// Like cron, when both day fields are restricted a day matching either
+	// one runs
+	domAny, dowAny bool
+}
+
+// cronFields are the bounds of each field, in expression order
+var cronFields = []struct {
+	name     string
+	min, max int
+}{
+	{"minute", 0, 59},
+	{"hour", 0, 23},
+	{"day of month", 1, 31},
+	{"month", 1, 12},
+	{"day of week", 0, 7},
+}
+
+// ParseCron parses a five-field cron expression
+func ParseCron(expr string) (*Cron, error) {
+	fields := strings.Fields(expr)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("cron expression %q must have 5 fields", expr)
+	}
+
+	sets := make([]uint64, len(fields))
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i].min, cronFields[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s field %q: %w", cronFields[i].name, field, err)
+		}
+		sets[i] = set
+	}
+
+	c := &Cron{
+		minute: sets[0],
+		hour:   sets[1],
+		dom:    sets[2],
+		month:  sets[3],
+		dow:    sets[4],
+		domAny: fields[2] == "*",
+		dowAny: fields[4] == "*",
+	}
+	// 7 is another name for Sunday
+	if c.dow&(1<<7) != 0 {
+		c.dow |= 1
+	}
+	return c, nil
+}
+
+func parseCronField(field string, min, max int) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			n, err := strconv.Atoi(stepPart)
+			if err != nil || n <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+			step = n
+		}
+
+		lo, hi := min, max
+		if rangePart != "*" {
+			first, last, isRange := strings.Cut(rangePart, "-")
+			var err error
+			if lo, err = strconv.Atoi(first); err != nil {
+				return 0, fmt.Errorf("invalid value %q", first)
+			}
+			hi = lo
+			if isRange {
+				if hi, err = strconv.Atoi(last); err != nil {
+					return 0, fmt.Errorf("invalid value %q", last)
+				}
+			} else if hasStep {
+				hi = max
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return 0, fmt.Errorf("%s is outside %d-%d", rangePart, min, max)
+		}
+
+		for v := lo; v <= hi; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// Next returns the first minute after t the expression matches, in t's
+// location, or the zero time when none comes within five years (e.g. for
+// February 30th)
+func (c *Cron) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if c.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !c.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if c.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	dom := c.dom&(1<<uint(t.Day())) != 0
+	dow := c.dow&(1<<uint(t.Weekday())) != 0
+	switch {
+	case c.domAny && c.dowAny:
+		return true
+	case c.domAny:
+		return dow
+	case c.dowAny:
+		return dom
+	default:
+		return dom || dow
+	}
+}
+
+// NextRun returns when an item with the given schedule that last ran at
+// last is due next. It reports false for schedules that are neither a
+// fixed interval nor a cron expression.
+func NextRun(schedule string, last time.Time) (time.Time, bool) {
+	if interval := Interval(schedule); interval > 0 {
+		return last.Add(interval), true
+	}
+	cron, err := ParseCron(schedule)
+	if err != nil {
+		return time.Time{}, false
+	}
+	next := cron.Next(last)
+	return next, !next.IsZero()
+}
+
+// Gaps returns the average and the shortest time between runs of a
+// schedule. Cron expressions are sampled over the coming year, or their
+// first 10000 runs. Both are 0 for schedules that are neither a fixed
+// interval nor a cron expression.
+func Gaps(schedule string) (average, shortest time.Duration) {
+	if interval := Interval(schedule); interval > 0 {
+		return interval, interval
+	}
+	cron, err := ParseCron(schedule)
+	if err != nil {
+		return 0, 0
+	}
+
+	start := cron.Next(time.Now())
+	if start.IsZero() {
+		return 0, 0
+	}
+	end := start.AddDate(1, 0, 0)
+	prev, runs := start, 0
+	for runs < 10000 {
+		next := cron.Next(prev)
+		if next.IsZero() || next.After(end) {
+			break
+		}
+		if gap := next.Sub(prev); shortest == 0 || gap < shortest {
+			shortest = gap
+		}
+		prev = next
+		runs++
+	}
+	if runs == 0 {
+		// Runs at most once a year
+		return 365 * 24 * time.Hour, 365 * 24 * time.Hour
+	}
+	return prev.Sub(start) / time.Duration(runs), shortest
+}
@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronNext(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2026, 3, 4, 10, 31, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2026, 3, 4, 10, 45, 0, 0, time.UTC)},
+		{"0 * * * *", time.Date(2026, 3, 4, 11, 0, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 0", time.Date(2026, 3, 8, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 7", time.Date(2026, 3, 8, 9, 0, 0, 0, time.UTC)},
+		{"30 8,20 * * *", time.Date(2026, 3, 4, 20, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 1 *", time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// Either restricted day field matches
+		{"0 0 15 * 5", time.Date(2026, 3, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			cron, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("ParseCron: %v", err)
+			}
+			if got := cron.Next(from); !got.Equal(tt.want) {
+				t.Errorf("Next(%v) = %v, want %v", from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	}
+
+	for _, expr := range tests {
+		t.Run(expr, func(t *testing.T) {
+			if _, err := ParseCron(expr); err == nil {
+				t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+			}
+		})
+	}
+}
+
+func TestNextRun(t *testing.T) {
+	last := time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		schedule string
+		want     time.Time
+		ok       bool
+	}{
+		{"hourly", last.Add(time.Hour), true},
+		{"15m", last.Add(15 * time.Minute), true},
+		{"0 9 * * *", time.Date(2026, 3, 5, 9, 0, 0, 0, time.UTC), true},
+		{"*/15 * * * *", last.Add(15 * time.Minute), true},
+		// Steps on restricted days wait for the next matching day
+		{"*/15 * * * 6", time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC), true},
+		{"0 */6 1 * *", time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC), true},
+		{"whenever", time.Time{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schedule, func(t *testing.T) {
+			got, ok := NextRun(tt.schedule, last)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("NextRun(%q) = %v, %v, want %v, %v", tt.schedule, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestGaps(t *testing.T) {
+	tests := []struct {
+		schedule     string
+		wantAverage  time.Duration
+		wantShortest time.Duration
+	}{
+		{"hourly", time.Hour, time.Hour},
+		{"*/15 * * * *", 15 * time.Minute, 15 * time.Minute},
+		{"0 9 * * *", 24 * time.Hour, 24 * time.Hour},
+		{"0 9,10 * * *", 12 * time.Hour, time.Hour},
+		{"whenever", 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.schedule, func(t *testing.T) {
+			average, shortest := Gaps(tt.schedule)
+			// DST changes in the local zone can shift daily averages slightly
+			if diff := average - tt.wantAverage; diff > time.Minute || diff < -time.Minute {
+				t.Errorf("Gaps(%q) average = %v, want %v", tt.schedule, average, tt.wantAverage)
+			}
+			if shortest != tt.wantShortest && tt.wantShortest < 24*time.Hour {
+				t.Errorf("Gaps(%q) shortest = %v, want %v", tt.schedule, shortest, tt.wantShortest)
+			}
+		})
+	}
+}
@@ -0,0 +1,52 @@
+package scheduler
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Interval returns the expected time between runs for an item schedule
+// (hourly, daily, weekly, a Go duration, or a simple "*/N" cron step on
+// every day). It returns 0 when the schedule has no fixed interval.
+func Interval(schedule string) time.Duration {
+	schedule = strings.TrimSpace(schedule)
+	switch strings.ToLower(schedule) {
+	case "hourly":
+		return time.Hour
+	case "daily":
+		return 24 * time.Hour
+	case "weekly":
+		return 7 * 24 * time.Hour
+	}
+
+	if d, err := time.ParseDuration(schedule); err == nil {
+		return d
+	}
+
+	// Cron steps in the minute or hour field, e.g. "*/15 * * * *" or "0 */6 * * *";
+	// restricted days ("*/15 * * * 1-5") leave gaps, so they have no fixed interval
+	fields := strings.Fields(schedule)
+	if len(fields) != 5 || fields[2] != "*" || fields[3] != "*" || fields[4] != "*" {
+		return 0
+	}
+	if step, ok := cronStep(fields[0]); ok && fields[1] == "*" {
+		return time.Duration(step) * time.Minute
+	}
+	if step, ok := cronStep(fields[1]); ok && !strings.Contains(fields[0], "*") {
+		return time.Duration(step) * time.Hour
+	}
+	return 0
+}
+
+func cronStep(field string) (int, bool) {
+	n, ok := strings.CutPrefix(field, "*/")
+	if !ok {
+		return 0, false
+	}
+	step, err := strconv.Atoi(n)
+	if err != nil || step <= 0 {
+		return 0, false
+	}
+	return step, true
+}
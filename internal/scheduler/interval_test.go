@@ -0,0 +1,32 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func TestInterval(t *testing.T) {
+	tests := []struct {
+		schedule string
+		want     time.Duration
+	}{
+		{"hourly", time.Hour},
+		{"Daily", 24 * time.Hour},
+		{"weekly", 7 * 24 * time.Hour},
+		{"90m", 90 * time.Minute},
+		{"*/15 * * * *", 15 * time.Minute},
+		{"0 */6 * * *", 6 * time.Hour},
+		{"*/15 * * * 1-5", 0},
+		{"0 */6 1 * *", 0},
+		{"*/10 * * 1 *", 0},
+		{"0 9 * * *", 0},
+		{"*/0 * * * *", 0},
+		{"whenever", 0},
+	}
+
+	for _, tt := range tests {
+		if got := Interval(tt.schedule); got != tt.want {
+			t.Errorf("Interval(%q) = %v, want %v", tt.schedule, got, tt.want)
+		}
+	}
+}
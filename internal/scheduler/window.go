@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Window is a daily time range such as 09:00-22:00. Windows whose end is
+// before their start wrap past midnight (22:00-06:00).
+type Window struct {
+	Start time.Duration // offset from midnight
+	End   time.Duration
+}
+
+// ParseWindow parses an "HH:MM-HH:MM" range; 24:00 is accepted as an end
+func ParseWindow(s string) (Window, error) {
+	start, end, ok := strings.Cut(strings.TrimSpace(s), "-")
+	if !ok {
+		return Window{}, fmt.Errorf("invalid time window %q: expected HH:MM-HH:MM", s)
+	}
+
+	var w Window
+	var err error
+	if w.Start, err = parseClock(start); err != nil {
+		return Window{}, fmt.Errorf("invalid time window %q: %w", s, err)
+	}
+	if w.End, err = parseClock(end); err != nil {
+		return Window{}, fmt.Errorf("invalid time window %q: %w", s, err)
+	}
+	if w.Start == w.End {
+		return Window{}, fmt.Errorf("invalid time window %q: start equals end", s)
+	}
+	return w, nil
+}
+
+// Contains reports whether t's wall-clock time falls inside the window
+func (w Window) Contains(t time.Time) bool {
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	offset := t.Sub(midnight)
+	if w.Start < w.End {
+		return offset >= w.Start && offset < w.End
+	}
+	return offset >= w.Start || offset < w.End
+}
+
+func (w Window) String() string {
+	return fmt.Sprintf("%s-%s", formatClock(w.Start), formatClock(w.End))
+}
+
+func parseClock(s string) (time.Duration, error) {
+	hours, minutes, ok := strings.Cut(strings.TrimSpace(s), ":")
+	if !ok {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	h, err := strconv.Atoi(hours)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not HH:MM", s)
+	}
+	m, err := strconv.Atoi(minutes)
+	if err != nil || m < 0 || m > 59 || h < 0 || h > 24 || (h == 24 && m != 0) {
+		return 0, fmt.Errorf("%q is not a valid time of day", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute, nil
+}
+
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,164 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Aggregate is the open/high/low/close summary of an item's samples in one
+// day or week
+type Aggregate struct {
+	ItemID   string    `json:"item_id"`
+	Currency string    `json:"currency"`
+	Period   string    `json:"period"`
+	Start    time.Time `json:"start"`
+	Open     float64   `json:"open"`
+	Close    float64   `json:"close"`
+	Min      float64   `json:"min"`
+	Max      float64   `json:"max"`
+	Avg      float64   `json:"avg"`
+	Samples  int       `json:"samples"`
+
+	openTime  time.Time
+	closeTime time.Time
+	sum       float64
+}
+
+// Aggregator folds price samples into per-period aggregates. Samples may be
+// added in any order; ones written by CompactPrices contribute their stored
+// open/min/max/avg.
+type Aggregator struct {
+	granularity string
+	index       map[string]*Aggregate
+}
+
+// NewAggregator returns an aggregator for daily or weekly periods
+func NewAggregator(granularity string) (*Aggregator, error) {
+	if granularity != GranularityDaily && granularity != GranularityWeekly {
+		return nil, fmt.Errorf("unsupported granularity %q (use daily or weekly)", granularity)
+	}
+	return &Aggregator{granularity: granularity, index: make(map[string]*Aggregate)}, nil
+}
+
+// Add folds one sample into its period
+func (a *Aggregator) Add(sample PriceSample) {
+	stats := sampleStats(sample.Price, sample.Meta)
+
+	period, start := periodOf(sample.Time, a.granularity)
+	key := sample.ItemID + "\x00" + sample.Currency + "\x00" + period
+	agg, ok := a.index[key]
+	if !ok {
+		a.index[key] = &Aggregate{
+			ItemID:    sample.ItemID,
+			Currency:  sample.Currency,
+			Period:    period,
+			Start:     start,
+			Open:      stats.open,
+			Close:     sample.Price,
+			Min:       stats.min,
+			Max:       stats.max,
+			Samples:   stats.count,
+			openTime:  sample.Time,
+			closeTime: sample.Time,
+			sum:       stats.sum,
+		}
+		return
+	}
+
+	if sample.Time.Before(agg.openTime) {
+		agg.Open, agg.openTime = stats.open, sample.Time
+	}
+	if !sample.Time.Before(agg.closeTime) {
+		agg.Close, agg.closeTime = sample.Price, sample.Time
+	}
+	agg.Min = min(agg.Min, stats.min)
+	agg.Max = max(agg.Max, stats.max)
+	agg.sum += stats.sum
+	agg.Samples += stats.count
+}
+
+// Aggregates returns the periods seen so far, ordered by item, currency and
+// then oldest period first
+func (a *Aggregator) Aggregates() []Aggregate {
+	aggregates := make([]Aggregate, 0, len(a.index))
+	for _, agg := range a.index {
+		result := *agg
+		result.Avg = agg.sum / float64(agg.Samples)
+		aggregates = append(aggregates, result)
+	}
+	sort.Slice(aggregates, func(i, j int) bool {
+		x, y := aggregates[i], aggregates[j]
+		if x.ItemID != y.ItemID {
+			return x.ItemID < y.ItemID
+		}
+		if x.Currency != y.Currency {
+			return x.Currency < y.Currency
+		}
+		return x.Start.Before(y.Start)
+	})
+	return aggregates
+}
+
+// periodOf returns the local day (2006-01-02) or ISO week (2006-W01) that t
+// falls in, and when that period starts
+func periodOf(t time.Time, granularity string) (string, time.Time) {
+	local := t.Local()
+	day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.Local)
+	if granularity != GranularityWeekly {
+		return day.Format("2006-01-02"), day
+	}
+
+	year, week := local.ISOWeek()
+	offset := (int(day.Weekday()) + 6) % 7 // days since Monday
+	return fmt.Sprintf("%d-W%02d", year, week), day.AddDate(0, 0, -offset)
+}
+
+// priceStats summarizes one stored sample
+type priceStats struct {
+	open  float64
+	min   float64
+	max   float64
+	sum   float64
+	count int
+}
+
+// sampleStats returns the stats of a sample. Samples that are already
+// aggregates carry their own open/min/max/avg, so re-aggregating them keeps
+// the true range.
+func sampleStats(price float64, meta map[string]interface{}) priceStats {
+	stats := priceStats{open: price, min: price, max: price, sum: price, count: 1}
+	if meta == nil {
+		return stats
+	}
+
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return stats
+	}
+	return compactedStats(stats, data)
+}
+
+// compactedStats applies the aggregate stored in a compacted sample's meta
+// JSON to stats
+func compactedStats(stats priceStats, metaJSON []byte) priceStats {
+	var agg struct {
+		Compacted string  `json:"compacted"`
+		Samples   int     `json:"samples"`
+		Open      float64 `json:"open"`
+		Min       float64 `json:"min"`
+		Max       float64 `json:"max"`
+		Avg       float64 `json:"avg"`
+	}
+	if json.Unmarshal(metaJSON, &agg) != nil || agg.Compacted == "" || agg.Samples <= 0 {
+		return stats
+	}
+	return priceStats{
+		open:  agg.Open,
+		min:   agg.Min,
+		max:   agg.Max,
+		sum:   agg.Avg * float64(agg.Samples),
+		count: agg.Samples,
+	}
+}
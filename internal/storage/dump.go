@@ -0,0 +1,204 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// dumpTables are the tables whose rows DumpSQL writes, in load order.
+// Items are replaced on load so a dump can be replayed over existing data.
+var dumpTables = []struct {
+	name   string
+	insert string
+}{
+	{"items", "INSERT OR REPLACE INTO"},
+	{"prices", "INSERT INTO"},
+}
+
+// DumpSQL writes the schema and the items and prices as SQL statements
+// that LoadSQL, or the sqlite3 shell, can replay
+func (s *sqliteStorage) DumpSQL(ctx context.Context, w io.Writer) error {
+	fmt.Fprintf(w, "-- PriceTrek SQL dump, %s\n", time.Now().UTC().Format(time.RFC3339))
+	fmt.Fprintln(w, "BEGIN TRANSACTION;")
+
+	// Tables before indexes so the script runs top to bottom
+	rows, err := s.db.QueryContext(ctx, `
+	SELECT sql FROM sqlite_master
+	WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+	ORDER BY CASE type WHEN 'table' THEN 0 ELSE 1 END, name
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+	var schema []string
+	for rows.Next() {
+		var stmt string
+		if err := rows.Scan(&stmt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read schema: %w", err)
+		}
+		schema = append(schema, ifNotExists(stmt))
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read schema: %w", err)
+	}
+	for _, stmt := range schema {
+		fmt.Fprintf(w, "%s;\n", stmt)
+	}
+
+	for _, table := range dumpTables {
+		if err := s.dumpRows(ctx, w, table.name, table.insert); err != nil {
+			return err
+		}
+	}
+
+	if _, err := fmt.Fprintln(w, "COMMIT;"); err != nil {
+		return fmt.Errorf("failed to write dump: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteStorage) dumpRows(ctx context.Context, w io.Writer, table, insert string) error {
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s ORDER BY rowid", table))
+	if err != nil {
+		return fmt.Errorf("failed to query %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return fmt.Errorf("failed to read %s columns: %w", table, err)
+	}
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdent(column)
+	}
+	prefix := fmt.Sprintf("%s %s (%s) VALUES (", insert, quoteIdent(table), strings.Join(quoted, ", "))
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	literals := make([]string, len(columns))
+
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return fmt.Errorf("failed to scan %s: %w", table, err)
+		}
+		for i, value := range values {
+			literals[i] = sqlLiteral(value)
+		}
+		if _, err := fmt.Fprintf(w, "%s%s);\n", prefix, strings.Join(literals, ", ")); err != nil {
+			return fmt.Errorf("failed to write dump: %w", err)
+		}
+	}
+	return rows.Err()
+}
+
+// LoadSQL replays a dump written by DumpSQL, then applies schema migrations
+// so dumps from older versions gain the current columns. Loaded price
+// samples already in the database (same item, time and price) are dropped,
+// so replaying a dump twice doesn't duplicate the history.
+func (s *sqliteStorage) LoadSQL(ctx context.Context, r io.Reader) error {
+	script, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read dump: %w", err)
+	}
+
+	// Rows past this rowid come from the dump. A fresh database has no
+	// prices table until the dump creates it.
+	var lastRowID int64
+	var tables int
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'prices'").Scan(&tables); err != nil {
+		return fmt.Errorf("failed to inspect prices: %w", err)
+	}
+	if tables > 0 {
+		if err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(rowid), 0) FROM prices").Scan(&lastRowID); err != nil {
+			return fmt.Errorf("failed to inspect prices: %w", err)
+		}
+	}
+
+	// The dump's own BEGIN/COMMIT must run on a single connection
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, string(script)); err != nil {
+		conn.ExecContext(context.Background(), "ROLLBACK")
+		return fmt.Errorf("failed to load dump: %w", err)
+	}
+
+	// Timestamps are compared as instants, since a dump may carry them in
+	// another zone offset
+	if lastRowID == 0 {
+		return s.migrate()
+	}
+	if _, err := conn.ExecContext(ctx, `
+	DELETE FROM prices
+	WHERE rowid > ? AND EXISTS (
+		SELECT 1 FROM prices p
+		WHERE p.rowid <= ? AND p.item_id = prices.item_id AND p.price = prices.price
+			AND (p.ts = prices.ts OR julianday(p.ts) = julianday(prices.ts))
+	)`, lastRowID, lastRowID); err != nil {
+		return fmt.Errorf("failed to remove duplicate prices: %w", err)
+	}
+
+	return s.migrate()
+}
+
+// ifNotExists makes a CREATE TABLE/INDEX statement from sqlite_master safe
+// to run against a database that already has the object
+func ifNotExists(stmt string) string {
+	upper := strings.ToUpper(stmt)
+	if strings.Contains(upper, "IF NOT EXISTS") {
+		return stmt
+	}
+	for _, prefix := range []string{"CREATE TABLE ", "CREATE INDEX ", "CREATE UNIQUE INDEX "} {
+		if strings.HasPrefix(upper, prefix) {
+			return stmt[:len(prefix)] + "IF NOT EXISTS " + stmt[len(prefix):]
+		}
+	}
+	return stmt
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// sqlLiteral renders a scanned value as an SQLite literal
+func sqlLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return quoteString(v.Format("2006-01-02 15:04:05.999999999-07:00"))
+	case []byte:
+		return "X'" + hex.EncodeToString(v) + "'"
+	case string:
+		return quoteString(v)
+	default:
+		return quoteString(fmt.Sprint(v))
+	}
+}
+
+func quoteString(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/makalin/pricetrek/internal/config"
+)
+
+// newTestStorage opens an initialized database in a temporary directory
+func newTestStorage(t *testing.T, name string) Storage {
+	t.Helper()
+	store, err := New(config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), name)})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	return store
+}
+
+func countPrices(t *testing.T, store Storage, itemID string) int {
+	t.Helper()
+	prices, err := store.GetPrices(context.Background(), itemID, 1000)
+	if err != nil {
+		t.Fatalf("GetPrices: %v", err)
+	}
+	return len(prices)
+}
+
+func TestLoadSQLSkipsExistingPrices(t *testing.T) {
+	ctx := context.Background()
+	source := newTestStorage(t, "source.db")
+	for _, price := range []float64{10, 11, 12} {
+		if err := source.SavePrice(ctx, "a", price, "USD", nil); err != nil {
+			t.Fatalf("SavePrice: %v", err)
+		}
+	}
+	var dump bytes.Buffer
+	if err := source.DumpSQL(ctx, &dump); err != nil {
+		t.Fatalf("DumpSQL: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		loads int
+		want  int
+	}{
+		{"single load", 1, 3},
+		{"replayed dump", 2, 3},
+		{"loaded three times", 3, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := newTestStorage(t, "target.db")
+			for i := 0; i < tt.loads; i++ {
+				if err := target.LoadSQL(ctx, bytes.NewReader(dump.Bytes())); err != nil {
+					t.Fatalf("LoadSQL: %v", err)
+				}
+			}
+			if got := countPrices(t, target, "a"); got != tt.want {
+				t.Errorf("got %d prices, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CachedRates is the last exchange rate table fetched from a source URL
+type CachedRates struct {
+	Source  string             `json:"source"`
+	Base    string             `json:"base"`
+	Rates   map[string]float64 `json:"rates"`
+	Fetched time.Time          `json:"fetched"`
+}
+
+// GetCachedRates returns the rates cached for source, or nil when there are
+// none
+func (s *sqliteStorage) GetCachedRates(ctx context.Context, source string) (*CachedRates, error) {
+	query := `SELECT base, rates, fetched FROM fx_rates WHERE source = ?`
+
+	cached := CachedRates{Source: source}
+	var ratesJSON string
+	err := s.db.QueryRowContext(ctx, query, source).Scan(&cached.Base, &ratesJSON, &cached.Fetched)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cached rates: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(ratesJSON), &cached.Rates); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal cached rates: %w", err)
+	}
+	return &cached, nil
+}
+
+// SaveCachedRates replaces the rates cached for rates.Source
+func (s *sqliteStorage) SaveCachedRates(ctx context.Context, rates CachedRates) error {
+	ratesJSON, err := json.Marshal(rates.Rates)
+	if err != nil {
+		return fmt.Errorf("failed to marshal rates: %w", err)
+	}
+
+	query := `INSERT OR REPLACE INTO fx_rates (source, base, rates, fetched) VALUES (?, ?, ?, ?)`
+	if _, err := s.db.ExecContext(ctx, query, rates.Source, rates.Base, string(ratesJSON), rates.Fetched); err != nil {
+		return fmt.Errorf("failed to save cached rates: %w", err)
+	}
+	return nil
+}
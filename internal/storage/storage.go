@@ -4,9 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"time"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -18,11 +22,119 @@ type Storage interface {
 	Close() error
 	SavePrice(ctx context.Context, itemID string, price float64, currency string, meta map[string]interface{}) error
 	GetPrices(ctx context.Context, itemID string, limit int) ([]PriceSample, error)
+	GetPricesSince(ctx context.Context, itemID string, since time.Time) ([]PriceSample, error)
+	EachPrice(ctx context.Context, itemID string, fn func(PriceSample) error) error
 	GetLatestPrice(ctx context.Context, itemID string) (*PriceSample, error)
+	GetPriceAt(ctx context.Context, itemID string, at time.Time) (*PriceSample, error)
 	GetItems(ctx context.Context) ([]Item, error)
 	SaveItem(ctx context.Context, item Item) error
 	DeleteItem(ctx context.Context, itemID string) error
 	GetItem(ctx context.Context, itemID string) (*Item, error)
+	SaveFetchStatus(ctx context.Context, status FetchStatus) error
+	GetFetchStatuses(ctx context.Context) (map[string]FetchStatus, error)
+	GetPendingLevel(ctx context.Context, itemID string) (*PendingLevel, error)
+	SavePendingLevel(ctx context.Context, level PendingLevel) error
+	DeletePendingLevel(ctx context.Context, itemID string) error
+	GetAlertStates(ctx context.Context, itemID string) (map[string]AlertState, error)
+	SaveAlertState(ctx context.Context, state AlertState) error
+	DeleteAlertState(ctx context.Context, itemID, rule string) error
+	Initialized(ctx context.Context) (bool, error)
+	CompactPrices(ctx context.Context, before time.Time, granularity string) (*CompactResult, error)
+	CleanMeta(ctx context.Context, keep []string) (*CleanMetaResult, error)
+	MergePrices(ctx context.Context, samples []PriceSample) (*MergeResult, error)
+	SaveEvent(ctx context.Context, event Event) error
+	GetEvents(ctx context.Context, itemID string, since time.Time, limit int) ([]Event, error)
+	GetCachedRates(ctx context.Context, source string) (*CachedRates, error)
+	SaveCachedRates(ctx context.Context, rates CachedRates) error
+	DumpSQL(ctx context.Context, w io.Writer) error
+	LoadSQL(ctx context.Context, r io.Reader) error
+}
+
+// Event kinds recorded in the audit log
+const (
+	EventFetchOK         = "fetch_ok"
+	EventFetchError      = "fetch_error"
+	EventFetchBlocked    = "fetch_blocked"
+	EventAlertSent       = "alert_sent"
+	EventAlertFailed     = "alert_failed"
+	EventAlertSuppressed = "alert_suppressed"
+)
+
+// Event is one entry of the tracking and alerting audit log
+type Event struct {
+	Time   time.Time `json:"time"`
+	ItemID string    `json:"item_id"`
+	Kind   string    `json:"kind"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// Compaction granularities
+const (
+	GranularityDaily  = "daily"
+	GranularityWeekly = "weekly"
+)
+
+// CompactResult summarizes a price history compaction
+type CompactResult struct {
+	Buckets  int `json:"buckets"`
+	Removed  int `json:"removed"`
+	Inserted int `json:"inserted"`
+}
+
+// CleanMetaResult summarizes a meta cleanup. Reclaimed is the drop in
+// database size after vacuuming, in bytes.
+type CleanMetaResult struct {
+	Rows      int   `json:"rows"`
+	Reclaimed int64 `json:"reclaimed_bytes"`
+}
+
+// MergeResult summarizes a history merge
+type MergeResult struct {
+	Added   int `json:"added"`
+	Skipped int `json:"skipped_duplicates"`
+}
+
+// compactionKeys are the meta keys CompactPrices writes; they hold the
+// period's statistics, so CleanMeta always keeps them on compacted rows
+var compactionKeys = []string{"compacted", "samples", "open", "min", "max", "avg"}
+
+// ErrNotInitialized is returned for databases without the PriceTrek schema
+var ErrNotInitialized = errors.New("database not initialized; run `pricetrek init`")
+
+// Fetch outcomes recorded per item
+const (
+	StatusOK      = "ok"
+	StatusError   = "error"
+	StatusBlocked = "blocked"
+)
+
+// FetchStatus is the outcome of an item's most recent fetch
+type FetchStatus struct {
+	ItemID string    `json:"item_id"`
+	Time   time.Time `json:"time"`
+	Status string    `json:"status"`
+	Detail string    `json:"detail,omitempty"`
+}
+
+// PendingLevel is a run of samples rejected by validate.max_change_pct that
+// agree with each other. Validation accepts the new level once it has been
+// seen in validate.accept_after runs in a row.
+type PendingLevel struct {
+	ItemID   string    `json:"item_id"`
+	Price    float64   `json:"price"`
+	Currency string    `json:"currency"`
+	Runs     int       `json:"runs"`
+	Since    time.Time `json:"since"`
+}
+
+// AlertState records the last delivered alert of an item's rule. While the
+// rule's condition keeps holding it doesn't alert again; the state is
+// removed once the condition clears.
+type AlertState struct {
+	ItemID string    `json:"item_id"`
+	Rule   string    `json:"rule"`
+	Price  float64   `json:"price"`
+	Time   time.Time `json:"time"`
 }
 
 type PriceSample struct {
@@ -46,6 +158,152 @@ type Item struct {
 	Regex       string   `json:"regex,omitempty"`
 	Attr        string   `json:"attr,omitempty"`
 	Command     string   `json:"command,omitempty"`
+
+	AcceptLanguage string        `json:"accept_language,omitempty"`
+	// HTTPTimeoutSec is in whole seconds, like http_timeout_sec in the config
+	HTTPTimeoutSec int           `json:"http_timeout_sec,omitempty"`
+	Notes          string        `json:"notes,omitempty"`
+	FetchKey       string        `json:"fetch_key,omitempty"`
+	// TLSInsecure and TLSCAFile override defaults.tls when either is set
+	TLSInsecure    bool          `json:"tls_insecure,omitempty"`
+	TLSCAFile      string        `json:"tls_ca_file,omitempty"`
+	ActiveHours    string        `json:"active_hours,omitempty"`
+	// Providers is the fallback chain tried instead of Provider when set
+	Providers      []string      `json:"providers,omitempty"`
+	Validate       *config.ValidateConfig `json:"validate,omitempty"`
+	ConfirmRuns    int           `json:"confirm_runs,omitempty"`
+}
+
+// ItemFromConfig converts a configured item into a storage item
+func ItemFromConfig(ic config.ItemConfig) Item {
+	item := Item{
+		ID:             ic.ID,
+		Name:           ic.Name,
+		URL:            ic.URL,
+		Provider:       ic.Provider,
+		Selector:       ic.Selector,
+		Currency:       ic.Currency,
+		TargetPrice:    ic.TargetPrice,
+		PercentDrop:    ic.PercentDrop,
+		Schedule:       ic.Schedule,
+		Regex:          ic.Regex,
+		Attr:           ic.Attr,
+		Command:        ic.Command,
+		AcceptLanguage: ic.AcceptLanguage,
+		HTTPTimeoutSec: TimeoutSeconds(ic.HTTPTimeout),
+		Notes:          ic.Notes,
+		FetchKey:       ic.FetchKey,
+		ActiveHours:    ic.ActiveHours,
+		Providers:      ic.Providers,
+		Validate:       ic.Validate,
+		ConfirmRuns:    ic.ConfirmRuns,
+	}
+	if ic.TLS != nil {
+		item.TLSInsecure = ic.TLS.InsecureSkipVerify
+		item.TLSCAFile = ic.TLS.CAFile
+	}
+	return item
+}
+
+// Config converts a storage item into the config form used by the tracker
+func (i Item) Config() config.ItemConfig {
+	ic := config.ItemConfig{
+		ID:             i.ID,
+		Name:           i.Name,
+		URL:            i.URL,
+		Provider:       i.Provider,
+		Selector:       i.Selector,
+		Currency:       i.Currency,
+		TargetPrice:    i.TargetPrice,
+		PercentDrop:    i.PercentDrop,
+		Schedule:       i.Schedule,
+		Regex:          i.Regex,
+		Attr:           i.Attr,
+		Command:        i.Command,
+		AcceptLanguage: i.AcceptLanguage,
+		HTTPTimeout:    time.Duration(i.HTTPTimeoutSec) * time.Second,
+		Notes:          i.Notes,
+		FetchKey:       i.FetchKey,
+		ActiveHours:    i.ActiveHours,
+		Providers:      i.Providers,
+		Validate:       i.Validate,
+		ConfirmRuns:    i.ConfirmRuns,
+	}
+	if i.TLSInsecure || i.TLSCAFile != "" {
+		ic.TLS = &config.TLSConfig{InsecureSkipVerify: i.TLSInsecure, CAFile: i.TLSCAFile}
+	}
+	return ic
+}
+
+// TimeoutSeconds converts a timeout to whole seconds, rounding up so a
+// sub-second timeout isn't stored as "use the default"
+func TimeoutSeconds(d time.Duration) int {
+	if d <= 0 {
+		return 0
+	}
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// itemMigrations lists columns added to the items table after the initial
+// schema. They are applied to existing databases when storage is opened.
+var itemMigrations = []struct {
+	column     string
+	definition string
+}{
+	{"accept_language", "TEXT NOT NULL DEFAULT ''"},
+	{"http_timeout_sec", "INTEGER NOT NULL DEFAULT 0"},
+	{"notes", "TEXT NOT NULL DEFAULT ''"},
+	{"fetch_key", "TEXT NOT NULL DEFAULT ''"},
+	{"tls_insecure", "INTEGER NOT NULL DEFAULT 0"},
+	{"tls_ca_file", "TEXT NOT NULL DEFAULT ''"},
+	{"active_hours", "TEXT NOT NULL DEFAULT ''"},
+	{"providers", "TEXT NOT NULL DEFAULT ''"},
+	{"validate", "TEXT NOT NULL DEFAULT ''"},
+	{"confirm_runs", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// tableMigrations creates tables introduced after the initial schema
+var tableMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS fetch_status (
+		item_id TEXT PRIMARY KEY,
+		ts DATETIME NOT NULL,
+		status TEXT NOT NULL,
+		detail TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE TABLE IF NOT EXISTS events (
+		ts DATETIME NOT NULL,
+		item_id TEXT NOT NULL,
+		kind TEXT NOT NULL,
+		detail TEXT NOT NULL DEFAULT ''
+	)`,
+	`CREATE INDEX IF NOT EXISTS idx_events_item_ts ON events(item_id, ts DESC)`,
+	`CREATE TABLE IF NOT EXISTS pending_levels (
+		item_id TEXT PRIMARY KEY,
+		price REAL NOT NULL,
+		currency TEXT NOT NULL,
+		runs INTEGER NOT NULL,
+		since DATETIME NOT NULL
+	)`,
+	`CREATE TABLE IF NOT EXISTS alert_state (
+		item_id TEXT NOT NULL,
+		rule TEXT NOT NULL,
+		price REAL NOT NULL,
+		ts DATETIME NOT NULL,
+		PRIMARY KEY (item_id, rule)
+	)`,
+	`CREATE TABLE IF NOT EXISTS fx_rates (
+		source TEXT PRIMARY KEY,
+		base TEXT NOT NULL,
+		rates TEXT NOT NULL,
+		fetched DATETIME NOT NULL
+	)`,
+}
+
+// itemColumns is the column list shared by all item queries
+const itemColumns = `id, name, url, provider, selector, currency, target_price, percent_drop, schedule, regex, attr, command, accept_language, http_timeout_sec, notes, fetch_key, tls_insecure, tls_ca_file, active_hours, providers, validate, confirm_runs`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
 }
 
 type sqliteStorage struct {
@@ -64,7 +322,65 @@ func New(cfg config.StorageConfig) (Storage, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	return &sqliteStorage{db: db}, nil
+	s := &sqliteStorage{db: db}
+	if err := s.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// migrate adds tables and columns introduced after the initial schema. Databases that
+// haven't been initialized yet are left alone; Init creates the full schema.
+func (s *sqliteStorage) migrate() error {
+	rows, err := s.db.Query(`PRAGMA table_info(items)`)
+	if err != nil {
+		return fmt.Errorf("failed to inspect items table: %w", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			colType    string
+			notNull    int
+			defaultVal sql.NullString
+			pk         int
+		)
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultVal, &pk); err != nil {
+			return fmt.Errorf("failed to inspect items table: %w", err)
+		}
+		existing[name] = true
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to inspect items table: %w", err)
+	}
+	rows.Close()
+
+	if len(existing) == 0 {
+		return nil
+	}
+
+	for _, query := range tableMigrations {
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to create table: %w", err)
+		}
+	}
+
+	for _, m := range itemMigrations {
+		if existing[m.column] {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE items ADD COLUMN %s %s", m.column, m.definition)
+		if _, err := s.db.Exec(query); err != nil {
+			return fmt.Errorf("failed to add column %s: %w", m.column, err)
+		}
+	}
+
+	return nil
 }
 
 func (s *sqliteStorage) Init() error {
@@ -111,7 +427,7 @@ func (s *sqliteStorage) Init() error {
 		return fmt.Errorf("failed to create items table: %w", err)
 	}
 
-	return nil
+	return s.migrate()
 }
 
 func (s *sqliteStorage) Close() error {
@@ -156,28 +472,92 @@ func (s *sqliteStorage) GetPrices(ctx context.Context, itemID string, limit int)
 	}
 	defer rows.Close()
 
-	var samples []PriceSample
-	for rows.Next() {
-		var sample PriceSample
-		var metaJSON sql.NullString
+	return scanPrices(rows)
+}
+
+// GetPricesSince returns an item's samples taken at or after since, newest
+// first
+func (s *sqliteStorage) GetPricesSince(ctx context.Context, itemID string, since time.Time) ([]PriceSample, error) {
+	query := `
+	SELECT item_id, ts, price, currency, meta
+	FROM prices
+	WHERE item_id = ? AND ts >= ?
+	ORDER BY ts DESC
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, itemID, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prices: %w", err)
+	}
+	defer rows.Close()
+
+	return scanPrices(rows)
+}
+
+// EachPrice calls fn for every stored sample, or only itemID's when it is
+// not empty, ordered by item and then oldest first. Rows are read one at a
+// time, so memory use doesn't grow with the history. An error from fn stops
+// the iteration and is returned.
+func (s *sqliteStorage) EachPrice(ctx context.Context, itemID string, fn func(PriceSample) error) error {
+	query := `
+	SELECT item_id, ts, price, currency, meta
+	FROM prices
+	WHERE ? = '' OR item_id = ?
+	ORDER BY item_id, ts
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, itemID, itemID)
+	if err != nil {
+		return fmt.Errorf("failed to query prices: %w", err)
+	}
+	defer rows.Close()
 
-		err := rows.Scan(&sample.ItemID, &sample.Time, &sample.Price, &sample.Currency, &metaJSON)
+	for rows.Next() {
+		sample, err := scanPrice(rows)
 		if err != nil {
-			return nil, fmt.Errorf("failed to scan price: %w", err)
+			return err
 		}
-
-		if metaJSON.Valid && metaJSON.String != "" {
-			if err := json.Unmarshal([]byte(metaJSON.String), &sample.Meta); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal meta: %w", err)
-			}
+		if err := fn(sample); err != nil {
+			return err
 		}
+	}
+	return rows.Err()
+}
 
+// scanPrices reads rows selected as item_id, ts, price, currency, meta
+func scanPrices(rows *sql.Rows) ([]PriceSample, error) {
+	var samples []PriceSample
+	for rows.Next() {
+		sample, err := scanPrice(rows)
+		if err != nil {
+			return nil, err
+		}
 		samples = append(samples, sample)
 	}
 
 	return samples, nil
 }
 
+// scanPrice reads the current row selected as item_id, ts, price, currency,
+// meta
+func scanPrice(rows *sql.Rows) (PriceSample, error) {
+	var sample PriceSample
+	var metaJSON sql.NullString
+
+	err := rows.Scan(&sample.ItemID, &sample.Time, &sample.Price, &sample.Currency, &metaJSON)
+	if err != nil {
+		return sample, fmt.Errorf("failed to scan price: %w", err)
+	}
+
+	if metaJSON.Valid && metaJSON.String != "" {
+		if err := json.Unmarshal([]byte(metaJSON.String), &sample.Meta); err != nil {
+			return sample, fmt.Errorf("failed to unmarshal meta: %w", err)
+		}
+	}
+
+	return sample, nil
+}
+
 func (s *sqliteStorage) GetLatestPrice(ctx context.Context, itemID string) (*PriceSample, error) {
 	query := `
 	SELECT item_id, ts, price, currency, meta
@@ -209,9 +589,40 @@ func (s *sqliteStorage) GetLatestPrice(ctx context.Context, itemID string) (*Pri
 	return &sample, nil
 }
 
+// GetPriceAt returns the last sample at or before at. When the history
+// doesn't reach back that far it returns the earliest sample instead.
+func (s *sqliteStorage) GetPriceAt(ctx context.Context, itemID string, at time.Time) (*PriceSample, error) {
+	for _, query := range []string{
+		`SELECT item_id, ts, price, currency, meta FROM prices WHERE item_id = ? AND ts <= ? ORDER BY ts DESC LIMIT 1`,
+		`SELECT item_id, ts, price, currency, meta FROM prices WHERE item_id = ? AND ts > ? ORDER BY ts ASC LIMIT 1`,
+	} {
+		var sample PriceSample
+		var metaJSON sql.NullString
+
+		err := s.db.QueryRowContext(ctx, query, itemID, at).Scan(
+			&sample.ItemID, &sample.Time, &sample.Price, &sample.Currency, &metaJSON,
+		)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get price: %w", err)
+		}
+
+		if metaJSON.Valid && metaJSON.String != "" {
+			if err := json.Unmarshal([]byte(metaJSON.String), &sample.Meta); err != nil {
+				return nil, fmt.Errorf("failed to unmarshal meta: %w", err)
+			}
+		}
+		return &sample, nil
+	}
+
+	return nil, nil
+}
+
 func (s *sqliteStorage) GetItems(ctx context.Context) ([]Item, error) {
 	query := `
-	SELECT id, name, url, provider, selector, currency, target_price, percent_drop, schedule, regex, attr, command
+	SELECT ` + itemColumns + `
 	FROM items
 	ORDER BY name
 	`
@@ -224,41 +635,79 @@ func (s *sqliteStorage) GetItems(ctx context.Context) ([]Item, error) {
 
 	var items []Item
 	for rows.Next() {
-		var item Item
-		var targetPrice, percentDrop sql.NullFloat64
-
-		err := rows.Scan(
-			&item.ID, &item.Name, &item.URL, &item.Provider, &item.Selector,
-			&item.Currency, &targetPrice, &percentDrop, &item.Schedule,
-			&item.Regex, &item.Attr, &item.Command,
-		)
+		item, err := scanItem(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan item: %w", err)
 		}
 
-		if targetPrice.Valid {
-			item.TargetPrice = &targetPrice.Float64
-		}
-		if percentDrop.Valid {
-			item.PercentDrop = &percentDrop.Float64
-		}
-
 		items = append(items, item)
 	}
 
 	return items, nil
 }
 
+// scanItem reads a row selected with itemColumns
+func scanItem(row rowScanner) (Item, error) {
+	var item Item
+	var targetPrice, percentDrop sql.NullFloat64
+	var providerChain, validate string
+
+	err := row.Scan(
+		&item.ID, &item.Name, &item.URL, &item.Provider, &item.Selector,
+		&item.Currency, &targetPrice, &percentDrop, &item.Schedule,
+		&item.Regex, &item.Attr, &item.Command, &item.AcceptLanguage,
+		&item.HTTPTimeoutSec, &item.Notes, &item.FetchKey, &item.TLSInsecure,
+		&item.TLSCAFile, &item.ActiveHours, &providerChain, &validate,
+		&item.ConfirmRuns,
+	)
+	if err != nil {
+		return item, err
+	}
+
+	// The fallback chain is stored comma-separated
+	if providerChain != "" {
+		item.Providers = strings.Split(providerChain, ",")
+	}
+	if validate != "" {
+		item.Validate = &config.ValidateConfig{}
+		if err := json.Unmarshal([]byte(validate), item.Validate); err != nil {
+			return item, fmt.Errorf("failed to parse validation rules of item %s: %w", item.ID, err)
+		}
+	}
+
+	if targetPrice.Valid {
+		item.TargetPrice = &targetPrice.Float64
+	}
+	if percentDrop.Valid {
+		item.PercentDrop = &percentDrop.Float64
+	}
+
+	return item, nil
+}
+
 func (s *sqliteStorage) SaveItem(ctx context.Context, item Item) error {
 	query := `
-	INSERT OR REPLACE INTO items (id, name, url, provider, selector, currency, target_price, percent_drop, schedule, regex, attr, command)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	INSERT OR REPLACE INTO items (` + itemColumns + `)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
+	// Validation rules are stored as JSON
+	validate := ""
+	if item.Validate != nil {
+		data, err := json.Marshal(item.Validate)
+		if err != nil {
+			return fmt.Errorf("failed to marshal validation rules: %w", err)
+		}
+		validate = string(data)
+	}
+
 	_, err := s.db.ExecContext(ctx, query,
 		item.ID, item.Name, item.URL, item.Provider, item.Selector,
 		item.Currency, item.TargetPrice, item.PercentDrop, item.Schedule,
-		item.Regex, item.Attr, item.Command,
+		item.Regex, item.Attr, item.Command, item.AcceptLanguage,
+		item.HTTPTimeoutSec, item.Notes, item.FetchKey, item.TLSInsecure,
+		item.TLSCAFile, item.ActiveHours, strings.Join(item.Providers, ","), validate,
+		item.ConfirmRuns,
 	)
 	if err != nil {
 		return fmt.Errorf("failed to save item: %w", err)
@@ -287,19 +736,12 @@ func (s *sqliteStorage) DeleteItem(ctx context.Context, itemID string) error {
 
 func (s *sqliteStorage) GetItem(ctx context.Context, itemID string) (*Item, error) {
 	query := `
-	SELECT id, name, url, provider, selector, currency, target_price, percent_drop, schedule, regex, attr, command
+	SELECT ` + itemColumns + `
 	FROM items
 	WHERE id = ?
 	`
 
-	var item Item
-	var targetPrice, percentDrop sql.NullFloat64
-
-	err := s.db.QueryRowContext(ctx, query, itemID).Scan(
-		&item.ID, &item.Name, &item.URL, &item.Provider, &item.Selector,
-		&item.Currency, &targetPrice, &percentDrop, &item.Schedule,
-		&item.Regex, &item.Attr, &item.Command,
-	)
+	item, err := scanItem(s.db.QueryRowContext(ctx, query, itemID))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -307,12 +749,468 @@ func (s *sqliteStorage) GetItem(ctx context.Context, itemID string) (*Item, erro
 		return nil, fmt.Errorf("failed to get item: %w", err)
 	}
 
-	if targetPrice.Valid {
-		item.TargetPrice = &targetPrice.Float64
+	return &item, nil
+}
+
+func (s *sqliteStorage) SaveFetchStatus(ctx context.Context, status FetchStatus) error {
+	query := `INSERT OR REPLACE INTO fetch_status (item_id, ts, status, detail) VALUES (?, ?, ?, ?)`
+	_, err := s.db.ExecContext(ctx, query, status.ItemID, status.Time, status.Status, status.Detail)
+	if err != nil {
+		return fmt.Errorf("failed to save fetch status: %w", err)
 	}
-	if percentDrop.Valid {
-		item.PercentDrop = &percentDrop.Float64
+	return nil
+}
+
+// GetFetchStatuses returns the latest fetch status of every item, keyed by ID
+func (s *sqliteStorage) GetFetchStatuses(ctx context.Context) (map[string]FetchStatus, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT item_id, ts, status, detail FROM fetch_status`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query fetch status: %w", err)
 	}
+	defer rows.Close()
 
-	return &item, nil
+	statuses := make(map[string]FetchStatus)
+	for rows.Next() {
+		var status FetchStatus
+		if err := rows.Scan(&status.ItemID, &status.Time, &status.Status, &status.Detail); err != nil {
+			return nil, fmt.Errorf("failed to scan fetch status: %w", err)
+		}
+		statuses[status.ItemID] = status
+	}
+
+	return statuses, rows.Err()
+}
+
+// GetPendingLevel returns the item's pending price level, or nil when there
+// is none
+func (s *sqliteStorage) GetPendingLevel(ctx context.Context, itemID string) (*PendingLevel, error) {
+	query := `SELECT item_id, price, currency, runs, since FROM pending_levels WHERE item_id = ?`
+	var level PendingLevel
+	err := s.db.QueryRowContext(ctx, query, itemID).Scan(&level.ItemID, &level.Price, &level.Currency, &level.Runs, &level.Since)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get pending level: %w", err)
+	}
+	return &level, nil
+}
+
+func (s *sqliteStorage) SavePendingLevel(ctx context.Context, level PendingLevel) error {
+	query := `INSERT OR REPLACE INTO pending_levels (item_id, price, currency, runs, since) VALUES (?, ?, ?, ?, ?)`
+	_, err := s.db.ExecContext(ctx, query, level.ItemID, level.Price, level.Currency, level.Runs, level.Since)
+	if err != nil {
+		return fmt.Errorf("failed to save pending level: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteStorage) DeletePendingLevel(ctx context.Context, itemID string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM pending_levels WHERE item_id = ?`, itemID); err != nil {
+		return fmt.Errorf("failed to delete pending level: %w", err)
+	}
+	return nil
+}
+
+// GetAlertStates returns the last delivered alert of each of an item's
+// rules whose condition still held on the last run, keyed by rule
+func (s *sqliteStorage) GetAlertStates(ctx context.Context, itemID string) (map[string]AlertState, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT item_id, rule, price, ts FROM alert_state WHERE item_id = ?`, itemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query alert state: %w", err)
+	}
+	defer rows.Close()
+
+	states := make(map[string]AlertState)
+	for rows.Next() {
+		var state AlertState
+		if err := rows.Scan(&state.ItemID, &state.Rule, &state.Price, &state.Time); err != nil {
+			return nil, fmt.Errorf("failed to scan alert state: %w", err)
+		}
+		states[state.Rule] = state
+	}
+	return states, rows.Err()
+}
+
+func (s *sqliteStorage) SaveAlertState(ctx context.Context, state AlertState) error {
+	query := `INSERT OR REPLACE INTO alert_state (item_id, rule, price, ts) VALUES (?, ?, ?, ?)`
+	if _, err := s.db.ExecContext(ctx, query, state.ItemID, state.Rule, state.Price, state.Time); err != nil {
+		return fmt.Errorf("failed to save alert state: %w", err)
+	}
+	return nil
+}
+
+func (s *sqliteStorage) DeleteAlertState(ctx context.Context, itemID, rule string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM alert_state WHERE item_id = ? AND rule = ?`, itemID, rule); err != nil {
+		return fmt.Errorf("failed to delete alert state: %w", err)
+	}
+	return nil
+}
+
+// Initialized reports whether the schema has been created by Init
+func (s *sqliteStorage) Initialized(ctx context.Context) (bool, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name IN ('items', 'prices')`
+	if err := s.db.QueryRowContext(ctx, query).Scan(&count); err != nil {
+		return false, fmt.Errorf("failed to inspect database: %w", err)
+	}
+	return count == 2, nil
+}
+
+// CompactPrices replaces the samples older than before with one sample per
+// item, currency and day or week. The aggregate keeps the closing price and
+// meta, and records open/min/max/avg and the sample count in its meta.
+func (s *sqliteStorage) CompactPrices(ctx context.Context, before time.Time, granularity string) (*CompactResult, error) {
+	if granularity != GranularityDaily && granularity != GranularityWeekly {
+		return nil, fmt.Errorf("unsupported granularity %q (use daily or weekly)", granularity)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `
+	SELECT rowid, item_id, ts, price, currency, meta
+	FROM prices
+	WHERE ts < ?
+	ORDER BY item_id, currency, ts
+	`, before)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prices: %w", err)
+	}
+
+	// Samples that are already aggregates carry their own open/min/max/avg,
+	// so compacting daily rows into weekly ones keeps the true range
+	type sample struct {
+		rowid int64
+		ts    time.Time
+		price float64
+		open  float64
+		min   float64
+		max   float64
+		sum   float64
+		count int
+		meta  map[string]interface{}
+	}
+	type bucket struct {
+		itemID   string
+		currency string
+		samples  []sample
+	}
+	var (
+		buckets []*bucket
+		index   = make(map[string]*bucket)
+	)
+	for rows.Next() {
+		var (
+			smp      sample
+			itemID   string
+			currency string
+			metaJSON sql.NullString
+		)
+		if err := rows.Scan(&smp.rowid, &itemID, &smp.ts, &smp.price, &currency, &metaJSON); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan price: %w", err)
+		}
+
+		stats := priceStats{open: smp.price, min: smp.price, max: smp.price, sum: smp.price, count: 1}
+		if metaJSON.Valid {
+			stats = compactedStats(stats, []byte(metaJSON.String))
+			json.Unmarshal([]byte(metaJSON.String), &smp.meta)
+		}
+		smp.open, smp.min, smp.max, smp.sum, smp.count = stats.open, stats.min, stats.max, stats.sum, stats.count
+
+		period, _ := periodOf(smp.ts, granularity)
+		key := itemID + "\x00" + currency + "\x00" + period
+		b, ok := index[key]
+		if !ok {
+			b = &bucket{itemID: itemID, currency: currency}
+			index[key] = b
+			buckets = append(buckets, b)
+		}
+		b.samples = append(b.samples, smp)
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("failed to query prices: %w", err)
+	}
+	rows.Close()
+
+	result := &CompactResult{}
+	for _, b := range buckets {
+		if len(b.samples) < 2 {
+			continue // already one sample in this period
+		}
+
+		open, closing := b.samples[0], b.samples[len(b.samples)-1]
+		min, max, sum, count := open.min, open.max, 0.0, 0
+		for _, smp := range b.samples {
+			if smp.min < min {
+				min = smp.min
+			}
+			if smp.max > max {
+				max = smp.max
+			}
+			sum += smp.sum
+			count += smp.count
+			if _, err := tx.ExecContext(ctx, `DELETE FROM prices WHERE rowid = ?`, smp.rowid); err != nil {
+				return nil, fmt.Errorf("failed to delete price: %w", err)
+			}
+		}
+
+		// The row stands in for the period's closing sample, so it keeps
+		// that sample's meta next to the period's statistics
+		summary := make(map[string]interface{}, len(closing.meta)+len(compactionKeys))
+		for key, value := range closing.meta {
+			summary[key] = value
+		}
+		summary["compacted"] = granularity
+		summary["samples"] = count
+		summary["open"] = open.open
+		summary["min"] = min
+		summary["max"] = max
+		summary["avg"] = sum / float64(count)
+
+		meta, err := json.Marshal(summary)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal meta: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, `
+		INSERT INTO prices (item_id, ts, price, currency, meta)
+		VALUES (?, ?, ?, ?, ?)
+		`, b.itemID, closing.ts, closing.price, b.currency, string(meta))
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert compacted price: %w", err)
+		}
+
+		result.Buckets++
+		result.Removed += len(b.samples)
+		result.Inserted++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit compaction: %w", err)
+	}
+
+	return result, nil
+}
+
+// CleanMeta rewrites the meta of every price row to keep only the given
+// keys, or none when keep is empty, then vacuums the database. Compacted
+// rows also keep their compaction summary.
+func (s *sqliteStorage) CleanMeta(ctx context.Context, keep []string) (*CleanMetaResult, error) {
+	before, err := s.size(ctx)
+	if err != nil {
+		return nil, err
+	}
+	compactedKeys := append(slices.Clone(keep), compactionKeys...)
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, `SELECT rowid, meta FROM prices WHERE meta IS NOT NULL AND meta != ''`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query prices: %w", err)
+	}
+
+	type update struct {
+		rowid int64
+		meta  sql.NullString
+	}
+	var updates []update
+	for rows.Next() {
+		var (
+			rowid    int64
+			metaJSON string
+		)
+		if err := rows.Scan(&rowid, &metaJSON); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan price: %w", err)
+		}
+
+		var meta map[string]interface{}
+		if err := json.Unmarshal([]byte(metaJSON), &meta); err != nil {
+			meta = nil // unreadable meta is dropped like any unlisted key
+		}
+		keys := keep
+		if _, compacted := meta["compacted"]; compacted {
+			keys = compactedKeys
+		}
+		kept := make(map[string]interface{})
+		for _, key := range keys {
+			if value, ok := meta[key]; ok {
+				kept[key] = value
+			}
+		}
+		if meta != nil && len(kept) == len(meta) {
+			continue
+		}
+
+		var cleaned sql.NullString
+		if len(kept) > 0 {
+			data, err := json.Marshal(kept)
+			if err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to marshal meta: %w", err)
+			}
+			cleaned = sql.NullString{String: string(data), Valid: true}
+		}
+		updates = append(updates, update{rowid: rowid, meta: cleaned})
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return nil, fmt.Errorf("failed to query prices: %w", err)
+	}
+	rows.Close()
+
+	for _, u := range updates {
+		if _, err := tx.ExecContext(ctx, `UPDATE prices SET meta = ? WHERE rowid = ?`, u.meta, u.rowid); err != nil {
+			return nil, fmt.Errorf("failed to update price: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit meta cleanup: %w", err)
+	}
+
+	// VACUUM can't run inside a transaction
+	if _, err := s.db.ExecContext(ctx, `VACUUM`); err != nil {
+		return nil, fmt.Errorf("failed to vacuum database: %w", err)
+	}
+	after, err := s.size(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CleanMetaResult{Rows: len(updates), Reclaimed: before - after}, nil
+}
+
+// MergePrices inserts samples with their own timestamps, in one transaction,
+// skipping those already stored: a sample with the same item, instant and
+// price as a stored one, or as one earlier in samples, is a duplicate
+func (s *sqliteStorage) MergePrices(ctx context.Context, samples []PriceSample) (*MergeResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Instants are compared in UTC, since two machines may store the same
+	// moment with different zone offsets
+	type sampleKey struct {
+		itemID string
+		ts     int64
+		price  float64
+	}
+	seen := make(map[sampleKey]bool)
+	loaded := make(map[string]bool)
+
+	result := &MergeResult{}
+	for _, sample := range samples {
+		if !loaded[sample.ItemID] {
+			rows, err := tx.QueryContext(ctx, `SELECT ts, price FROM prices WHERE item_id = ?`, sample.ItemID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to query prices: %w", err)
+			}
+			for rows.Next() {
+				var (
+					ts    time.Time
+					price float64
+				)
+				if err := rows.Scan(&ts, &price); err != nil {
+					rows.Close()
+					return nil, fmt.Errorf("failed to scan price: %w", err)
+				}
+				seen[sampleKey{sample.ItemID, ts.UnixNano(), price}] = true
+			}
+			if err := rows.Err(); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to query prices: %w", err)
+			}
+			rows.Close()
+			loaded[sample.ItemID] = true
+		}
+
+		key := sampleKey{sample.ItemID, sample.Time.UnixNano(), sample.Price}
+		if seen[key] {
+			result.Skipped++
+			continue
+		}
+		seen[key] = true
+
+		var metaJSON string
+		if sample.Meta != nil {
+			data, err := json.Marshal(sample.Meta)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal meta: %w", err)
+			}
+			metaJSON = string(data)
+		}
+		if _, err := tx.ExecContext(ctx, `
+		INSERT INTO prices (item_id, ts, price, currency, meta)
+		VALUES (?, ?, ?, ?, ?)
+		`, sample.ItemID, sample.Time, sample.Price, sample.Currency, metaJSON); err != nil {
+			return nil, fmt.Errorf("failed to save price: %w", err)
+		}
+		result.Added++
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit merge: %w", err)
+	}
+	return result, nil
+}
+
+// size returns the database size in bytes from its page count
+func (s *sqliteStorage) size(ctx context.Context) (int64, error) {
+	var pages, pageSize int64
+	if err := s.db.QueryRowContext(ctx, `PRAGMA page_count`).Scan(&pages); err != nil {
+		return 0, fmt.Errorf("failed to read page count: %w", err)
+	}
+	if err := s.db.QueryRowContext(ctx, `PRAGMA page_size`).Scan(&pageSize); err != nil {
+		return 0, fmt.Errorf("failed to read page size: %w", err)
+	}
+	return pages * pageSize, nil
+}
+
+func (s *sqliteStorage) SaveEvent(ctx context.Context, event Event) error {
+	query := `INSERT INTO events (ts, item_id, kind, detail) VALUES (?, ?, ?, ?)`
+	if _, err := s.db.ExecContext(ctx, query, event.Time, event.ItemID, event.Kind, event.Detail); err != nil {
+		return fmt.Errorf("failed to save event: %w", err)
+	}
+	return nil
+}
+
+// GetEvents returns events newer than since, newest first. An empty itemID
+// returns events for all items.
+func (s *sqliteStorage) GetEvents(ctx context.Context, itemID string, since time.Time, limit int) ([]Event, error) {
+	query := `
+	SELECT ts, item_id, kind, detail
+	FROM events
+	WHERE ts >= ? AND (? = '' OR item_id = ?)
+	ORDER BY ts DESC
+	LIMIT ?
+	`
+
+	rows, err := s.db.QueryContext(ctx, query, since, itemID, itemID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []Event
+	for rows.Next() {
+		var event Event
+		if err := rows.Scan(&event.Time, &event.ItemID, &event.Kind, &event.Detail); err != nil {
+			return nil, fmt.Errorf("failed to scan event: %w", err)
+		}
+		events = append(events, event)
+	}
+
+	return events, rows.Err()
 }
\ No newline at end of file
@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTimeoutSeconds(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    int
+	}{
+		{"unset", 0, 0},
+		{"whole seconds", 60 * time.Second, 60},
+		{"rounds up", 1500 * time.Millisecond, 2},
+		{"sub-second is not unset", 500 * time.Millisecond, 1},
+		{"negative", -time.Second, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TimeoutSeconds(tt.timeout); got != tt.want {
+				t.Errorf("TimeoutSeconds(%v) = %d, want %d", tt.timeout, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergePrices(t *testing.T) {
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	istanbul := time.FixedZone("TRT", 3*60*60)
+	stored := []PriceSample{
+		{ItemID: "a", Time: base, Price: 10, Currency: "USD"},
+		{ItemID: "a", Time: base.Add(time.Hour), Price: 11, Currency: "USD"},
+	}
+
+	tests := []struct {
+		name        string
+		samples     []PriceSample
+		wantAdded   int
+		wantSkipped int
+	}{
+		{
+			name:        "same sample is a duplicate",
+			samples:     []PriceSample{{ItemID: "a", Time: base, Price: 10, Currency: "USD"}},
+			wantSkipped: 1,
+		},
+		{
+			name:        "same instant in another zone is a duplicate",
+			samples:     []PriceSample{{ItemID: "a", Time: base.In(istanbul), Price: 10, Currency: "USD"}},
+			wantSkipped: 1,
+		},
+		{
+			name:      "different price at the same time is kept",
+			samples:   []PriceSample{{ItemID: "a", Time: base, Price: 12, Currency: "USD"}},
+			wantAdded: 1,
+		},
+		{
+			name:      "same price for another item is kept",
+			samples:   []PriceSample{{ItemID: "b", Time: base, Price: 10, Currency: "USD"}},
+			wantAdded: 1,
+		},
+		{
+			name: "duplicates within the batch are skipped",
+			samples: []PriceSample{
+				{ItemID: "a", Time: base.Add(2 * time.Hour), Price: 12, Currency: "USD"},
+				{ItemID: "a", Time: base.Add(2 * time.Hour), Price: 12, Currency: "USD"},
+			},
+			wantAdded:   1,
+			wantSkipped: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestStorage(t, "trek.db")
+			if _, err := store.MergePrices(ctx, stored); err != nil {
+				t.Fatalf("MergePrices: %v", err)
+			}
+
+			result, err := store.MergePrices(ctx, tt.samples)
+			if err != nil {
+				t.Fatalf("MergePrices: %v", err)
+			}
+			if result.Added != tt.wantAdded || result.Skipped != tt.wantSkipped {
+				t.Errorf("MergePrices() = %+v, want added %d, skipped %d", *result, tt.wantAdded, tt.wantSkipped)
+			}
+		})
+	}
+}
+
+func TestCleanMeta(t *testing.T) {
+	compacted := map[string]interface{}{
+		"compacted": "daily", "samples": 3.0, "open": 10.0, "min": 9.0, "max": 11.0, "avg": 10.0, "seller": "acme",
+	}
+	summary := map[string]interface{}{
+		"compacted": "daily", "samples": 3.0, "open": 10.0, "min": 9.0, "max": 11.0, "avg": 10.0,
+	}
+
+	tests := []struct {
+		name string
+		meta map[string]interface{}
+		keep []string
+		want map[string]interface{}
+	}{
+		{
+			name: "listed keys kept",
+			meta: map[string]interface{}{"seller": "acme", "in_stock": true, "raw": "x"},
+			keep: []string{"seller", "in_stock"},
+			want: map[string]interface{}{"seller": "acme", "in_stock": true},
+		},
+		{
+			name: "all removed",
+			meta: map[string]interface{}{"seller": "acme"},
+		},
+		{
+			name: "compaction key names on a fetched sample",
+			meta: map[string]interface{}{"min": 9.0, "seller": "acme"},
+			keep: []string{"seller"},
+			want: map[string]interface{}{"seller": "acme"},
+		},
+		{
+			name: "compacted row keeps its summary",
+			meta: compacted,
+			keep: []string{"seller"},
+			want: compacted,
+		},
+		{
+			name: "compacted row keeps its summary with all",
+			meta: compacted,
+			want: summary,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestStorage(t, "trek.db")
+			if err := store.SavePrice(ctx, "a", 10, "USD", tt.meta); err != nil {
+				t.Fatalf("SavePrice: %v", err)
+			}
+
+			if _, err := store.CleanMeta(ctx, tt.keep); err != nil {
+				t.Fatalf("CleanMeta: %v", err)
+			}
+
+			prices, err := store.GetPrices(ctx, "a", 1)
+			if err != nil || len(prices) != 1 {
+				t.Fatalf("GetPrices = %v, %v", prices, err)
+			}
+			if got := prices[0].Meta; len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("meta = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompactPricesKeepsClosingMeta(t *testing.T) {
+	day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name    string
+		samples []PriceSample
+		want    map[string]interface{}
+	}{
+		{
+			name: "closing meta kept",
+			samples: []PriceSample{
+				{ItemID: "a", Time: day.Add(time.Hour), Price: 10, Currency: "USD", Meta: map[string]interface{}{"seller": "first", "in_stock": true}},
+				{ItemID: "a", Time: day.Add(2 * time.Hour), Price: 8, Currency: "USD", Meta: map[string]interface{}{"seller": "last"}},
+			},
+			want: map[string]interface{}{
+				"seller": "last", "compacted": GranularityDaily, "samples": 2.0, "open": 10.0, "min": 8.0, "max": 10.0, "avg": 9.0,
+			},
+		},
+		{
+			name: "closing sample without meta",
+			samples: []PriceSample{
+				{ItemID: "a", Time: day.Add(time.Hour), Price: 10, Currency: "USD", Meta: map[string]interface{}{"seller": "first"}},
+				{ItemID: "a", Time: day.Add(2 * time.Hour), Price: 12, Currency: "USD"},
+			},
+			want: map[string]interface{}{
+				"compacted": GranularityDaily, "samples": 2.0, "open": 10.0, "min": 10.0, "max": 12.0, "avg": 11.0,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			store := newTestStorage(t, "trek.db")
+			if _, err := store.MergePrices(ctx, tt.samples); err != nil {
+				t.Fatalf("MergePrices: %v", err)
+			}
+
+			if _, err := store.CompactPrices(ctx, day.AddDate(0, 0, 1), GranularityDaily); err != nil {
+				t.Fatalf("CompactPrices: %v", err)
+			}
+
+			prices, err := store.GetPrices(ctx, "a", 10)
+			if err != nil || len(prices) != 1 {
+				t.Fatalf("GetPrices = %v, %v; want one compacted sample", prices, err)
+			}
+			if !reflect.DeepEqual(prices[0].Meta, tt.want) {
+				t.Errorf("meta = %v, want %v", prices[0].Meta, tt.want)
+			}
+		})
+	}
+}
+
+func TestItemTimeoutStoredInSeconds(t *testing.T) {
+	ctx := context.Background()
+	store := newTestStorage(t, "trek.db")
+
+	tests := []struct {
+		name    string
+		seconds int
+	}{
+		{"unset", 0},
+		{"one minute", 60},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := Item{ID: "a", Name: "A", URL: "https://example.com/a", Provider: "generic", Currency: "USD", HTTPTimeoutSec: tt.seconds}
+			if err := store.SaveItem(ctx, item); err != nil {
+				t.Fatalf("SaveItem: %v", err)
+			}
+			got, err := store.GetItem(ctx, "a")
+			if err != nil || got == nil {
+				t.Fatalf("GetItem = %v, %v", got, err)
+			}
+			if got.HTTPTimeoutSec != tt.seconds {
+				t.Errorf("HTTPTimeoutSec = %d, want %d", got.HTTPTimeoutSec, tt.seconds)
+			}
+		})
+	}
+
+	// Only the seconds column exists
+	var legacy int
+	err := store.(*sqliteStorage).db.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('items') WHERE name = 'http_timeout'`).Scan(&legacy)
+	if err != nil || legacy != 0 {
+		t.Errorf("items has an http_timeout column (%d, %v)", legacy, err)
+	}
+}
@@ -0,0 +1,169 @@
+// Package telemetry reports failed fetches to a self-hosted endpoint so a
+// team can see when a store's markup changes across many items. It is off
+// unless defaults.telemetry.enabled is set, and sends nothing anywhere else.
+package telemetry
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/buildinfo"
+	"github.com/makalin/pricetrek/internal/config"
+	"github.com/makalin/pricetrek/internal/httpclient"
+	"github.com/makalin/pricetrek/internal/logger"
+)
+
+// Failure categories
+const (
+	CategoryBlocked     = "blocked"
+	CategoryValidation  = "validation"
+	CategoryContentType = "content_type"
+	CategoryRedirect    = "redirect"
+	CategoryTimeout     = "timeout"
+	CategoryNetwork     = "network"
+	CategoryOther       = "other"
+)
+
+// Failure is one failed fetch. Only the host, provider, selector and error
+// category are sent unless include_urls or include_prices is set; error
+// messages never are, since they can quote URLs and prices.
+type Failure struct {
+	Time     time.Time `json:"time"`
+	Host     string    `json:"host"`
+	Provider string    `json:"provider"`
+	Selector string    `json:"selector,omitempty"`
+	Category string    `json:"category"`
+	URL      string    `json:"url,omitempty"`
+	// Price is the rejected price of a validation failure
+	Price    *float64 `json:"price,omitempty"`
+	Currency string   `json:"currency,omitempty"`
+}
+
+// batch is the body POSTed to the endpoint
+type batch struct {
+	Version  string    `json:"version"`
+	Failures []Failure `json:"failures"`
+}
+
+// sendTimeout bounds a report, however the run ended
+const sendTimeout = 10 * time.Second
+
+// Reporter collects failures during a run and sends them in one batch. A
+// nil Reporter, returned when telemetry is disabled, discards everything.
+type Reporter struct {
+	cfg     config.TelemetryConfig
+	logger  *logger.Logger
+	mu      sync.Mutex
+	pending []Failure
+}
+
+// New returns a Reporter for cfg, or nil when telemetry is disabled
+func New(cfg config.TelemetryConfig, log *logger.Logger) *Reporter {
+	if !cfg.Enabled || cfg.Endpoint == "" {
+		return nil
+	}
+	return &Reporter{cfg: cfg, logger: log}
+}
+
+// Validate checks that an enabled telemetry config has a usable endpoint
+func Validate(cfg config.TelemetryConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Endpoint == "" {
+		return fmt.Errorf("endpoint is required when telemetry is enabled")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("endpoint %q is not an http(s) URL", cfg.Endpoint)
+	}
+	return nil
+}
+
+// Record queues a failed fetch of item. price is only kept for validation
+// failures and only sent with include_prices.
+func (r *Reporter) Record(item config.ItemConfig, category string, price *float64) {
+	if r == nil {
+		return
+	}
+
+	failure := Failure{
+		Time:     time.Now().UTC(),
+		Provider: strings.Join(item.ProviderChain(), ","),
+		Selector: item.Selector,
+		Category: category,
+	}
+	if parsed, err := url.Parse(item.URL); err == nil {
+		failure.Host = parsed.Hostname()
+	}
+	if r.cfg.IncludeURLs {
+		failure.URL = item.URL
+	}
+	if r.cfg.IncludePrices && price != nil {
+		failure.Price = price
+		failure.Currency = item.Currency
+	}
+
+	r.mu.Lock()
+	r.pending = append(r.pending, failure)
+	r.mu.Unlock()
+}
+
+// Flush sends the queued failures, if any. The batch is dropped when the
+// endpoint can't be reached, so a down collector never backs up tracking.
+func (r *Reporter) Flush(ctx context.Context) {
+	if r == nil {
+		return
+	}
+
+	r.mu.Lock()
+	failures := r.pending
+	r.pending = nil
+	r.mu.Unlock()
+	if len(failures) == 0 {
+		return
+	}
+
+	// Report what was collected even when the run was interrupted
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), sendTimeout)
+	defer cancel()
+	if err := r.send(ctx, failures); err != nil {
+		r.logger.Warn("Failed to report fetch failures", "count", len(failures), "error", err)
+		return
+	}
+	r.logger.Debug("Reported fetch failures", "count", len(failures))
+}
+
+func (r *Reporter) send(ctx context.Context, failures []Failure) error {
+	body, err := json.Marshal(batch{Version: buildinfo.Get().Version, Failures: failures})
+	if err != nil {
+		return fmt.Errorf("failed to marshal failures: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create telemetry request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	// The service client carries the proxy and root CAs but not the fetch
+	// or debug transports; reports get their own timeout
+	client := &http.Client{Transport: httpclient.ServiceClient().Transport, Timeout: sendTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send failures: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("telemetry endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
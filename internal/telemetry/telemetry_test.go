@@ -0,0 +1,153 @@
+package telemetry
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/config"
+	"github.com/makalin/pricetrek/internal/httpclient"
+	"github.com/makalin/pricetrek/internal/logger"
+)
+
+func TestFlush(t *testing.T) {
+	// A fetch timeout far too short for any request must not reach reports
+	if err := httpclient.Configure(httpclient.Options{Timeout: time.Nanosecond, Debug: true, Logger: logger.New(false)}); err != nil {
+		t.Fatalf("Configure: %v", err)
+	}
+
+	tests := []struct {
+		name         string
+		cfg          config.TelemetryConfig
+		record       bool
+		wantFailures int
+		wantURL      string
+	}{
+		{
+			name:         "failures sent",
+			cfg:          config.TelemetryConfig{Enabled: true},
+			record:       true,
+			wantFailures: 1,
+		},
+		{
+			name:         "urls included",
+			cfg:          config.TelemetryConfig{Enabled: true, IncludeURLs: true},
+			record:       true,
+			wantFailures: 1,
+			wantURL:      "https://shop.example.com/a",
+		},
+		{
+			name: "nothing recorded",
+			cfg:  config.TelemetryConfig{Enabled: true},
+		},
+		{
+			name:   "disabled",
+			record: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []batch
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var b batch
+				if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+					t.Errorf("decode report: %v", err)
+				}
+				got = append(got, b)
+			}))
+			defer server.Close()
+
+			tt.cfg.Endpoint = server.URL
+			r := New(tt.cfg, logger.New(false))
+			if tt.record {
+				r.Record(config.ItemConfig{ID: "a", URL: "https://shop.example.com/a", Selector: ".price"}, CategoryBlocked, nil)
+			}
+			r.Flush(context.Background())
+
+			var failures []Failure
+			for _, b := range got {
+				failures = append(failures, b.Failures...)
+			}
+			if len(failures) != tt.wantFailures {
+				t.Fatalf("collector got %d failures, want %d", len(failures), tt.wantFailures)
+			}
+			for _, f := range failures {
+				if f.Host != "shop.example.com" || f.Category != CategoryBlocked || f.URL != tt.wantURL {
+					t.Errorf("failure = %+v, want host shop.example.com, category %s and URL %q", f, CategoryBlocked, tt.wantURL)
+				}
+			}
+		})
+	}
+}
+
+func TestFlushUsesProxyAndCA(t *testing.T) {
+	var reports int
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		reports++
+	})
+
+	collector := httptest.NewTLSServer(handler)
+	defer collector.Close()
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: collector.Certificate().Raw})
+	if err := os.WriteFile(caFile, certPEM, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// A plain HTTP proxy answers for any host
+	var proxied []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.Host)
+		handler(w, r)
+	}))
+	defer proxy.Close()
+
+	tests := []struct {
+		name        string
+		opts        httpclient.Options
+		endpoint    string
+		wantProxied bool
+	}{
+		{
+			name:        "through defaults.proxy",
+			opts:        httpclient.Options{Proxy: proxy.URL},
+			endpoint:    "http://collector.example/report",
+			wantProxied: true,
+		},
+		{
+			name:     "collector signed by tls.ca_file",
+			opts:     httpclient.Options{TLS: httpclient.TLSOptions{CAFile: caFile}},
+			endpoint: collector.URL,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, proxied = 0, nil
+			tt.opts.Timeout = time.Nanosecond
+			tt.opts.Debug = true
+			tt.opts.Logger = logger.New(false)
+			if err := httpclient.Configure(tt.opts); err != nil {
+				t.Fatalf("Configure: %v", err)
+			}
+
+			r := New(config.TelemetryConfig{Enabled: true, Endpoint: tt.endpoint}, logger.New(false))
+			r.Record(config.ItemConfig{ID: "a", URL: "https://shop.example.com/a"}, CategoryBlocked, nil)
+			r.Flush(context.Background())
+
+			if reports != 1 {
+				t.Errorf("collector got %d reports, want 1", reports)
+			}
+			if (len(proxied) > 0) != tt.wantProxied {
+				t.Errorf("proxied requests = %v, want proxied %v", proxied, tt.wantProxied)
+			}
+		})
+	}
+}
@@ -3,10 +3,16 @@ package tools
 import (
 	"archive/tar"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -22,103 +28,556 @@ func NewBackupManager(backupDir string) *BackupManager {
 	}
 }
 
-// CreateBackup creates a compressed backup of the PriceTrek data
+// backupInfoName is the first entry of every archive, describing the backup
+const backupInfoName = ".pricetrek-backup.json"
+
+// manifestName records the files seen by the latest backup, in the backup
+// directory
+const manifestName = "manifest.json"
+
+// Backup types
+const (
+	BackupFull        = "full"
+	BackupIncremental = "incremental"
+)
+
+// backupInfo describes an archive. Files lists every file in the data
+// directory at backup time, so a restore can drop files deleted since an
+// earlier archive in the chain.
+type backupInfo struct {
+	Type  string   `json:"type"`
+	Base  string   `json:"base,omitempty"`
+	Files []string `json:"files"`
+}
+
+// manifestFile is what a backup remembers about one file
+type manifestFile struct {
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"mod_time"`
+	SHA256  string    `json:"sha256"`
+}
+
+// backupManifest is the state an incremental backup compares against
+type backupManifest struct {
+	Last  string                  `json:"last"`
+	Files map[string]manifestFile `json:"files"`
+}
+
+// CreateBackup creates a compressed full backup of the PriceTrek data
 func (bm *BackupManager) CreateBackup(dataDir string) (string, error) {
+	return bm.createBackup(dataDir, false)
+}
+
+// CreateIncrementalBackup creates a compressed backup of the files changed
+// since the previous backup in the backup directory. A file counts as
+// changed when its size or modification time differs and its content hash
+// does too. Without a previous backup a full one is made instead.
+func (bm *BackupManager) CreateIncrementalBackup(dataDir string) (string, error) {
+	return bm.createBackup(dataDir, true)
+}
+
+func (bm *BackupManager) createBackup(dataDir string, incremental bool) (string, error) {
 	// Ensure backup directory exists
 	if err := os.MkdirAll(bm.backupDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create backup directory: %w", err)
 	}
 
-	// Generate backup filename
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	backupFile := filepath.Join(bm.backupDir, fmt.Sprintf("pricetrek_backup_%s.tar.gz", timestamp))
+	files, err := bm.scanDataDir(dataDir)
+	if err != nil {
+		return "", fmt.Errorf("failed to create backup: %w", err)
+	}
 
-	// Create backup file
-	file, err := os.Create(backupFile)
+	// An incremental backup needs the archive it builds on
+	previous, err := bm.loadManifest()
 	if err != nil {
-		return "", fmt.Errorf("failed to create backup file: %w", err)
+		return "", err
+	}
+	info := backupInfo{Type: BackupFull}
+	if incremental && previous != nil {
+		if _, err := os.Stat(filepath.Join(bm.backupDir, previous.Last)); err == nil {
+			info = backupInfo{Type: BackupIncremental, Base: previous.Last}
+		}
 	}
-	defer file.Close()
 
-	// Create gzip writer
-	gzWriter := gzip.NewWriter(file)
-	defer gzWriter.Close()
+	// Hash files, reusing the previous hash when size and mtime match
+	var changed []string
+	for _, name := range sortedKeys(files) {
+		file := files[name]
+		info.Files = append(info.Files, name)
 
-	// Create tar writer
-	tarWriter := tar.NewWriter(gzWriter)
-	defer tarWriter.Close()
+		var old manifestFile
+		var seen bool
+		if previous != nil {
+			old, seen = previous.Files[name]
+		}
+		if seen && old.Size == file.Size && old.ModTime.Equal(file.ModTime) {
+			file.SHA256 = old.SHA256
+		} else {
+			sum, err := hashFile(filepath.Join(dataDir, name))
+			if err != nil {
+				return "", fmt.Errorf("failed to hash %s: %w", name, err)
+			}
+			file.SHA256 = sum
+		}
+		files[name] = file
+
+		if info.Type == BackupFull || !seen || old.SHA256 != file.SHA256 {
+			changed = append(changed, name)
+		}
+	}
 
-	// Walk through data directory and add files to archive
+	file, err := bm.newArchiveFile(info.Type)
+	if err != nil {
+		return "", err
+	}
+	backupFile := file.Name()
+	name := filepath.Base(backupFile)
+
+	if err := writeArchive(file, dataDir, info, changed); err != nil {
+		os.Remove(backupFile)
+		return "", fmt.Errorf("failed to create backup: %w", err)
+	}
+
+	if err := bm.saveManifest(&backupManifest{Last: name, Files: files}); err != nil {
+		return "", err
+	}
+
+	return backupFile, nil
+}
+
+// scanDataDir returns the files under dataDir by relative path, skipping
+// the backup directory, which may live inside it
+func (bm *BackupManager) scanDataDir(dataDir string) (map[string]manifestFile, error) {
+	backupDir, err := filepath.Abs(bm.backupDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve backup directory: %w", err)
+	}
+
+	files := make(map[string]manifestFile)
 	err = filepath.Walk(dataDir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
-		// Skip directories
+		// Skip directories, and earlier backups
 		if info.IsDir() {
+			if abs, err := filepath.Abs(path); err == nil && abs == backupDir {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 
-		// Create tar header
-		header, err := tar.FileInfoHeader(info, "")
-		if err != nil {
-			return err
-		}
-
-		// Update header name to be relative to data directory
 		relPath, err := filepath.Rel(dataDir, path)
 		if err != nil {
 			return err
 		}
-		header.Name = relPath
+		files[filepath.ToSlash(relPath)] = manifestFile{Size: info.Size(), ModTime: info.ModTime()}
+		return nil
+	})
+	return files, err
+}
 
-		// Write header
-		if err := tarWriter.WriteHeader(header); err != nil {
-			return err
+// backupTimeLayout is the timestamp in backup names
+const backupTimeLayout = "2006-01-02_15-04-05.000"
+
+// newArchiveFile creates a backup file named after the current time. The
+// file is created exclusively, moving on to a later timestamp when a backup
+// already has the name, so quick successive backups never overwrite each
+// other.
+func (bm *BackupManager) newArchiveFile(backupType string) (*os.File, error) {
+	for attempt := 0; ; attempt++ {
+		timestamp := time.Now().Format(backupTimeLayout)
+		name := fmt.Sprintf("pricetrek_backup_%s.tar.gz", timestamp)
+		if backupType == BackupIncremental {
+			name = fmt.Sprintf("pricetrek_backup_%s_incr.tar.gz", timestamp)
 		}
 
-		// Open file and copy content
-		file, err := os.Open(path)
+		file, err := os.OpenFile(filepath.Join(bm.backupDir, name), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if os.IsExist(err) && attempt < 100 {
+			time.Sleep(time.Millisecond)
+			continue
+		}
 		if err != nil {
+			return nil, fmt.Errorf("failed to create backup file: %w", err)
+		}
+		return file, nil
+	}
+}
+
+// writeArchive writes info followed by the named files of dataDir to file
+// as a tar.gz, closing it
+func writeArchive(file *os.File, dataDir string, info backupInfo, names []string) error {
+	defer file.Close()
+
+	// Create gzip writer
+	gzWriter := gzip.NewWriter(file)
+
+	// Create tar writer
+	tarWriter := tar.NewWriter(gzWriter)
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to encode backup info: %w", err)
+	}
+	header := &tar.Header{
+		Name:    backupInfoName,
+		Mode:    0644,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+	if _, err := tarWriter.Write(data); err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		if err := addFile(tarWriter, dataDir, name); err != nil {
 			return err
 		}
-		defer file.Close()
+	}
 
-		_, err = io.Copy(tarWriter, file)
+	if err := tarWriter.Close(); err != nil {
 		return err
-	})
+	}
+	if err := gzWriter.Close(); err != nil {
+		return err
+	}
+	return file.Close()
+}
 
+// addFile copies one file of dataDir into the archive
+func addFile(tarWriter *tar.Writer, dataDir, name string) error {
+	path := filepath.Join(dataDir, filepath.FromSlash(name))
+	file, err := os.Open(path)
 	if err != nil {
-		return "", fmt.Errorf("failed to create backup: %w", err)
+		return err
 	}
+	defer file.Close()
 
-	return backupFile, nil
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	// Create tar header
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+
+	// Write header
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(tarWriter, file)
+	return err
 }
 
-// RestoreBackup restores a backup to the specified directory
-func (bm *BackupManager) RestoreBackup(backupFile, targetDir string) error {
-	// Open backup file
-	file, err := os.Open(backupFile)
+// hashFile returns the hex SHA-256 of the file at path
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
 	if err != nil {
-		return fmt.Errorf("failed to open backup file: %w", err)
+		return "", err
 	}
 	defer file.Close()
 
-	// Create gzip reader
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// loadManifest reads the manifest of the latest backup; nil when there is none
+func (bm *BackupManager) loadManifest() (*backupManifest, error) {
+	data, err := os.ReadFile(filepath.Join(bm.backupDir, manifestName))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup manifest: %w", err)
+	}
+
+	var manifest backupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse backup manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// saveManifest replaces the manifest atomically
+func (bm *BackupManager) saveManifest(manifest *backupManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup manifest: %w", err)
+	}
+
+	path := filepath.Join(bm.backupDir, manifestName)
+	if err := os.WriteFile(path+".tmp", data, 0644); err != nil {
+		return fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		return fmt.Errorf("failed to write backup manifest: %w", err)
+	}
+	return nil
+}
+
+// openArchive returns a tar reader over the backup; call close when done
+func openArchive(backupFile string) (*tar.Reader, func(), error) {
+	file, err := os.Open(backupFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open backup file: %w", err)
+	}
+
 	gzReader, err := gzip.NewReader(file)
 	if err != nil {
-		return fmt.Errorf("failed to create gzip reader: %w", err)
+		file.Close()
+		return nil, nil, fmt.Errorf("failed to create gzip reader: %w", err)
+	}
+
+	return tar.NewReader(gzReader), func() {
+		gzReader.Close()
+		file.Close()
+	}, nil
+}
+
+// readBackupInfo returns the info stored in the backup. Archives made before
+// incremental backups have none and are full backups of unknown files.
+func readBackupInfo(backupFile string) (*backupInfo, error) {
+	tarReader, closeArchive, err := openArchive(backupFile)
+	if err != nil {
+		return nil, err
+	}
+	defer closeArchive()
+
+	header, err := tarReader.Next()
+	if err == io.EOF {
+		return &backupInfo{Type: BackupFull}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tar header: %w", err)
+	}
+	if header.Name != backupInfoName {
+		return &backupInfo{Type: BackupFull}, nil
+	}
+
+	var info backupInfo
+	if err := json.NewDecoder(tarReader).Decode(&info); err != nil {
+		return nil, fmt.Errorf("failed to read backup info: %w", err)
+	}
+	return &info, nil
+}
+
+// BackupChain returns the archives needed to restore backupFile, oldest
+// first: the full backup it builds on, then each incremental up to and
+// including backupFile. Base archives are looked up next to backupFile.
+func (bm *BackupManager) BackupChain(backupFile string) ([]string, error) {
+	chain, _, err := backupChain(backupFile)
+	return chain, err
+}
+
+// backupChain is BackupChain, also returning the info of backupFile
+func backupChain(backupFile string) ([]string, *backupInfo, error) {
+	var last *backupInfo
+	chain := []string{backupFile}
+	current := backupFile
+	for {
+		info, err := readBackupInfo(current)
+		if err != nil {
+			return nil, nil, err
+		}
+		if last == nil {
+			last = info
+		}
+		if info.Type != BackupIncremental {
+			break
+		}
+
+		if info.Base == "" || !filepath.IsLocal(info.Base) {
+			return nil, nil, fmt.Errorf("backup %s has an invalid base %q", current, info.Base)
+		}
+		base := filepath.Join(filepath.Dir(current), info.Base)
+		if slices.Contains(chain, base) {
+			return nil, nil, fmt.Errorf("backup %s builds on itself through %s", current, info.Base)
+		}
+		current = base
+		if _, err := os.Stat(current); err != nil {
+			return nil, nil, fmt.Errorf("backup %s needs missing base backup %s", backupFile, info.Base)
+		}
+		if len(chain) > 10000 {
+			return nil, nil, fmt.Errorf("backup chain of %s is too long", backupFile)
+		}
+		chain = append([]string{current}, chain...)
+	}
+	return chain, last, nil
+}
+
+// BackupEntry is a file stored in a backup
+type BackupEntry struct {
+	Name string
+	Size int64
+}
+
+// BackupContents lists the files a restore of the backup writes without
+// extracting them, including those from the backups an incremental builds on
+func (bm *BackupManager) BackupContents(backupFile string) ([]BackupEntry, error) {
+	chain, info, err := backupChain(backupFile)
+	if err != nil {
+		return nil, err
 	}
-	defer gzReader.Close()
 
-	// Create tar reader
-	tarReader := tar.NewReader(gzReader)
+	entries := make(map[string]BackupEntry)
+	for _, archive := range chain {
+		tarReader, closeArchive, err := openArchive(archive)
+		if err != nil {
+			return nil, err
+		}
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				closeArchive()
+				return nil, fmt.Errorf("failed to read tar header: %w", err)
+			}
+			if header.Name == backupInfoName {
+				continue
+			}
+			entries[header.Name] = BackupEntry{Name: header.Name, Size: header.Size}
+		}
+		closeArchive()
+	}
+
+	var contents []BackupEntry
+	for _, name := range sortedKeys(entries) {
+		if info.Files != nil && !slices.Contains(info.Files, name) {
+			continue
+		}
+		contents = append(contents, entries[name])
+	}
+	return contents, nil
+}
+
+// RestoreBackup restores a backup to the specified directory. An incremental
+// backup is restored by extracting its full backup and every incremental up
+// to it in order.
+func (bm *BackupManager) RestoreBackup(backupFile, targetDir string) error {
+	chain, info, err := backupChain(backupFile)
+	if err != nil {
+		return err
+	}
 
 	// Ensure target directory exists
 	if err := os.MkdirAll(targetDir, 0755); err != nil {
 		return fmt.Errorf("failed to create target directory: %w", err)
 	}
 
+	// SQLite would replay the replaced database's journal into the restored
+	// one, so drop it first
+	entries, err := bm.BackupContents(backupFile)
+	if err != nil {
+		return err
+	}
+	// Refuse entries escaping the target before anything is removed
+	for _, entry := range entries {
+		if _, err := entryPath(targetDir, entry.Name); err != nil {
+			return err
+		}
+	}
+	for _, name := range StaleJournals(entries) {
+		path, err := entryPath(targetDir, name)
+		if err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("failed to remove stale journal: %w", err)
+		}
+	}
+
+	extracted := make(map[string]bool)
+	for _, archive := range chain {
+		if err := extractArchive(archive, targetDir, extracted); err != nil {
+			return err
+		}
+	}
+
+	// Drop files an earlier archive restored that were deleted since
+	if info.Files != nil {
+		for name := range extracted {
+			if slices.Contains(info.Files, name) {
+				continue
+			}
+			if err := os.Remove(filepath.Join(targetDir, filepath.FromSlash(name))); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove deleted file: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// journalSuffixes name the files SQLite keeps next to a database: the
+// write-ahead log, its shared-memory index and the rollback journal
+var journalSuffixes = []string{"-wal", "-shm", "-journal"}
+
+// StaleJournals returns the SQLite journal files a restore of entries must
+// remove from the target: those of each restored file that the backup
+// doesn't restore itself. Left in place they belong to the database being
+// replaced.
+func StaleJournals(entries []BackupEntry) []string {
+	restored := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		restored[entry.Name] = true
+	}
+
+	var stale []string
+	for _, entry := range entries {
+		if isJournal(entry.Name) {
+			continue
+		}
+		for _, suffix := range journalSuffixes {
+			if name := entry.Name + suffix; !restored[name] {
+				stale = append(stale, name)
+			}
+		}
+	}
+	return stale
+}
+
+// isJournal reports whether name is a SQLite journal file
+func isJournal(name string) bool {
+	for _, suffix := range journalSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// entryPath returns where a backup entry is restored under targetDir,
+// refusing names that escape it
+func entryPath(targetDir, name string) (string, error) {
+	name = filepath.FromSlash(name)
+	if !filepath.IsLocal(name) {
+		return "", fmt.Errorf("backup entry %q is outside the target directory", name)
+	}
+	return filepath.Join(targetDir, name), nil
+}
+
+// extractArchive writes the files of one archive into targetDir, recording
+// their names in extracted
+func extractArchive(backupFile, targetDir string, extracted map[string]bool) error {
+	tarReader, closeArchive, err := openArchive(backupFile)
+	if err != nil {
+		return err
+	}
+	defer closeArchive()
+
 	// Extract files
 	for {
 		header, err := tarReader.Next()
@@ -128,9 +587,14 @@ func (bm *BackupManager) RestoreBackup(backupFile, targetDir string) error {
 		if err != nil {
 			return fmt.Errorf("failed to read tar header: %w", err)
 		}
+		if header.Name == backupInfoName {
+			continue
+		}
 
-		// Create target file path
-		targetPath := filepath.Join(targetDir, header.Name)
+		targetPath, err := entryPath(targetDir, header.Name)
+		if err != nil {
+			return err
+		}
 
 		// Create directory if needed
 		if err := os.MkdirAll(filepath.Dir(targetPath), 0755); err != nil {
@@ -155,11 +619,22 @@ func (bm *BackupManager) RestoreBackup(backupFile, targetDir string) error {
 		if err := os.Chmod(targetPath, os.FileMode(header.Mode)); err != nil {
 			return fmt.Errorf("failed to set file permissions: %w", err)
 		}
+		extracted[header.Name] = true
 	}
 
 	return nil
 }
 
+// sortedKeys returns the keys of m in order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // ListBackups returns a list of available backups
 func (bm *BackupManager) ListBackups() ([]string, error) {
 	files, err := filepath.Glob(filepath.Join(bm.backupDir, "pricetrek_backup_*.tar.gz"))
@@ -170,6 +645,83 @@ func (bm *BackupManager) ListBackups() ([]string, error) {
 	return files, nil
 }
 
+// LatestBackup returns the newest backup, by the timestamp in its name or
+// else its modification time
+func (bm *BackupManager) LatestBackup() (string, error) {
+	files, err := bm.ListBackups()
+	if err != nil {
+		return "", err
+	}
+
+	var latest string
+	var latestTime time.Time
+	for _, file := range files {
+		created, ok := backupTime(file)
+		if !ok {
+			continue
+		}
+		if latest == "" || created.After(latestTime) {
+			latest, latestTime = file, created
+		}
+	}
+	if latest == "" {
+		return "", fmt.Errorf("no backups found in %s", bm.backupDir)
+	}
+	return latest, nil
+}
+
+// backupTime returns when a backup was made
+func backupTime(file string) (time.Time, bool) {
+	name := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "pricetrek_backup_"), ".tar.gz")
+	name = strings.TrimSuffix(name, "_incr")
+	// Parsing accepts the fraction without the layout asking for it, which
+	// also reads names from before millisecond resolution
+	if created, err := time.ParseInLocation("2006-01-02_15-04-05", name, time.Local); err == nil {
+		return created, true
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return info.ModTime(), true
+}
+
+// VerifyBackup reads every archive needed to restore the backup to the end,
+// so truncated or corrupted archives fail their gzip checksum before
+// anything is restored
+func (bm *BackupManager) VerifyBackup(backupFile string) error {
+	chain, err := bm.BackupChain(backupFile)
+	if err != nil {
+		return err
+	}
+
+	for _, archive := range chain {
+		tarReader, closeArchive, err := openArchive(archive)
+		if err != nil {
+			return err
+		}
+		for {
+			_, err = tarReader.Next()
+			if err == io.EOF {
+				err = nil
+				break
+			}
+			if err != nil {
+				break
+			}
+			if _, err = io.Copy(io.Discard, tarReader); err != nil {
+				break
+			}
+		}
+		closeArchive()
+		if err != nil {
+			return fmt.Errorf("backup %s is damaged: %w", filepath.Base(archive), err)
+		}
+	}
+	return nil
+}
+
 // CleanOldBackups removes backups older than the specified duration
 func (bm *BackupManager) CleanOldBackups(olderThan time.Duration) error {
 	files, err := bm.ListBackups()
@@ -180,13 +732,27 @@ func (bm *BackupManager) CleanOldBackups(olderThan time.Duration) error {
 	cutoff := time.Now().Add(-olderThan)
 	var removed int
 
+	// Keep old backups that newer incrementals still build on
+	var old []string
+	needed := make(map[string]bool)
 	for _, file := range files {
 		info, err := os.Stat(file)
 		if err != nil {
 			continue
 		}
-
 		if info.ModTime().Before(cutoff) {
+			old = append(old, file)
+			continue
+		}
+		if chain, err := bm.BackupChain(file); err == nil {
+			for _, base := range chain {
+				needed[base] = true
+			}
+		}
+	}
+
+	for _, file := range old {
+		if !needed[file] {
 			if err := os.Remove(file); err != nil {
 				continue
 			}
@@ -0,0 +1,223 @@
+package tools
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestStaleJournals(t *testing.T) {
+	tests := []struct {
+		name    string
+		entries []string
+		want    []string
+	}{
+		{
+			name:    "database alone",
+			entries: []string{"trek.db"},
+			want:    []string{"trek.db-wal", "trek.db-shm", "trek.db-journal"},
+		},
+		{
+			name:    "journal restored with the database",
+			entries: []string{"trek.db", "trek.db-shm", "trek.db-wal"},
+			want:    []string{"trek.db-journal"},
+		},
+		{
+			name:    "nested file",
+			entries: []string{"cache/pages.db"},
+			want:    []string{"cache/pages.db-wal", "cache/pages.db-shm", "cache/pages.db-journal"},
+		},
+		{
+			name: "empty backup",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var entries []BackupEntry
+			for _, name := range tt.entries {
+				entries = append(entries, BackupEntry{Name: name})
+			}
+			if got := StaleJournals(entries); !slices.Equal(got, tt.want) {
+				t.Errorf("StaleJournals(%v) = %v, want %v", tt.entries, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRestoreBackupRemovesStaleJournals(t *testing.T) {
+	dataDir := t.TempDir()
+	writeFile(t, filepath.Join(dataDir, "trek.db"), "backed up")
+
+	bm := NewBackupManager(t.TempDir())
+	backupFile, err := bm.CreateBackup(dataDir)
+	if err != nil {
+		t.Fatalf("CreateBackup: %v", err)
+	}
+
+	// The database changed since, and its write-ahead log is still around
+	writeFile(t, filepath.Join(dataDir, "trek.db"), "newer")
+	writeFile(t, filepath.Join(dataDir, "trek.db-wal"), "newer log")
+	writeFile(t, filepath.Join(dataDir, "trek.db-shm"), "newer index")
+
+	if err := bm.RestoreBackup(backupFile, dataDir); err != nil {
+		t.Fatalf("RestoreBackup: %v", err)
+	}
+
+	if data, err := os.ReadFile(filepath.Join(dataDir, "trek.db")); err != nil || string(data) != "backed up" {
+		t.Errorf("trek.db = %q, %v; want the backed up content", data, err)
+	}
+	for _, name := range []string{"trek.db-wal", "trek.db-shm"} {
+		if _, err := os.Stat(filepath.Join(dataDir, name)); !os.IsNotExist(err) {
+			t.Errorf("%s still exists after restore (err %v)", name, err)
+		}
+	}
+}
+
+func TestBackupChain(t *testing.T) {
+	dataDir := t.TempDir()
+	bm := NewBackupManager(t.TempDir())
+
+	// Backups in quick succession each get their own archive
+	var backups []string
+	for i, content := range []string{"one", "two", "three"} {
+		writeFile(t, filepath.Join(dataDir, "trek.db"), content)
+		create := bm.CreateIncrementalBackup
+		if i == 0 {
+			create = bm.CreateBackup
+		}
+		backupFile, err := create(dataDir)
+		if err != nil {
+			t.Fatalf("backup %d: %v", i, err)
+		}
+		if slices.Contains(backups, backupFile) {
+			t.Fatalf("backup %d reused the name %s", i, backupFile)
+		}
+		backups = append(backups, backupFile)
+	}
+
+	tests := []struct {
+		name   string
+		backup string
+		want   []string
+	}{
+		{name: "full backup", backup: backups[0], want: backups[:1]},
+		{name: "first incremental", backup: backups[1], want: backups[:2]},
+		{name: "second incremental", backup: backups[2], want: backups},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chain, err := bm.BackupChain(tt.backup)
+			if err != nil {
+				t.Fatalf("BackupChain: %v", err)
+			}
+			if !slices.Equal(chain, tt.want) {
+				t.Errorf("BackupChain = %v, want %v", chain, tt.want)
+			}
+		})
+	}
+
+	if latest, err := bm.LatestBackup(); err != nil || latest != backups[2] {
+		t.Errorf("LatestBackup = %q, %v; want %q", latest, err, backups[2])
+	}
+}
+
+func TestBackupChainRejectsLoops(t *testing.T) {
+	backupDir := t.TempDir()
+	archive := func(name, base string) string {
+		file, err := os.Create(filepath.Join(backupDir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writeArchive(file, t.TempDir(), backupInfo{Type: BackupIncremental, Base: base}, nil); err != nil {
+			t.Fatal(err)
+		}
+		return file.Name()
+	}
+
+	tests := []struct {
+		name   string
+		backup string
+	}{
+		{name: "based on itself", backup: archive("self_incr.tar.gz", "self_incr.tar.gz")},
+		{name: "based on each other", backup: archive("a_incr.tar.gz", "b_incr.tar.gz")},
+	}
+	archive("b_incr.tar.gz", "a_incr.tar.gz")
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if chain, err := NewBackupManager(backupDir).BackupChain(tt.backup); err == nil {
+				t.Errorf("BackupChain = %v, want an error", chain)
+			}
+		})
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestoreBackupRejectsEscapingEntries(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry func(outside string) string
+	}{
+		{name: "parent directory", entry: func(string) string { return "../outside/trek.db" }},
+		{name: "nested parent", entry: func(string) string { return "data/../../outside/trek.db" }},
+		{name: "absolute path", entry: func(outside string) string { return filepath.ToSlash(filepath.Join(outside, "trek.db")) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			targetDir := filepath.Join(root, "data")
+			outside := filepath.Join(root, "outside")
+			if err := os.MkdirAll(outside, 0755); err != nil {
+				t.Fatal(err)
+			}
+			writeFile(t, filepath.Join(outside, "trek.db-wal"), "not ours")
+
+			backupFile := filepath.Join(t.TempDir(), "crafted.tar.gz")
+			writeTarGz(t, backupFile, map[string]string{"trek.db": "restored", tt.entry(outside): "escape"})
+
+			if err := NewBackupManager(filepath.Dir(backupFile)).RestoreBackup(backupFile, targetDir); err == nil {
+				t.Errorf("RestoreBackup accepted an entry outside the target")
+			}
+			if _, err := os.Stat(filepath.Join(outside, "trek.db-wal")); err != nil {
+				t.Errorf("journal outside the target was touched: %v", err)
+			}
+		})
+	}
+}
+
+// writeTarGz writes a backup archive holding files, without backup info
+func writeTarGz(t *testing.T, path string, files map[string]string) {
+	t.Helper()
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
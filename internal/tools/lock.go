@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ErrLocked is returned when another PriceTrek process holds the lock
+var ErrLocked = errors.New("another PriceTrek instance is running")
+
+// Lock is a PID lock file guarding against concurrent writers
+type Lock struct {
+	path string
+}
+
+// AcquireLock creates the lock file at path. A lock left behind by a process
+// that is no longer running is taken over. With force, an existing lock is
+// replaced regardless of its owner.
+func AcquireLock(path string, force bool) (*Lock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = file.WriteString(strconv.Itoa(os.Getpid()))
+			file.Close()
+			if err != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("failed to write lock file: %w", err)
+			}
+			return &Lock{path: path}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to create lock file: %w", err)
+		}
+
+		pid, running := lockOwner(path)
+		if running && !force {
+			return nil, fmt.Errorf("%w (pid %d, lock file %s); use --force-lock to override", ErrLocked, pid, path)
+		}
+
+		// Stale or forced: remove and try again
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("failed to remove stale lock file: %w", err)
+		}
+	}
+
+	return nil, fmt.Errorf("%w (lock file %s)", ErrLocked, path)
+}
+
+// Release removes the lock file
+func (l *Lock) Release() error {
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove lock file: %w", err)
+	}
+	return nil
+}
+
+// lockOwner returns the PID recorded in the lock file and whether that
+// process is still alive
+func lockOwner(path string) (int, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, false
+	}
+	return pid, processAlive(pid)
+}
+
+func processAlive(pid int) bool {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	// On Windows FindProcess already fails for missing processes
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	err = proc.Signal(syscall.Signal(0))
+	return err == nil || errors.Is(err, os.ErrPermission)
+}
@@ -2,8 +2,12 @@ package tools
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
 	"runtime"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -25,6 +29,7 @@ func (sm *SystemMonitor) GetSystemStats() SystemStats {
 	runtime.ReadMemStats(&m)
 
 	return SystemStats{
+		Time:          time.Now(),
 		Uptime:        time.Since(sm.startTime),
 		GoRoutines:    runtime.NumGoroutine(),
 		MemoryAlloc:   m.Alloc,
@@ -38,6 +43,7 @@ func (sm *SystemMonitor) GetSystemStats() SystemStats {
 
 // SystemStats contains system performance statistics
 type SystemStats struct {
+	Time          time.Time // when the sample was taken
 	Uptime        time.Duration
 	GoRoutines    int
 	MemoryAlloc   uint64
@@ -62,6 +68,95 @@ func (ss *SystemStats) FormatBytes(bytes uint64) string {
 	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "KMGTPE"[exp])
 }
 
+// WriteText writes the stats as a human readable block. The block has no
+// leading or trailing blank lines; every line is "Key: value" between the
+// header and footer, so consecutive samples can be split and grepped
+func (ss SystemStats) WriteText(w io.Writer) error {
+	lastGC := "never"
+	if last := ss.lastGC(); last != nil {
+		lastGC = last.Format(time.RFC3339)
+	}
+
+	lines := []string{
+		"=== System Statistics ===",
+		"Time: " + ss.Time.Format(time.RFC3339),
+		fmt.Sprintf("Uptime: %v", ss.Uptime.Round(time.Millisecond)),
+		fmt.Sprintf("Go Routines: %d", ss.GoRoutines),
+		fmt.Sprintf("Memory Allocated: %s", ss.FormatBytes(ss.MemoryAlloc)),
+		fmt.Sprintf("Memory Total: %s", ss.FormatBytes(ss.MemoryTotal)),
+		fmt.Sprintf("Memory System: %s", ss.FormatBytes(ss.MemorySys)),
+		fmt.Sprintf("GC Count: %d", ss.NumGC),
+		fmt.Sprintf("GC Pause Total: %v", time.Duration(ss.GCPauseTotal)),
+		"Last GC: " + lastGC,
+		"=========================",
+	}
+	_, err := io.WriteString(w, strings.Join(lines, "\n")+"\n")
+	return err
+}
+
+// MarshalJSON encodes the stats with snake_case keys, durations in seconds
+// and sizes in bytes
+func (ss SystemStats) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Time                time.Time  `json:"time"`
+		UptimeSeconds       float64    `json:"uptime_seconds"`
+		GoRoutines          int        `json:"goroutines"`
+		MemoryAllocBytes    uint64     `json:"memory_alloc_bytes"`
+		MemoryTotalBytes    uint64     `json:"memory_total_alloc_bytes"`
+		MemorySysBytes      uint64     `json:"memory_sys_bytes"`
+		NumGC               uint32     `json:"gc_count"`
+		GCPauseTotalSeconds float64    `json:"gc_pause_total_seconds"`
+		LastGC              *time.Time `json:"last_gc,omitempty"`
+	}{
+		Time:                ss.Time,
+		UptimeSeconds:       ss.Uptime.Seconds(),
+		GoRoutines:          ss.GoRoutines,
+		MemoryAllocBytes:    ss.MemoryAlloc,
+		MemoryTotalBytes:    ss.MemoryTotal,
+		MemorySysBytes:      ss.MemorySys,
+		NumGC:               ss.NumGC,
+		GCPauseTotalSeconds: time.Duration(ss.GCPauseTotal).Seconds(),
+		LastGC:              ss.lastGC(),
+	})
+}
+
+// promMetric is a single sample in the Prometheus text format
+type promMetric struct {
+	name, kind, help string
+	value            float64
+}
+
+// WritePrometheus writes the stats in the Prometheus text exposition format
+func (ss SystemStats) WritePrometheus(w io.Writer) error {
+	metrics := []promMetric{
+		{"pricetrek_uptime_seconds", "gauge", "Time since the monitor started.", ss.Uptime.Seconds()},
+		{"pricetrek_goroutines", "gauge", "Number of goroutines.", float64(ss.GoRoutines)},
+		{"pricetrek_memory_alloc_bytes", "gauge", "Bytes of allocated heap objects.", float64(ss.MemoryAlloc)},
+		{"pricetrek_memory_total_alloc_bytes", "counter", "Cumulative bytes allocated for heap objects.", float64(ss.MemoryTotal)},
+		{"pricetrek_memory_sys_bytes", "gauge", "Bytes of memory obtained from the OS.", float64(ss.MemorySys)},
+		{"pricetrek_gc_count_total", "counter", "Number of completed GC cycles.", float64(ss.NumGC)},
+		{"pricetrek_gc_pause_seconds_total", "counter", "Cumulative GC stop-the-world pause time.", time.Duration(ss.GCPauseTotal).Seconds()},
+	}
+	if last := ss.lastGC(); last != nil {
+		metrics = append(metrics, promMetric{"pricetrek_last_gc_timestamp_seconds", "gauge", "Unix time of the last GC.", float64(last.UnixNano()) / 1e9})
+	}
+
+	for _, m := range metrics {
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s %s\n", m.name, m.help, m.name, m.kind, m.name, strconv.FormatFloat(m.value, 'f', -1, 64)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lastGC returns the last GC time, or nil if no GC has run yet
+func (ss SystemStats) lastGC() *time.Time {
+	if ss.NumGC == 0 || ss.LastGC.IsZero() {
+		return nil
+	}
+	return &ss.LastGC
+}
+
 // MonitorLoop runs continuous monitoring
 func (sm *SystemMonitor) MonitorLoop(ctx context.Context, interval time.Duration, callback func(SystemStats)) {
 	ticker := time.NewTicker(interval)
@@ -0,0 +1,127 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/httpclient"
+	"github.com/makalin/pricetrek/internal/scheduler"
+)
+
+// HostEstimate is the number of requests a tracking loop is expected to
+// send to one host per day
+type HostEstimate struct {
+	Host           string   `json:"host"`
+	Items          []string `json:"items"`
+	RequestsPerDay float64  `json:"requests_per_day"`
+	OverLimit      bool     `json:"over_limit"`
+}
+
+// Estimate returns the requests per day a loop ticking every tick would send
+// to each host, busiest first, flagging hosts above limit. It follows
+// TrackDue: items without a fixed interval or cron expression are fetched
+// every tick, the rest on the first tick within half a tick of their
+// (average, for cron) interval, scaled down by their active hours. Items
+// sharing a fetch key count once, and exec items, retries and redirects are
+// not counted.
+func (t *Tracker) Estimate(ctx context.Context, tick time.Duration, limit float64) ([]HostEstimate, error) {
+	if tick <= 0 {
+		return nil, fmt.Errorf("tick must be positive")
+	}
+
+	type fetch struct {
+		host   string
+		perDay float64
+	}
+	fetches := make(map[string]*fetch)
+	hosts := make(map[string]*HostEstimate)
+
+	for _, item := range t.items(ctx) {
+		if !slices.ContainsFunc(item.ProviderChain(), func(name string) bool { return name != "exec" }) {
+			continue
+		}
+		host := httpclient.HostKey(item.URL)
+		if host == "" {
+			continue
+		}
+
+		interval, _ := scheduler.Gaps(item.Schedule)
+		perDay := float64(24*time.Hour) / float64(runEvery(interval, tick))
+		share, err := t.activeShare(item.ActiveHours)
+		if err != nil {
+			return nil, fmt.Errorf("item %s: %w", item.ID, err)
+		}
+		perDay *= share
+
+		estimate, ok := hosts[host]
+		if !ok {
+			estimate = &HostEstimate{Host: host}
+			hosts[host] = estimate
+		}
+		estimate.Items = append(estimate.Items, item.ID)
+
+		// Items sharing a fetch key and URL read one page per run between
+		// them
+		key := item.ID
+		if item.FetchKey != "" {
+			key = "\x00" + item.FetchKey + " " + item.URL
+		}
+		if f, ok := fetches[key]; ok {
+			f.perDay = max(f.perDay, perDay)
+			continue
+		}
+		fetches[key] = &fetch{host: host, perDay: perDay}
+	}
+
+	for _, f := range fetches {
+		hosts[f.host].RequestsPerDay += f.perDay
+	}
+
+	estimates := make([]HostEstimate, 0, len(hosts))
+	for _, estimate := range hosts {
+		estimate.OverLimit = limit > 0 && estimate.RequestsPerDay > limit
+		estimates = append(estimates, *estimate)
+	}
+	sort.Slice(estimates, func(i, j int) bool {
+		if estimates[i].RequestsPerDay != estimates[j].RequestsPerDay {
+			return estimates[i].RequestsPerDay > estimates[j].RequestsPerDay
+		}
+		return estimates[i].Host < estimates[j].Host
+	})
+	return estimates, nil
+}
+
+// runEvery returns how often an item with the given schedule interval is
+// fetched by a loop ticking every tick. TrackDue counts an item due half a
+// tick early, so the interval rounds to the nearest whole number of ticks.
+func runEvery(interval, tick time.Duration) time.Duration {
+	if interval <= tick {
+		return tick
+	}
+	ticks := (interval - tick/2 + tick - 1) / tick
+	return max(ticks, 1) * tick
+}
+
+// activeShare returns the fraction of the day an item with the given active
+// hours (or defaults.active_hours) is tracked
+func (t *Tracker) activeShare(hours string) (float64, error) {
+	if hours == "" {
+		hours = t.config.Defaults.ActiveHours
+	}
+	if hours == "" {
+		return 1, nil
+	}
+
+	window, err := scheduler.ParseWindow(hours)
+	if err != nil {
+		return 0, fmt.Errorf("invalid active_hours: %w", err)
+	}
+	span := window.End - window.Start
+	if span < 0 {
+		span += 24 * time.Hour
+	}
+	return float64(span) / float64(24*time.Hour), nil
+}
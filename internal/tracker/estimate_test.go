@@ -0,0 +1,45 @@
+package tracker
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/config"
+)
+
+func TestEstimate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+		tick     time.Duration
+		want     float64
+	}{
+		{"hourly", "hourly", 15 * time.Minute, 24},
+		{"no fixed schedule runs every tick", "", 15 * time.Minute, 96},
+		{"interval below the tick", "5m", 15 * time.Minute, 96},
+		{"daily cron", "0 9 * * *", 15 * time.Minute, 1},
+		{"cron step", "*/30 * * * *", 15 * time.Minute, 48},
+		{"weekday cron", "0 9 * * 1-5", time.Hour, 5.0 / 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Items = []config.ItemConfig{{ID: "a", Provider: "generic", URL: "https://shop.example/p", Schedule: tt.schedule}}
+			tr, _ := newTestTracker(t, cfg)
+
+			estimates, err := tr.Estimate(context.Background(), tt.tick, 0)
+			if err != nil {
+				t.Fatalf("Estimate: %v", err)
+			}
+			if len(estimates) != 1 {
+				t.Fatalf("got %d hosts, want 1", len(estimates))
+			}
+			if got := estimates[0].RequestsPerDay; math.Abs(got-tt.want) > 0.05 {
+				t.Errorf("requests per day = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
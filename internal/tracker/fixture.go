@@ -0,0 +1,158 @@
+package tracker
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/config"
+)
+
+//go:embed fixtures
+var fixtureFiles embed.FS
+
+// providerFixture is a known page and the price a provider must read from it
+type providerFixture struct {
+	file        string
+	contentType string
+	item        config.ItemConfig
+	price       float64
+	currency    string
+}
+
+// providerFixtures holds one fixture per provider. HTTP fixtures are served
+// from a local test server; the exec fixture is written to a temporary file
+// for the command to print.
+var providerFixtures = map[string]providerFixture{
+	"generic": {
+		file:        "generic.html",
+		contentType: "text/html; charset=utf-8",
+		item:        config.ItemConfig{Selector: ".price", Currency: "USD"},
+		price:       1234.56,
+		currency:    "USD",
+	},
+	"json": {
+		file:        "json.json",
+		contentType: "application/json",
+		item:        config.ItemConfig{Selector: "price", Currency: "EUR"},
+		price:       49.99,
+		currency:    "EUR",
+	},
+	"exec": {
+		file:     "exec.json",
+		item:     config.ItemConfig{Currency: "TRY"},
+		price:    4199.00,
+		currency: "TRY",
+	},
+	"headless": {
+		file:        "generic.html",
+		contentType: "text/html; charset=utf-8",
+		item:        config.ItemConfig{Selector: ".price", Currency: "USD"},
+		price:       1234.56,
+		currency:    "USD",
+	},
+}
+
+// FixtureProviders lists the providers with a bundled fixture
+func FixtureProviders() []string {
+	names := make([]string, 0, len(providerFixtures))
+	for name := range providerFixtures {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// ProviderTest is the outcome of running a provider against its fixture
+type ProviderTest struct {
+	Provider   string  `json:"provider"`
+	Fixture    string  `json:"fixture"`
+	Expected   float64 `json:"expected"`
+	Price      float64 `json:"price,omitempty"`
+	Currency   string  `json:"currency,omitempty"`
+	Passed     bool    `json:"passed"`
+	Error      string  `json:"error,omitempty"`
+	DurationMS int64   `json:"duration_ms"`
+}
+
+// TestProvider runs the named provider through the regular fetch path
+// against its bundled fixture and checks the price and currency it reads.
+// Nothing leaves the machine. It returns an error only for providers
+// without a fixture; a failed check is reported in the result.
+func (t *Tracker) TestProvider(ctx context.Context, name string) (*ProviderTest, error) {
+	fixture, ok := providerFixtures[name]
+	if !ok {
+		return nil, fmt.Errorf("no fixture for provider %q (available: %s)", name, strings.Join(FixtureProviders(), ", "))
+	}
+	result := &ProviderTest{Provider: name, Fixture: fixture.file, Expected: fixture.price}
+
+	body, err := fixtureFiles.ReadFile("fixtures/" + fixture.file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixture: %w", err)
+	}
+
+	item := fixture.item
+	item.ID = "fixture-" + name
+	item.Name = item.ID
+	item.Provider = name
+
+	if name == "exec" {
+		dir, err := os.MkdirTemp("", "pricetrek-fixture-")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fixture directory: %w", err)
+		}
+		defer os.RemoveAll(dir)
+		path := filepath.Join(dir, fixture.file)
+		if err := os.WriteFile(path, body, 0644); err != nil {
+			return nil, fmt.Errorf("failed to write fixture: %w", err)
+		}
+		item.URL = "file://" + filepath.ToSlash(path)
+		item.Command = printFileCommand(path)
+	} else {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", fixture.contentType)
+			w.Write(body)
+		}))
+		defer server.Close()
+		item.URL = server.URL + "/" + fixture.file
+	}
+
+	start := time.Now()
+	sample, err := t.FetchItem(ctx, item)
+	result.DurationMS = time.Since(start).Milliseconds()
+	if err != nil {
+		result.Error = err.Error()
+		return result, nil
+	}
+
+	result.Price = sample.Price
+	result.Currency = sample.Currency
+	switch {
+	case math.Abs(sample.Price-fixture.price) >= 0.005:
+		result.Error = fmt.Sprintf("expected price %.2f, got %v", fixture.price, sample.Price)
+	case !strings.EqualFold(sample.Currency, fixture.currency):
+		result.Error = fmt.Sprintf("expected currency %s, got %s", fixture.currency, sample.Currency)
+	default:
+		result.Passed = true
+	}
+	return result, nil
+}
+
+// printFileCommand returns a shell command printing the file at path, which
+// may contain spaces or quotes
+func printFileCommand(path string) string {
+	if runtime.GOOS == "windows" {
+		// Windows paths can't contain double quotes
+		return `type "` + path + `"`
+	}
+	return "cat '" + strings.ReplaceAll(path, "'", `'\''`) + "'"
+}
@@ -0,0 +1,47 @@
+package tracker
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestPrintFileCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("runs the command with sh")
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+	}{
+		{name: "plain", dir: "fixtures"},
+		{name: "spaces", dir: "my fixtures"},
+		{name: "quotes", dir: `it's "here"`},
+		{name: "shell characters", dir: "$HOME; rm -rf `x`"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), tt.dir)
+			if err := os.Mkdir(dir, 0755); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join(dir, "exec.json")
+			want := `{"price": 4199.00}`
+			if err := os.WriteFile(path, []byte(want), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			out, err := exec.Command("sh", "-c", printFileCommand(path)).Output()
+			if err != nil {
+				t.Fatalf("%s: %v", printFileCommand(path), err)
+			}
+			if string(out) != want {
+				t.Errorf("%s printed %q, want %q", printFileCommand(path), out, want)
+			}
+		})
+	}
+}
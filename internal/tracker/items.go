@@ -0,0 +1,140 @@
+package tracker
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/makalin/pricetrek/internal/config"
+)
+
+// Item sources for item_source
+const (
+	ItemSourceMerge  = "merge"
+	ItemSourceConfig = "config"
+	ItemSourceDB     = "db"
+)
+
+// ValidItemSource reports whether source is an item_source value; empty
+// means merge
+func ValidItemSource(source string) error {
+	switch source {
+	case "", ItemSourceMerge, ItemSourceConfig, ItemSourceDB:
+		return nil
+	}
+	return fmt.Errorf("invalid item source %q (use merge, config or db)", source)
+}
+
+// items returns the items runs work on, per item_source:
+//
+//   - config: the items in the config file
+//   - db: the items in the database (added with add, import or sync)
+//   - merge (default): config items in file order, then database-only
+//     items; an ID in both uses the database copy field by field, falling
+//     back to the config value for fields the database doesn't set (such as
+//     percent_rise, which isn't stored). Config fields that differ from a
+//     set database field are logged once as a resolved conflict
+//
+// Without storage, or when the database can't be read, the config items
+// are used.
+func (t *Tracker) items(ctx context.Context) []config.ItemConfig {
+	configured := t.configItems()
+	source := t.config.ItemSource
+	if source == ItemSourceConfig || t.storage == nil {
+		return configured
+	}
+
+	stored, err := t.storage.GetItems(ctx)
+	if err != nil {
+		t.logger.Warn("Failed to load items from the database, using the config items", "error", err)
+		return configured
+	}
+	dbItems := make([]config.ItemConfig, len(stored))
+	for i, item := range stored {
+		dbItems[i] = item.Config()
+	}
+	if source == ItemSourceDB {
+		return dbItems
+	}
+
+	byID := make(map[string]config.ItemConfig, len(dbItems))
+	for _, item := range dbItems {
+		byID[item.ID] = item
+	}
+	merged := make([]config.ItemConfig, 0, len(configured)+len(dbItems))
+	seen := make(map[string]bool, len(configured))
+	for _, item := range configured {
+		seen[item.ID] = true
+		dbItem, ok := byID[item.ID]
+		if !ok {
+			merged = append(merged, item)
+			continue
+		}
+		if fields := conflictingFields(item, dbItem); len(fields) > 0 {
+			t.logConflict(item.ID, fields)
+		}
+		merged = append(merged, mergeItem(item, dbItem))
+	}
+	for _, item := range dbItems {
+		if !seen[item.ID] {
+			merged = append(merged, item)
+		}
+	}
+	return merged
+}
+
+// Item returns the item with the given ID from the items runs work on
+func (t *Tracker) Item(ctx context.Context, id string) (config.ItemConfig, bool) {
+	for _, item := range t.items(ctx) {
+		if item.ID == id {
+			return item, true
+		}
+	}
+	return config.ItemConfig{}, false
+}
+
+// logConflict logs the first time an item's config copy is overridden by
+// its database copy
+func (t *Tracker) logConflict(id string, fields []string) {
+	t.itemsMu.Lock()
+	defer t.itemsMu.Unlock()
+	if t.conflicts[id] {
+		return
+	}
+	if t.conflicts == nil {
+		t.conflicts = make(map[string]bool)
+	}
+	t.conflicts[id] = true
+	t.logger.Info("Item is in both the config and the database, using the database copy (item_source: merge)",
+		"item", id, "fields", strings.Join(fields, ","))
+}
+
+// conflictingFields returns the YAML names of the fields set in the config
+// copy of an item that differ from the database copy. Fields left empty in
+// either copy don't conflict.
+func conflictingFields(configured, stored config.ItemConfig) []string {
+	var fields []string
+	cv, sv := reflect.ValueOf(configured), reflect.ValueOf(stored)
+	for i := 0; i < cv.NumField(); i++ {
+		if cv.Field(i).IsZero() || sv.Field(i).IsZero() || reflect.DeepEqual(cv.Field(i).Interface(), sv.Field(i).Interface()) {
+			continue
+		}
+		name, _, _ := strings.Cut(cv.Type().Field(i).Tag.Get("yaml"), ",")
+		fields = append(fields, name)
+	}
+	return fields
+}
+
+// mergeItem returns the database copy of an item with the fields it leaves
+// empty taken from the config copy
+func mergeItem(configured, stored config.ItemConfig) config.ItemConfig {
+	merged := stored
+	mv, cv := reflect.ValueOf(&merged).Elem(), reflect.ValueOf(configured)
+	for i := 0; i < mv.NumField(); i++ {
+		if mv.Field(i).IsZero() {
+			mv.Field(i).Set(cv.Field(i))
+		}
+	}
+	return merged
+}
@@ -0,0 +1,52 @@
+package tracker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/config"
+)
+
+func float(v float64) *float64 { return &v }
+
+func TestMergeItem(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured config.ItemConfig
+		stored     config.ItemConfig
+		want       config.ItemConfig
+		conflicts  []string
+	}{
+		{
+			name:       "config fills fields the database doesn't store",
+			configured: config.ItemConfig{ID: "a", Name: "A", PercentRise: float(5)},
+			stored:     config.ItemConfig{ID: "a", Name: "A"},
+			want:       config.ItemConfig{ID: "a", Name: "A", PercentRise: float(5)},
+		},
+		{
+			name:       "database wins on set fields",
+			configured: config.ItemConfig{ID: "a", Name: "Config", HTTPTimeout: time.Minute},
+			stored:     config.ItemConfig{ID: "a", Name: "Database"},
+			want:       config.ItemConfig{ID: "a", Name: "Database", HTTPTimeout: time.Minute},
+			conflicts:  []string{"name"},
+		},
+		{
+			name:       "empty config fields keep the database value",
+			configured: config.ItemConfig{ID: "a"},
+			stored:     config.ItemConfig{ID: "a", Selector: ".price", TargetPrice: float(10)},
+			want:       config.ItemConfig{ID: "a", Selector: ".price", TargetPrice: float(10)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeItem(tt.configured, tt.stored); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeItem() = %+v, want %+v", got, tt.want)
+			}
+			if got := conflictingFields(tt.configured, tt.stored); !reflect.DeepEqual(got, tt.conflicts) {
+				t.Errorf("conflictingFields() = %v, want %v", got, tt.conflicts)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package tracker
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/storage"
+)
+
+// RunResult summarizes a tracking run
+type RunResult struct {
+	Attempted int
+	Succeeded int
+	Failed    int
+	Skipped   int // items outside their active hours, or fresh with RespectCache
+	NotDue    int // items left for a later run by their schedule
+	Duration  time.Duration
+	FetchTime time.Duration // sum of per-item fetch durations
+	// FetchesSaved counts requests served from the run's shared page cache
+	FetchesSaved int
+	// Recovered lists the items that failed first and succeeded on the
+	// retry pass at the end of the run
+	Recovered []string
+}
+
+// AverageFetch returns the mean duration of attempted fetches
+func (r *RunResult) AverageFetch() time.Duration {
+	if r.Attempted == 0 {
+		return 0
+	}
+	return r.FetchTime / time.Duration(r.Attempted)
+}
+
+// MarshalJSON renders durations in milliseconds
+func (r *RunResult) MarshalJSON() ([]byte, error) {
+	recovered := r.Recovered
+	if recovered == nil {
+		recovered = []string{}
+	}
+	return json.Marshal(map[string]interface{}{
+		"attempted":     r.Attempted,
+		"succeeded":     r.Succeeded,
+		"failed":        r.Failed,
+		"skipped":       r.Skipped,
+		"not_due":       r.NotDue,
+		"duration_ms":   r.Duration.Milliseconds(),
+		"avg_fetch_ms":  r.AverageFetch().Milliseconds(),
+		"fetches_saved": r.FetchesSaved,
+		"recovered":     recovered,
+	})
+}
+
+// ItemResult is the outcome of tracking one item, reported to the
+// OnItemResult handler
+type ItemResult struct {
+	ID       string  `json:"id"`
+	Price    float64 `json:"price,omitempty"`
+	Currency string  `json:"currency,omitempty"`
+	Stored   bool    `json:"stored"`
+	// ChangePct is the change from the previous sample, if there is one
+	ChangePct *float64 `json:"change_pct,omitempty"`
+	// Triggered lists the rules whose conditions were met, whether or not
+	// their alerts could be delivered
+	Triggered []string `json:"triggered,omitempty"`
+	Alerts    []string `json:"alerts,omitempty"` // rules whose alerts were sent
+	Error     string   `json:"error,omitempty"`
+	// fetchFailed is set when the error came from fetching, not storing
+	fetchFailed bool
+}
+
+// AlertFired reports whether any alert was delivered for the item
+func (r ItemResult) AlertFired() bool {
+	return len(r.Alerts) > 0
+}
+
+// MarshalJSON adds the alert_fired flag
+func (r ItemResult) MarshalJSON() ([]byte, error) {
+	type plain ItemResult
+	return json.Marshal(struct {
+		plain
+		AlertFired bool `json:"alert_fired"`
+	}{plain(r), r.AlertFired()})
+}
+
+// setChange records the percent change between the two newest prices
+func (r *ItemResult) setChange(prices []storage.PriceSample) {
+	if len(prices) < 2 || prices[1].Price == 0 {
+		return
+	}
+	change := (prices[0].Price - prices[1].Price) / prices[1].Price * 100
+	r.ChangePct = &change
+}
@@ -0,0 +1,97 @@
+package tracker
+
+import (
+	"context"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/config"
+	"github.com/makalin/pricetrek/internal/scheduler"
+)
+
+// TrackDue tracks the items whose schedule says they are due at now and
+// counts the rest as not due. An item is due once its interval has passed
+// since its last fetch, or its cron expression has matched since then.
+// Items never fetched before, and those whose schedule is neither, are
+// always due. An item whose next run falls within slack after now counts as
+// due too, so a tick arriving a little early doesn't postpone it by a whole
+// tick.
+func (t *Tracker) TrackDue(ctx context.Context, now time.Time, slack time.Duration) *RunResult {
+	items := t.items(ctx)
+	last := t.lastRuns(ctx)
+
+	// Cron expressions are read in defaults.timezone
+	loc, err := time.LoadLocation(t.config.Defaults.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	var due []config.ItemConfig
+	for _, item := range items {
+		ran, ok := last[item.ID]
+		if !ok {
+			due = append(due, item)
+			continue
+		}
+		next, scheduled := scheduler.NextRun(item.Schedule, ran.In(loc))
+		if !scheduled || !now.Add(slack).Before(next) {
+			due = append(due, item)
+			continue
+		}
+		t.logger.Debug("Item not due yet", "item", item.ID, "schedule", item.Schedule, "next_run", next.Format(time.RFC3339))
+	}
+
+	t.logger.Info("Starting scheduled price tracking", "due", len(due), "not_due", len(items)-len(due))
+	result := t.TrackItems(ctx, due)
+	result.NotDue = len(items) - len(due)
+	return result
+}
+
+// ShortestInterval returns the shortest time between runs of any item's
+// schedule, or 0 when no item has a fixed interval or cron expression
+func (t *Tracker) ShortestInterval(ctx context.Context) time.Duration {
+	var shortest time.Duration
+	for _, item := range t.items(ctx) {
+		_, interval := scheduler.Gaps(item.Schedule)
+		if interval > 0 && (shortest == 0 || interval < shortest) {
+			shortest = interval
+		}
+	}
+	return shortest
+}
+
+// markRun records that an item was fetched at when
+func (t *Tracker) markRun(itemID string, when time.Time) {
+	t.lastRunMu.Lock()
+	defer t.lastRunMu.Unlock()
+	if t.lastRun == nil {
+		t.lastRun = make(map[string]time.Time)
+	}
+	t.lastRun[itemID] = when
+}
+
+// lastRuns returns when each item was last fetched. The first call starts
+// from the fetch statuses in storage, so a restarted loop doesn't refetch
+// items that ran recently.
+func (t *Tracker) lastRuns(ctx context.Context) map[string]time.Time {
+	t.lastRunMu.Lock()
+	defer t.lastRunMu.Unlock()
+
+	if t.lastRun == nil {
+		t.lastRun = make(map[string]time.Time)
+		if t.storage != nil {
+			statuses, err := t.storage.GetFetchStatuses(ctx)
+			if err != nil {
+				t.logger.Warn("Failed to load last fetch times, treating every item as due", "error", err)
+			}
+			for id, status := range statuses {
+				t.lastRun[id] = status.Time
+			}
+		}
+	}
+
+	last := make(map[string]time.Time, len(t.lastRun))
+	for id, when := range t.lastRun {
+		last[id] = when
+	}
+	return last
+}
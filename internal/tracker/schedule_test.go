@@ -0,0 +1,48 @@
+package tracker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/config"
+)
+
+func TestTrackDue(t *testing.T) {
+	now := time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		schedule string
+		lastRun  time.Duration // before now; 0 means never ran
+		slack    time.Duration
+		wantDue  bool
+	}{
+		{"never ran", "hourly", 0, 0, true},
+		{"interval passed", "hourly", 61 * time.Minute, 0, true},
+		{"interval not passed", "hourly", 30 * time.Minute, 0, false},
+		{"interval ends within slack", "hourly", 55 * time.Minute, 10 * time.Minute, true},
+		{"cron matched since last run", "0 10 * * *", 31 * time.Minute, 0, true},
+		{"cron not matched since last run", "0 10 * * *", 29 * time.Minute, 0, false},
+		{"cron matches within slack", "45 10 * * *", 10 * time.Minute, 20 * time.Minute, true},
+		{"cron on another weekday", "0 10 * * 1", 2 * time.Hour, 0, false},
+		{"unknown schedule", "whenever", time.Minute, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := config.Default()
+			cfg.Items = []config.ItemConfig{{ID: "a", Provider: "generic", URL: "http://127.0.0.1:1/", Schedule: tt.schedule}}
+			tr, _ := newTestTracker(t, cfg)
+			tr.lastRun = make(map[string]time.Time)
+			if tt.lastRun > 0 {
+				tr.markRun("a", now.Add(-tt.lastRun))
+			}
+
+			result := tr.TrackDue(context.Background(), now, tt.slack)
+			if gotDue := result.NotDue == 0; gotDue != tt.wantDue {
+				t.Errorf("due = %v, want %v (%+v)", gotDue, tt.wantDue, result)
+			}
+		})
+	}
+}
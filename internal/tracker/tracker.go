@@ -2,75 +2,771 @@ package tracker
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/makalin/pricetrek/internal/config"
+	"github.com/makalin/pricetrek/internal/fx"
+	"github.com/makalin/pricetrek/internal/httpclient"
 	"github.com/makalin/pricetrek/internal/logger"
+	"github.com/makalin/pricetrek/internal/notifications"
 	"github.com/makalin/pricetrek/internal/providers"
+	"github.com/makalin/pricetrek/internal/scheduler"
 	"github.com/makalin/pricetrek/internal/storage"
+	"github.com/makalin/pricetrek/internal/telemetry"
+	"github.com/makalin/pricetrek/internal/utils"
 )
 
 type Tracker struct {
-	config  *config.Config
-	storage storage.Storage
-	logger  *logger.Logger
+	config   *config.Config
+	storage  storage.Storage
+	logger   *logger.Logger
+	notifier *notifications.NotificationManager
+	noStore  bool
+	onItem   func(ItemResult)
+	progress func(n, total int, name string)
+	// itemsMu guards config.Items, which SetItems replaces on config reload
+	itemsMu  sync.RWMutex
+	// conflicts holds the item IDs whose config/DB conflict was logged
+	conflicts map[string]bool
+	// rates normalize samples to fx.normalize_to; refreshed every run
+	rates    *fx.Rates
+	// lastRun records when each item was last fetched, for TrackDue
+	lastRunMu sync.Mutex
+	lastRun   map[string]time.Time
+	// telemetry batches failed fetches per run; nil unless opted in
+	telemetry *telemetry.Reporter
+	// cacheTTL skips items sampled more recently than this; 0 fetches all
+	cacheTTL time.Duration
+	// alertDryRun logs triggered alerts instead of sending them
+	alertDryRun bool
 }
 
 func New(cfg *config.Config, store storage.Storage, log *logger.Logger) *Tracker {
 	return &Tracker{
-		config:  cfg,
-		storage: store,
-		logger:  log,
+		config:    cfg,
+		storage:   store,
+		logger:    log,
+		notifier:  notifications.New(cfg, log),
+		telemetry: telemetry.New(cfg.Defaults.Telemetry, log),
 	}
 }
 
+// providerContentTypes lists the response media types each HTTP provider
+// can parse
+var providerContentTypes = map[string][]string{
+	"generic": {"text/html", "application/xhtml+xml"},
+	"json":    {"application/json"},
+}
+
+// Sample is a fetched price ready to be stored
+type Sample struct {
+	Price    float64
+	Currency string
+	Meta     map[string]interface{}
+}
+
+// DisableStore makes tracking evaluate alerts against live fetches without
+// saving the samples
+func (t *Tracker) DisableStore() {
+	t.noStore = true
+}
+
+// OnItemResult registers fn to receive the outcome of every item tracked by
+// TrackItems. Per-item info logging drops to debug level while it is set.
+func (t *Tracker) OnItemResult(fn func(ItemResult)) {
+	t.onItem = fn
+}
+
+// DryRunAlerts makes alert evaluation log the alerts that would be sent
+// instead of sending them, leaving the stored alert states untouched
+func (t *Tracker) DryRunAlerts() {
+	t.alertDryRun = true
+}
+
+// RespectCache makes TrackItems skip items whose latest stored sample is
+// younger than ttl, counting them in the run's Skipped
+func (t *Tracker) RespectCache(ttl time.Duration) {
+	t.cacheTTL = ttl
+}
+
+// fresh reports whether item's latest stored sample is within the cache TTL
+func (t *Tracker) fresh(ctx context.Context, item config.ItemConfig) bool {
+	if t.cacheTTL <= 0 || t.storage == nil {
+		return false
+	}
+	prices, err := t.storage.GetPrices(ctx, item.ID, 1)
+	if err != nil || len(prices) == 0 {
+		return false
+	}
+	return time.Since(prices[0].Time) < t.cacheTTL
+}
+
+// SetItems replaces the configured items tracked by TrackAll and
+// CheckAlerts. A run already in progress keeps the items it started with.
+func (t *Tracker) SetItems(items []config.ItemConfig) {
+	t.itemsMu.Lock()
+	defer t.itemsMu.Unlock()
+	t.config.Items = items
+}
+
+// configItems returns the configured items
+func (t *Tracker) configItems() []config.ItemConfig {
+	t.itemsMu.RLock()
+	defer t.itemsMu.RUnlock()
+	return t.config.Items
+}
+
+// OnProgress registers fn to be called by TrackItems before each item with
+// its 1-based position, and once more with n == total and no name when the
+// run is done. Per-item info logging drops to debug level while it is set.
+func (t *Tracker) OnProgress(fn func(n, total int, name string)) {
+	t.progress = fn
+}
+
 func (t *Tracker) TrackItem(ctx context.Context, item config.ItemConfig) error {
+	_, err := t.trackItem(ctx, item)
+	return err
+}
+
+// trackItem fetches, stores and evaluates one item, describing the outcome
+// in an ItemResult
+func (t *Tracker) trackItem(ctx context.Context, item config.ItemConfig) (*ItemResult, error) {
 	t.logger.Debug("Tracking item", "id", item.ID, "name", item.Name)
+	result := &ItemResult{ID: item.ID}
 
-	// Get provider
-	provider, err := providers.GetProvider(item.Provider, t.config.Defaults)
+	sample, err := t.FetchItem(ctx, item)
+	t.recordStatus(ctx, item, err)
 	if err != nil {
-		return fmt.Errorf("failed to get provider: %w", err)
+		t.trackLevelChange(ctx, item, err)
+		result.fetchFailed = true
+		return result, err
 	}
+	t.normalize(item, sample)
+	result.Price = sample.Price
+	result.Currency = sample.Currency
 
-	// Fetch price
-	sample, err := provider.Fetch(ctx, item)
-	if err != nil {
-		return fmt.Errorf("failed to fetch price: %w", err)
+	logInfo := t.logger.Info
+	if t.onItem != nil || t.progress != nil {
+		logInfo = t.logger.Debug
+	}
+
+	if t.noStore {
+		logInfo("Price fetched (not stored)",
+			"item", item.ID,
+			"price", sample.Price,
+			"currency", sample.Currency,
+		)
+
+		// Evaluate the live sample against the stored history
+		history, err := t.storage.GetPrices(ctx, item.ID, t.historyLimit(item)-1)
+		if err != nil {
+			return result, fmt.Errorf("failed to get price history: %w", err)
+		}
+		live := storage.PriceSample{
+			ItemID:   item.ID,
+			Time:     time.Now(),
+			Price:    sample.Price,
+			Currency: sample.Currency,
+			Meta:     sample.Meta,
+		}
+		prices := append([]storage.PriceSample{live}, history...)
+		result.setChange(prices)
+		result.Triggered, result.Alerts, err = t.evaluateAlerts(ctx, item, prices)
+		if err != nil {
+			t.logger.Error("Failed to check alerts for item", "item", item.ID, "error", err)
+		}
+		return result, nil
 	}
 
 	// Save to storage
 	if err := t.storage.SavePrice(ctx, item.ID, sample.Price, sample.Currency, sample.Meta); err != nil {
-		return fmt.Errorf("failed to save price: %w", err)
+		return result, fmt.Errorf("failed to save price: %w", err)
+	}
+	result.Stored = true
+	if t.validateRules(item).MaxChange > 0 {
+		if err := t.storage.DeletePendingLevel(ctx, item.ID); err != nil {
+			t.logger.Warn("Failed to clear pending price level", "item", item.ID, "error", err)
+		}
 	}
 
-	t.logger.Info("Price tracked", 
+	logInfo("Price tracked", 
 		"item", item.ID, 
 		"price", sample.Price, 
 		"currency", sample.Currency,
 	)
 
-	return nil
+	// Evaluate alert rules on the new sample
+	prices, err := t.storage.GetPrices(ctx, item.ID, t.historyLimit(item))
+	if err != nil {
+		t.logger.Error("Failed to check alerts for item", "item", item.ID,
+			"error", fmt.Errorf("failed to get price history: %w", err))
+		return result, nil
+	}
+	result.setChange(prices)
+	result.Triggered, result.Alerts, err = t.evaluateAlerts(ctx, item, prices)
+	if err != nil {
+		t.logger.Error("Failed to check alerts for item", "item", item.ID, "error", err)
+	}
+
+	return result, nil
+}
+
+// FetchItem runs the item's provider once and returns the resulting sample
+// without storing it. Samples failing validateSample are rejected; items
+// with a provider chain then try the next provider, recording the one that
+// succeeded in meta.provider.
+func (t *Tracker) FetchItem(ctx context.Context, item config.ItemConfig) (*Sample, error) {
+	chain := item.ProviderChain()
+	tried := slices.Clone(chain)
+	var sample *Sample
+	var errs []error
+	for i, name := range chain {
+		attempt := item
+		attempt.Provider = name
+		fetched, err := t.fetch(ctx, attempt)
+		if err == nil {
+			err = t.validateSample(ctx, item, fetched)
+		}
+		if err == nil {
+			sample = fetched
+			if len(chain) > 1 {
+				sample.Meta["provider"] = name
+			}
+			break
+		}
+		errs = append(errs, err)
+		if i < len(chain)-1 {
+			t.logger.Warn("Provider failed, trying the next one", "item", item.ID, "provider", name, "next", chain[i+1], "error", err)
+		}
+	}
+
+	var blocked *httpclient.BlockedError
+	if sample == nil && errors.As(errs[len(errs)-1], &blocked) && t.config.Defaults.Headless.OnBlock && !slices.Contains(chain, "headless") {
+		t.logger.Warn("Fetch blocked, retrying with headless provider",
+			"item", item.ID,
+			"marker", blocked.Marker,
+		)
+		fallback := item
+		fallback.Provider = "headless"
+		fetched, err := t.fetch(ctx, fallback)
+		if err == nil {
+			err = t.validateSample(ctx, item, fetched)
+		}
+		if err != nil {
+			tried = append(tried, fallback.Provider)
+			errs = append(errs, err)
+		} else {
+			sample = fetched
+			if len(chain) > 1 {
+				sample.Meta["provider"] = fallback.Provider
+			}
+		}
+	}
+	if sample == nil {
+		if len(chain) == 1 {
+			return nil, errs[len(errs)-1]
+		}
+		return nil, &chainError{providers: tried, errs: errs}
+	}
+
+	// Store the currency the page reported, falling back to the configured one
+	if sample.Currency == "" {
+		sample.Currency = item.Currency
+	}
+	if item.Currency != "" && !strings.EqualFold(sample.Currency, item.Currency) {
+		t.logger.Warn("Detected currency differs from configured currency",
+			"item", item.ID,
+			"detected", sample.Currency,
+			"configured", item.Currency,
+		)
+		sample.Meta["detected_currency"] = sample.Currency
+		sample.Meta["configured_currency"] = item.Currency
+	}
+
+	return sample, nil
 }
 
-func (t *Tracker) TrackAll(ctx context.Context) error {
+// chainError reports that every provider in an item's chain failed
+type chainError struct {
+	providers []string
+	errs      []error
+}
+
+func (e *chainError) Error() string {
+	parts := make([]string, len(e.errs))
+	for i, err := range e.errs {
+		parts[i] = fmt.Sprintf("%s: %v", e.providers[i], err)
+	}
+	return "all providers failed: " + strings.Join(parts, "; ")
+}
+
+func (e *chainError) Unwrap() []error {
+	return e.errs
+}
+
+// fetch runs the item's provider and classifies soft-block pages
+func (t *Tracker) fetch(ctx context.Context, item config.ItemConfig) (*Sample, error) {
+	// Slow sites can override the global timeout
+	defaults := t.config.Defaults
+	if item.HTTPTimeout > 0 {
+		defaults.HTTPTimeout = item.HTTPTimeout
+	}
+
+	// Get provider
+	provider, err := providers.GetProvider(item.Provider, defaults)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get provider: %w", err)
+	}
+
+	// Per-item request headers
+	if item.AcceptLanguage != "" {
+		ctx = httpclient.WithHeaders(ctx, http.Header{"Accept-Language": {item.AcceptLanguage}})
+	}
+
+	// Self-signed or private-CA targets
+	if item.TLS != nil {
+		ctx = httpclient.WithTLS(ctx, httpclient.TLSOptions{
+			InsecureSkipVerify: item.TLS.InsecureSkipVerify,
+			CAFile:             item.TLS.CAFile,
+		})
+	}
+
+	// Reject responses the provider can't parse with an informative error
+	if mediaTypes, ok := providerContentTypes[item.Provider]; ok {
+		ctx = httpclient.ExpectContentType(ctx, mediaTypes...)
+	}
+
+	ctx, report := httpclient.WithBlockReport(ctx, t.config.Defaults.BlockMarkers)
+
+	var timing *httpclient.TimingRecorder
+	if t.config.Defaults.StoreHTTPTiming {
+		ctx, timing = httpclient.WithTimingRecorder(ctx)
+	}
+
+	// Items sharing a fetch key parse the same page within a run, as long
+	// as they point at the same URL
+	if item.FetchKey != "" {
+		ctx = httpclient.WithCacheKey(ctx, item.FetchKey)
+	}
+
+	// Fetch price
+	fetched, err := provider.Fetch(ctx, item)
+	if err != nil {
+		// A marker only matters when the provider couldn't find a price;
+		// plenty of real product pages mention captcha somewhere
+		if url, marker, ok := report.Blocked(); ok {
+			return nil, &httpclient.BlockedError{URL: url, Marker: marker, Err: err}
+		}
+		return nil, fmt.Errorf("failed to fetch price: %w", err)
+	}
+
+	sample := &Sample{
+		Price:    fetched.Price,
+		Currency: fetched.Currency,
+		Meta:     make(map[string]interface{}, len(fetched.Meta)+2),
+	}
+	for key, value := range fetched.Meta {
+		sample.Meta[key] = value
+	}
+	if timing != nil {
+		if last := timing.Last(); last != nil {
+			sample.Meta["http_timing"] = last.Millis()
+		}
+	}
+	return sample, nil
+}
+
+// recordStatus stores the outcome of an item's fetch for ls and doctor
+func (t *Tracker) recordStatus(ctx context.Context, item config.ItemConfig, fetchErr error) {
+	if t.noStore || t.storage == nil {
+		return
+	}
+
+	status := storage.FetchStatus{ItemID: item.ID, Time: time.Now(), Status: storage.StatusOK}
+	var blocked *httpclient.BlockedError
+	switch {
+	case errors.As(fetchErr, &blocked):
+		status.Status = storage.StatusBlocked
+		status.Detail = fmt.Sprintf("page contains %q", blocked.Marker)
+	case fetchErr != nil:
+		status.Status = storage.StatusError
+		status.Detail = fetchErr.Error()
+	}
+
+	if err := t.storage.SaveFetchStatus(ctx, status); err != nil {
+		t.logger.Warn("Failed to record fetch status", "item", item.ID, "error", err)
+	}
+
+	kind := map[string]string{
+		storage.StatusOK:      storage.EventFetchOK,
+		storage.StatusError:   storage.EventFetchError,
+		storage.StatusBlocked: storage.EventFetchBlocked,
+	}[status.Status]
+	t.recordEvent(ctx, item.ID, kind, status.Detail)
+}
+
+// recordEvent appends to the audit log when storage.events is enabled
+func (t *Tracker) recordEvent(ctx context.Context, itemID, kind, detail string) {
+	if !t.config.Storage.Events || t.noStore || t.storage == nil {
+		return
+	}
+
+	event := storage.Event{Time: time.Now(), ItemID: itemID, Kind: kind, Detail: detail}
+	if err := t.storage.SaveEvent(ctx, event); err != nil {
+		t.logger.Warn("Failed to record event", "item", itemID, "kind", kind, "error", err)
+	}
+}
+
+// sendAlert delivers an alert and records whether it was sent, failed or
+// suppressed because no channel is routed for its rule. It reports whether
+// the alert was delivered.
+func (t *Tracker) sendAlert(ctx context.Context, alert notifications.Alert) bool {
+	channels := t.notifier.Channels(alert.Rule)
+	if len(channels) == 0 {
+		t.logger.Warn("Alert not sent: no notification channel for rule", "item", alert.ItemID, "rule", alert.Rule)
+		t.recordEvent(ctx, alert.ItemID, storage.EventAlertSuppressed,
+			fmt.Sprintf("%s: no channel routed; %s", alert.Rule, alert.Text()))
+		return false
+	}
+
+	if err := t.notifier.Send(ctx, alert); err != nil {
+		t.logger.Error("Failed to send alert", "item", alert.ItemID, "rule", alert.Rule, "error", err)
+		t.recordEvent(ctx, alert.ItemID, storage.EventAlertFailed, fmt.Sprintf("%s: %v", alert.Rule, err))
+		return false
+	}
+
+	t.recordEvent(ctx, alert.ItemID, storage.EventAlertSent,
+		fmt.Sprintf("%s via %s: %s", alert.Rule, strings.Join(channels, ", "), alert.Text()))
+	return true
+}
+
+func (t *Tracker) TrackAll(ctx context.Context) (*RunResult, error) {
 	t.logger.Info("Starting price tracking for all items")
+	return t.TrackItems(ctx, t.items(ctx)), nil
+}
+
+// TrackItems tracks the given items and returns a summary of the run
+func (t *Tracker) TrackItems(ctx context.Context, items []config.ItemConfig) *RunResult {
+	result := &RunResult{}
+	start := time.Now()
+
+	// Identical requests, and items sharing a fetch_key, are fetched once
+	// within this run
+	ctx, cache := httpclient.WithRunCache(ctx)
+	defer cache.Clear()
+
+	t.loadRates(ctx)
+
+	loc := t.location()
 
-	for _, item := range t.config.Items {
-		if err := t.TrackItem(ctx, item); err != nil {
+	var retry []config.ItemConfig
+	for i, item := range items {
+		if t.progress != nil {
+			name := item.Name
+			if name == "" {
+				name = item.ID
+			}
+			t.progress(i+1, len(items), name)
+		}
+
+		active, err := t.activeAt(item, time.Now().In(loc))
+		if err != nil {
+			result.Failed++
 			t.logger.Error("Failed to track item", "item", item.ID, "error", err)
 			continue
 		}
+		if !active {
+			result.Skipped++
+			t.logger.Debug("Outside active hours, skipping item", "item", item.ID)
+			continue
+		}
+		if t.fresh(ctx, item) {
+			result.Skipped++
+			t.logger.Debug("Latest price is within the cache TTL, skipping item", "item", item.ID)
+			continue
+		}
+
+		result.Attempted++
+		itemResult, err := t.trackTimed(ctx, item, result)
+		if err != nil && t.config.Defaults.Retry.FinalPass && retryable(ctx, err) {
+			t.logger.Warn("Failed to track item, retrying at the end of the run", "item", item.ID, "error", err)
+			retry = append(retry, item)
+			continue
+		}
+		t.finishItem(result, item, itemResult, err)
 	}
 
-	t.logger.Info("Price tracking completed")
-	return nil
+	// Transient failures get one more try once the rest of the run is done
+	if len(retry) > 0 {
+		t.logger.Info("Retrying failed items", "count", len(retry), "delay", t.config.Defaults.Retry.BaseDelay)
+		select {
+		case <-ctx.Done():
+		case <-time.After(t.config.Defaults.Retry.BaseDelay):
+		}
+		for _, item := range retry {
+			itemResult, err := t.trackTimed(ctx, item, result)
+			if err == nil {
+				t.logger.Info("Item recovered on retry", "item", item.ID)
+				result.Recovered = append(result.Recovered, item.ID)
+			}
+			t.finishItem(result, item, itemResult, err)
+		}
+	}
+
+	if t.progress != nil {
+		t.progress(len(items), len(items), "")
+	}
+	t.telemetry.Flush(ctx)
+
+	result.Duration = time.Since(start)
+	result.FetchesSaved = cache.Hits()
+	t.logger.Info("Price tracking completed",
+		"attempted", result.Attempted,
+		"succeeded", result.Succeeded,
+		"failed", result.Failed,
+		"skipped", result.Skipped,
+		"duration", result.Duration.Round(time.Millisecond),
+		"avg_fetch", result.AverageFetch().Round(time.Millisecond),
+		"fetches_saved", result.FetchesSaved,
+		"recovered", len(result.Recovered),
+	)
+	return result
 }
 
+// trackTimed tracks one item, adding its fetch duration to the run
+func (t *Tracker) trackTimed(ctx context.Context, item config.ItemConfig, result *RunResult) (*ItemResult, error) {
+	fetchStart := time.Now()
+	t.markRun(item.ID, fetchStart)
+	itemResult, err := t.trackItem(ctx, item)
+	result.FetchTime += time.Since(fetchStart)
+	return itemResult, err
+}
+
+// finishItem counts an item's final outcome and reports it
+func (t *Tracker) finishItem(result *RunResult, item config.ItemConfig, itemResult *ItemResult, err error) {
+	if t.onItem != nil {
+		if err != nil {
+			itemResult.Error = err.Error()
+		}
+		t.onItem(*itemResult)
+	}
+	if err != nil {
+		result.Failed++
+		t.logger.Error("Failed to track item", "item", item.ID, "error", err)
+		if itemResult.fetchFailed {
+			category, price := failureCategory(err)
+			t.telemetry.Record(item, category, price)
+		}
+		return
+	}
+	result.Succeeded++
+}
+
+// failureCategory classifies a failed fetch for telemetry, returning the
+// rejected price of validation failures
+func failureCategory(err error) (string, *float64) {
+	var (
+		blocked     *httpclient.BlockedError
+		validation  *ValidationError
+		contentType *httpclient.ContentTypeError
+		redirect    *httpclient.RedirectError
+		netErr      net.Error
+	)
+	switch {
+	case errors.As(err, &blocked):
+		return telemetry.CategoryBlocked, nil
+	case errors.As(err, &validation):
+		return telemetry.CategoryValidation, &validation.Price
+	case errors.As(err, &contentType):
+		return telemetry.CategoryContentType, nil
+	case errors.As(err, &redirect):
+		return telemetry.CategoryRedirect, nil
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return telemetry.CategoryTimeout, nil
+	case errors.As(err, &netErr):
+		return telemetry.CategoryNetwork, nil
+	default:
+		return telemetry.CategoryOther, nil
+	}
+}
+
+// retryable reports whether a failed fetch may succeed when retried later
+// in the run. Blocked pages and rejected samples would only fail again; a
+// price that moved past max_change_pct is accepted across runs instead,
+// once the new level lasts validate.accept_after runs. Request timeouts are
+// retried; nothing is once the run itself has been cancelled or timed out.
+func retryable(ctx context.Context, err error) bool {
+	var (
+		blocked    *httpclient.BlockedError
+		validation *ValidationError
+	)
+	return ctx.Err() == nil && !errors.As(err, &blocked) && !errors.As(err, &validation)
+}
+
+// loadRates refreshes the exchange rates used to normalize samples. Without
+// rates, samples are stored with their listed price only.
+func (t *Tracker) loadRates(ctx context.Context) {
+	t.rates = nil
+	if t.config.FX.NormalizeTo == "" {
+		return
+	}
+
+	var cache fx.Cache
+	if t.storage != nil {
+		cache = t.storage
+	}
+	rates, err := fx.LoadCached(ctx, t.config.FX, cache, false)
+	if err != nil {
+		t.logger.Warn("Failed to load exchange rates, storing prices without normalization", "error", err)
+		return
+	}
+	if rates.Stale {
+		t.logger.Warn("Refreshing exchange rates failed, normalizing with stale cached rates", "fetched", rates.Fetched.Format(time.RFC3339))
+	}
+	t.rates = rates
+}
+
+// normalize records the sample's price converted to fx.normalize_to in its
+// meta, leaving the listed price untouched
+func (t *Tracker) normalize(item config.ItemConfig, sample *Sample) {
+	if t.rates == nil {
+		return
+	}
+
+	currency := strings.ToUpper(t.config.FX.NormalizeTo)
+	normalized, err := t.rates.Convert(sample.Price, sample.Currency, currency)
+	if err != nil {
+		t.logger.Warn("Failed to normalize price", "item", item.ID, "error", err)
+		return
+	}
+	sample.Meta["normalized_price"] = normalized
+	sample.Meta["normalized_currency"] = currency
+}
+
+// normalizedPair returns latest and previous priced in their normalized
+// currency, if both samples were normalized to the same one
+func normalizedPair(latest, previous storage.PriceSample) (storage.PriceSample, storage.PriceSample, bool) {
+	pair := []storage.PriceSample{latest, previous}
+	for i, sample := range pair {
+		price, ok := sample.Meta["normalized_price"].(float64)
+		currency, _ := sample.Meta["normalized_currency"].(string)
+		if !ok || currency == "" || price <= 0 {
+			return latest, previous, false
+		}
+		pair[i].Price = price
+		pair[i].Currency = currency
+	}
+	if pair[0].Currency != pair[1].Currency {
+		return latest, previous, false
+	}
+	return pair[0], pair[1], true
+}
+
+// confirmRuns returns how many consecutive samples must show a drop
+func (t *Tracker) confirmRuns(item config.ItemConfig) int {
+	if item.ConfirmRuns > 0 {
+		return item.ConfirmRuns
+	}
+	return max(t.config.Rules.ConfirmRuns, 1)
+}
+
+// chartSamples is how many stored prices email alerts chart
+const chartSamples = 30
+
+// chartHistory returns the item's recent stored prices, oldest first, for
+// the alert chart; nil when they can't be read
+func (t *Tracker) chartHistory(ctx context.Context, itemID string) []float64 {
+	prices, err := t.storage.GetPrices(ctx, itemID, chartSamples)
+	if err != nil {
+		t.logger.Debug("Failed to get prices for the alert chart", "item", itemID, "error", err)
+		return nil
+	}
+	history := make([]float64, len(prices))
+	for i, p := range prices {
+		history[len(prices)-1-i] = p.Price
+	}
+	return history
+}
+
+// historyLimit returns how many samples alert evaluation needs for item:
+// at least five, and enough for the drop confirmation window plus its baseline
+func (t *Tracker) historyLimit(item config.ItemConfig) int {
+	return max(5, t.confirmRuns(item)+1)
+}
+
+// confirmedDrop compares each of the newest runs samples with the sample
+// before them, the baseline. The drop is confirmed when every one of them
+// is at least threshold percent below it; the newest sample and its drop
+// are returned for the alert.
+func (t *Tracker) confirmedDrop(prices []storage.PriceSample, runs int, threshold float64) (current, baseline storage.PriceSample, drop float64, confirmed bool) {
+	if len(prices) <= runs {
+		return prices[0], prices[len(prices)-1], 0, false
+	}
+	for i := runs - 1; i >= 0; i-- {
+		current, baseline = prices[i], prices[runs]
+		if t.config.FX.NormalizedAlerts {
+			current, baseline, _ = normalizedPair(current, baseline)
+		}
+		drop = ((baseline.Price - current.Price) / baseline.Price) * 100
+		if drop < threshold {
+			return current, baseline, drop, false
+		}
+	}
+	return current, baseline, drop, true
+}
+
+// location returns defaults.timezone, in which active hours are read
+func (t *Tracker) location() *time.Location {
+	loc, err := time.LoadLocation(t.config.Defaults.Timezone)
+	if err != nil {
+		t.logger.Warn("Unknown timezone, using UTC for active hours", "timezone", t.config.Defaults.Timezone)
+		return time.UTC
+	}
+	return loc
+}
+
+// activeAt reports whether now falls inside the item's active hours, falling
+// back to defaults.active_hours. Items without a window are always active.
+func (t *Tracker) activeAt(item config.ItemConfig, now time.Time) (bool, error) {
+	hours := item.ActiveHours
+	if hours == "" {
+		hours = t.config.Defaults.ActiveHours
+	}
+	if hours == "" {
+		return true, nil
+	}
+
+	window, err := scheduler.ParseWindow(hours)
+	if err != nil {
+		return false, fmt.Errorf("invalid active_hours: %w", err)
+	}
+	return window.Contains(now), nil
+}
+
+// CheckAlerts re-evaluates the alert rules of every item inside its active
+// hours against the stored history
 func (t *Tracker) CheckAlerts(ctx context.Context) error {
 	t.logger.Info("Checking price alerts")
 
-	for _, item := range t.config.Items {
+	loc := t.location()
+	for _, item := range t.items(ctx) {
+		active, err := t.activeAt(item, time.Now().In(loc))
+		if err != nil {
+			t.logger.Error("Failed to check alerts for item", "item", item.ID, "error", err)
+			continue
+		}
+		if !active {
+			t.logger.Debug("Outside active hours, not alerting", "item", item.ID)
+			continue
+		}
+
 		if err := t.checkItemAlerts(ctx, item); err != nil {
 			t.logger.Error("Failed to check alerts for item", "item", item.ID, "error", err)
 			continue
@@ -81,24 +777,52 @@ func (t *Tracker) CheckAlerts(ctx context.Context) error {
 }
 
 func (t *Tracker) checkItemAlerts(ctx context.Context, item config.ItemConfig) error {
-	// Get latest price
-	latest, err := t.storage.GetLatestPrice(ctx, item.ID)
-	if err != nil {
-		return fmt.Errorf("failed to get latest price: %w", err)
-	}
-	if latest == nil {
-		return nil // No price data yet
-	}
-
 	// Get recent prices for comparison
-	prices, err := t.storage.GetPrices(ctx, item.ID, 5)
+	prices, err := t.storage.GetPrices(ctx, item.ID, t.historyLimit(item))
 	if err != nil {
 		return fmt.Errorf("failed to get price history: %w", err)
 	}
+
+	_, _, err = t.evaluateAlerts(ctx, item, prices)
+	return err
+}
+
+// evaluateAlerts checks the alert rules against prices, newest first, and
+// returns the rules that triggered and those whose alerts were delivered
+func (t *Tracker) evaluateAlerts(ctx context.Context, item config.ItemConfig, prices []storage.PriceSample) (triggered, sent []string, err error) {
 	if len(prices) < 2 {
-		return nil // Need at least 2 prices for comparison
+		return nil, nil, nil // Need at least 2 prices for comparison
 	}
 
+	// A rule alerts when its condition starts to hold, not on every run
+	// while it does; live --no-store fetches read the stored state but never
+	// change it, like the stored history
+	states := t.alertStates(ctx, item.ID)
+	held := make(map[string]bool)
+	fire := func(alert notifications.Alert) {
+		held[alert.Rule] = true
+		if _, ok := states[alert.Rule]; ok {
+			t.logger.Debug("Alert already sent while the condition holds", "item", item.ID, "rule", alert.Rule)
+			return
+		}
+		triggered = append(triggered, alert.Rule)
+		if t.alertDryRun {
+			t.logger.Info("Alert would be sent", "item", item.ID, "rule", alert.Rule, "channels", strings.Join(t.notifier.Channels(alert.Rule), ","))
+			return
+		}
+		alert.History = t.chartHistory(ctx, item.ID)
+		if t.sendAlert(ctx, alert) {
+			sent = append(sent, alert.Rule)
+			t.saveAlertState(ctx, alert)
+		}
+	}
+	defer func() {
+		if err == nil && !t.alertDryRun {
+			t.clearAlertStates(ctx, item.ID, states, held)
+		}
+	}()
+	latest := prices[0]
+
 	// Check target price alert
 	if item.TargetPrice != nil && latest.Price <= *item.TargetPrice {
 		t.logger.Info("Target price reached", 
@@ -106,7 +830,22 @@ func (t *Tracker) checkItemAlerts(ctx context.Context, item config.ItemConfig) e
 			"current", latest.Price, 
 			"target", *item.TargetPrice,
 		)
-		// TODO: Send notification
+		fire(notifications.Alert{
+			Rule:        notifications.RuleTarget,
+			ItemID:      item.ID,
+			ItemName:    item.Name,
+			URL:         item.URL,
+			Price:       latest.Price,
+			TargetPrice: item.TargetPrice,
+			Currency:    latest.Currency,
+			Time:        latest.Time,
+		})
+	}
+
+	// Relative rules can compare FX-normalized prices instead of listed ones
+	current, previous := latest, prices[1]
+	if t.config.FX.NormalizedAlerts {
+		current, previous, _ = normalizedPair(latest, prices[1])
 	}
 
 	// Check percent drop alert
@@ -116,19 +855,206 @@ func (t *Tracker) checkItemAlerts(ctx context.Context, item config.ItemConfig) e
 	}
 
 	if *percentDrop > 0 {
-		previousPrice := prices[1].Price
-		dropPercent := ((previousPrice - latest.Price) / previousPrice) * 100
-		
-		if dropPercent >= *percentDrop {
+		current, previous, dropPercent, confirmed := t.confirmedDrop(prices, t.confirmRuns(item), *percentDrop)
+		previousPrice := previous.Price
+
+		if confirmed {
 			t.logger.Info("Price drop alert", 
 				"item", item.ID, 
-				"current", latest.Price, 
+				"current", current.Price, 
 				"previous", previousPrice,
 				"drop_percent", dropPercent,
 			)
-			// TODO: Send notification
+			fire(notifications.Alert{
+				Rule:          notifications.RuleDrop,
+				ItemID:        item.ID,
+				ItemName:      item.Name,
+				URL:           item.URL,
+				Price:         current.Price,
+				PreviousPrice: previousPrice,
+				ChangePercent: -dropPercent,
+				Currency:      current.Currency,
+				Time:          current.Time,
+			})
 		}
 	}
 
-	return nil
+	// Check percent rise alert
+	percentRise := item.PercentRise
+	if percentRise == nil {
+		percentRise = &t.config.Rules.PercentRise
+	}
+
+	if *percentRise > 0 {
+		previousPrice := previous.Price
+		risePercent := ((current.Price - previousPrice) / previousPrice) * 100
+
+		if risePercent >= *percentRise {
+			t.logger.Info("Price rise alert",
+				"item", item.ID,
+				"current", current.Price,
+				"previous", previousPrice,
+				"rise_percent", risePercent,
+			)
+			fire(notifications.Alert{
+				Rule:          notifications.RuleRise,
+				ItemID:        item.ID,
+				ItemName:      item.Name,
+				URL:           item.URL,
+				Price:         current.Price,
+				PreviousPrice: previousPrice,
+				ChangePercent: risePercent,
+				Currency:      current.Currency,
+				Time:          current.Time,
+			})
+		}
+	}
+
+	// Check back-above-target alert. The previous sample acts as the
+	// last-known-below flag, so this fires only on the crossing sample.
+	if t.config.Rules.AboveTarget && item.TargetPrice != nil {
+		wasBelow := prices[1].Price <= *item.TargetPrice
+		if wasBelow && latest.Price > *item.TargetPrice {
+			t.logger.Info("Price back above target",
+				"item", item.ID,
+				"current", latest.Price,
+				"target", *item.TargetPrice,
+			)
+			fire(notifications.Alert{
+				Rule:          notifications.RuleAboveTarget,
+				ItemID:        item.ID,
+				ItemName:      item.Name,
+				URL:           item.URL,
+				Price:         latest.Price,
+				PreviousPrice: prices[1].Price,
+				TargetPrice:   item.TargetPrice,
+				Currency:      latest.Currency,
+				Time:          latest.Time,
+			})
+		}
+	}
+
+	// Check velocity alert
+	if rule := t.config.Rules.Velocity; rule.PercentPerDay > 0 {
+		velocity, crossed, err := t.velocityCrossed(ctx, item, prices, rule)
+		if err != nil {
+			return triggered, sent, err
+		}
+		if crossed {
+			t.logger.Info("Price velocity alert",
+				"item", item.ID,
+				"current", latest.Price,
+				"velocity_pct_per_day", velocity,
+				"days", rule.Days,
+			)
+			fire(notifications.Alert{
+				Rule:         notifications.RuleVelocity,
+				ItemID:       item.ID,
+				ItemName:     item.Name,
+				URL:          item.URL,
+				Price:        latest.Price,
+				Currency:     latest.Currency,
+				Time:         latest.Time,
+				Velocity:     velocity,
+				VelocityDays: rule.Days,
+			})
+		}
+	}
+
+	return triggered, sent, nil
+}
+
+// alertStates returns the rules of the item that already alerted while
+// their condition held
+func (t *Tracker) alertStates(ctx context.Context, itemID string) map[string]storage.AlertState {
+	if t.storage == nil {
+		return nil
+	}
+	states, err := t.storage.GetAlertStates(ctx, itemID)
+	if err != nil {
+		t.logger.Warn("Failed to get alert state", "item", itemID, "error", err)
+		return nil
+	}
+	return states
+}
+
+// saveAlertState remembers a delivered alert until its condition clears
+func (t *Tracker) saveAlertState(ctx context.Context, alert notifications.Alert) {
+	if t.noStore || t.storage == nil {
+		return
+	}
+	state := storage.AlertState{ItemID: alert.ItemID, Rule: alert.Rule, Price: alert.Price, Time: time.Now()}
+	if err := t.storage.SaveAlertState(ctx, state); err != nil {
+		t.logger.Warn("Failed to save alert state", "item", alert.ItemID, "rule", alert.Rule, "error", err)
+	}
+}
+
+// clearAlertStates re-arms the rules whose condition no longer holds
+func (t *Tracker) clearAlertStates(ctx context.Context, itemID string, states map[string]storage.AlertState, held map[string]bool) {
+	if t.noStore || t.storage == nil {
+		return
+	}
+	for rule := range states {
+		if held[rule] {
+			continue
+		}
+		if err := t.storage.DeleteAlertState(ctx, itemID, rule); err != nil {
+			t.logger.Warn("Failed to clear alert state", "item", itemID, "rule", rule, "error", err)
+		}
+	}
+}
+
+// velocityCrossed computes the price trend over the rule's window ending at
+// the latest sample and reports whether it has just fallen past the
+// threshold; the window ending at the previous sample must not have been
+// past it already, so a steady slide alerts once
+func (t *Tracker) velocityCrossed(ctx context.Context, item config.ItemConfig, prices []storage.PriceSample, rule config.VelocityRule) (float64, bool, error) {
+	latest := prices[0]
+	window := time.Duration(rule.Days) * 24 * time.Hour
+
+	history, err := t.storage.GetPricesSince(ctx, item.ID, latest.Time.Add(-window))
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to get price history: %w", err)
+	}
+	// A live sample that wasn't stored is newer than anything in history
+	if len(history) == 0 || history[0].Time.Before(latest.Time) {
+		history = append([]storage.PriceSample{latest}, history...)
+	}
+
+	velocity, ok := priceVelocity(history)
+	if !ok || velocity > -rule.PercentPerDay {
+		return velocity, false, nil
+	}
+
+	previous, ok := priceVelocity(history[1:])
+	return velocity, !ok || previous > -rule.PercentPerDay, nil
+}
+
+// priceVelocity returns the least-squares trend of samples (newest first)
+// in percent of their average price per day. It needs at least three
+// samples spanning a day.
+func priceVelocity(samples []storage.PriceSample) (float64, bool) {
+	if len(samples) < 3 {
+		return 0, false
+	}
+	oldest := samples[len(samples)-1].Time
+	if samples[0].Time.Sub(oldest) < 24*time.Hour {
+		return 0, false
+	}
+
+	xs := make([]float64, len(samples))
+	ys := make([]float64, len(samples))
+	var sum float64
+	for i, sample := range samples {
+		xs[i] = sample.Time.Sub(oldest).Hours() / 24
+		ys[i] = sample.Price
+		sum += sample.Price
+	}
+
+	slope, _, ok := utils.LinearTrend(xs, ys)
+	mean := sum / float64(len(samples))
+	if !ok || mean == 0 {
+		return 0, false
+	}
+	return slope / mean * 100, true
 }
\ No newline at end of file
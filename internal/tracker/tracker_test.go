@@ -0,0 +1,336 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/config"
+	"github.com/makalin/pricetrek/internal/httpclient"
+	"github.com/makalin/pricetrek/internal/logger"
+	"github.com/makalin/pricetrek/internal/notifications"
+	"github.com/makalin/pricetrek/internal/storage"
+	"github.com/makalin/pricetrek/internal/telemetry"
+)
+
+// newTestTracker returns a tracker for cfg backed by a fresh database
+func newTestTracker(t *testing.T, cfg *config.Config) (*Tracker, storage.Storage) {
+	t.Helper()
+	if cfg == nil {
+		cfg = config.Default()
+	}
+	store, err := storage.New(config.StorageConfig{Driver: "sqlite", Path: filepath.Join(t.TempDir(), "trek.db")})
+	if err != nil {
+		t.Fatalf("storage.New: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	if err := store.Init(); err != nil {
+		t.Fatalf("Init: %v", err)
+	}
+	return New(cfg, store, logger.New(false)), store
+}
+
+func TestRetryable(t *testing.T) {
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want bool
+	}{
+		{"network error", context.Background(), &net.OpError{Op: "dial", Err: errors.New("refused")}, true},
+		{"request timeout", context.Background(), fmt.Errorf("failed to fetch price: %w", context.DeadlineExceeded), true},
+		{"blocked page", context.Background(), &httpclient.BlockedError{Marker: "captcha", Err: errors.New("no price")}, false},
+		{"rejected sample", context.Background(), &ValidationError{Reason: "price 0 is not positive"}, false},
+		{"rejected in a chain", context.Background(), &chainError{providers: []string{"generic"}, errs: []error{&ValidationError{}}}, false},
+		{"run cancelled", cancelled, fmt.Errorf("failed to fetch price: %w", context.Canceled), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryable(tt.ctx, tt.err); got != tt.want {
+				t.Errorf("retryable(%v) = %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFailureCategory(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		want      string
+		wantPrice bool
+	}{
+		{"blocked", &httpclient.BlockedError{Marker: "captcha"}, telemetry.CategoryBlocked, false},
+		{"validation", &ValidationError{Price: 5}, telemetry.CategoryValidation, true},
+		{"content type", &httpclient.ContentTypeError{Got: "text/plain"}, telemetry.CategoryContentType, false},
+		{"redirect", fmt.Errorf("failed to fetch price: %w", &httpclient.RedirectError{Hops: 11}), telemetry.CategoryRedirect, false},
+		{"deadline", fmt.Errorf("failed to fetch price: %w", context.DeadlineExceeded), telemetry.CategoryTimeout, false},
+		{"network timeout", &net.DNSError{Err: "timeout", IsTimeout: true}, telemetry.CategoryTimeout, false},
+		{"network", &net.OpError{Op: "dial", Err: errors.New("refused")}, telemetry.CategoryNetwork, false},
+		{"other", errors.New("selector matched nothing"), telemetry.CategoryOther, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, price := failureCategory(tt.err)
+			if got != tt.want {
+				t.Errorf("failureCategory() = %q, want %q", got, tt.want)
+			}
+			if (price != nil) != tt.wantPrice {
+				t.Errorf("failureCategory() price = %v, want price %v", price, tt.wantPrice)
+			}
+		})
+	}
+}
+
+func TestEvaluateAlertsTriggeredWithoutChannels(t *testing.T) {
+	now := time.Now()
+	tests := []struct {
+		name   string
+		item   config.ItemConfig
+		prices []float64
+		want   []string
+	}{
+		{"nothing triggered", config.ItemConfig{ID: "a"}, []float64{100, 100}, nil},
+		{"target reached", config.ItemConfig{ID: "a", TargetPrice: float(90)}, []float64{90, 95}, []string{notifications.RuleTarget}},
+		{"drop past threshold", config.ItemConfig{ID: "a", PercentDrop: float(10)}, []float64{80, 100}, []string{notifications.RuleDrop}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, _ := newTestTracker(t, nil)
+			prices := make([]storage.PriceSample, len(tt.prices))
+			for i, price := range tt.prices {
+				prices[i] = storage.PriceSample{ItemID: "a", Time: now.Add(-time.Duration(i) * time.Hour), Price: price, Currency: "USD"}
+			}
+
+			triggered, sent, err := tr.evaluateAlerts(context.Background(), tt.item, prices)
+			if err != nil {
+				t.Fatalf("evaluateAlerts: %v", err)
+			}
+			if !reflect.DeepEqual(triggered, tt.want) {
+				t.Errorf("triggered = %v, want %v", triggered, tt.want)
+			}
+			if len(sent) != 0 {
+				t.Errorf("sent = %v with no channel configured", sent)
+			}
+		})
+	}
+}
+
+func TestTargetAlertsOncePerCrossing(t *testing.T) {
+	cfg := config.Default()
+	cfg.Notifications.Exec = config.ExecConfig{Enabled: true, Command: "exit 0"}
+	tr, _ := newTestTracker(t, cfg)
+	item := config.ItemConfig{ID: "a", TargetPrice: float(90), PercentDrop: float(50)}
+	stored := storage.PriceSample{ItemID: "a", Time: time.Now().Add(-time.Hour), Price: 95, Currency: "USD"}
+
+	// Live samples checked against the same stored history, as --no-store does
+	tests := []struct {
+		price float64
+		want  []string
+	}{
+		{85, []string{notifications.RuleTarget}},
+		{85, nil},
+		{80, nil},
+		{95, nil},
+		{85, []string{notifications.RuleTarget}},
+	}
+
+	for i, tt := range tests {
+		live := storage.PriceSample{ItemID: "a", Time: time.Now(), Price: tt.price, Currency: "USD"}
+		_, sent, err := tr.evaluateAlerts(context.Background(), item, []storage.PriceSample{live, stored})
+		if err != nil {
+			t.Fatalf("run %d: evaluateAlerts: %v", i, err)
+		}
+		if !reflect.DeepEqual(sent, tt.want) {
+			t.Errorf("run %d at %v: sent = %v, want %v", i, tt.price, sent, tt.want)
+		}
+	}
+}
+
+func TestRespectCache(t *testing.T) {
+	tests := []struct {
+		name    string
+		age     time.Duration
+		ttl     time.Duration
+		sampled bool
+		want    bool
+	}{
+		{name: "fresh sample", age: 10 * time.Minute, ttl: 30 * time.Minute, sampled: true, want: true},
+		{name: "stale sample", age: time.Hour, ttl: 30 * time.Minute, sampled: true},
+		{name: "never sampled", ttl: 30 * time.Minute},
+		{name: "cache not respected", age: time.Minute, sampled: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tr, store := newTestTracker(t, nil)
+			tr.RespectCache(tt.ttl)
+			if tt.sampled {
+				sample := storage.PriceSample{ItemID: "a", Time: time.Now().Add(-tt.age), Price: 10, Currency: "USD"}
+				if _, err := store.MergePrices(ctx, []storage.PriceSample{sample}); err != nil {
+					t.Fatalf("MergePrices: %v", err)
+				}
+			}
+
+			if got := tr.fresh(ctx, config.ItemConfig{ID: "a"}); got != tt.want {
+				t.Errorf("fresh = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Fresh items are counted as skipped without being fetched
+	tr, store := newTestTracker(t, nil)
+	tr.RespectCache(time.Hour)
+	if err := store.SavePrice(context.Background(), "a", 10, "USD", nil); err != nil {
+		t.Fatalf("SavePrice: %v", err)
+	}
+	result := tr.TrackItems(context.Background(), []config.ItemConfig{{ID: "a", URL: "http://127.0.0.1:1/a"}})
+	if result.Skipped != 1 || result.Attempted != 0 {
+		t.Errorf("TrackItems = %d skipped, %d attempted; want 1 skipped, 0 attempted", result.Skipped, result.Attempted)
+	}
+}
+
+func TestCheckAlertsActiveHours(t *testing.T) {
+	hour := time.Now().UTC().Hour()
+	window := func(from, to int) string {
+		return fmt.Sprintf("%02d:00-%02d:00", (hour+from)%24, (hour+to)%24)
+	}
+
+	tests := []struct {
+		name        string
+		activeHours string
+		dryRun      bool
+		want        bool
+	}{
+		{name: "no window", want: true},
+		{name: "inside the window", activeHours: window(23, 1), want: true},
+		{name: "outside the window", activeHours: window(2, 3)},
+		{name: "dry run", dryRun: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cfg := config.Default()
+			cfg.Defaults.Timezone = "UTC"
+			cfg.Notifications.Exec = config.ExecConfig{Enabled: true, Command: "exit 0"}
+			cfg.Items = []config.ItemConfig{{ID: "a", URL: "https://example.com/a", TargetPrice: float(90), PercentDrop: float(50), ActiveHours: tt.activeHours}}
+			tr, store := newTestTracker(t, cfg)
+			if tt.dryRun {
+				tr.DryRunAlerts()
+			}
+			_, err := store.MergePrices(ctx, []storage.PriceSample{
+				{ItemID: "a", Time: time.Now().Add(-time.Hour), Price: 95, Currency: "USD"},
+				{ItemID: "a", Time: time.Now(), Price: 85, Currency: "USD"},
+			})
+			if err != nil {
+				t.Fatalf("MergePrices: %v", err)
+			}
+
+			if err := tr.CheckAlerts(ctx); err != nil {
+				t.Fatalf("CheckAlerts: %v", err)
+			}
+
+			states, err := store.GetAlertStates(ctx, "a")
+			if err != nil {
+				t.Fatalf("GetAlertStates: %v", err)
+			}
+			if _, sent := states[notifications.RuleTarget]; sent != tt.want {
+				t.Errorf("target alert sent = %v, want %v", sent, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfirmedDrop(t *testing.T) {
+	tests := []struct {
+		name          string
+		prices        []float64 // newest first
+		runs          int
+		wantDrop      float64
+		wantConfirmed bool
+	}{
+		{name: "single run drop", prices: []float64{90, 100}, runs: 1, wantDrop: 10, wantConfirmed: true},
+		{name: "single run below the threshold", prices: []float64{95, 100}, runs: 1, wantDrop: 5},
+		{name: "drop held over two runs", prices: []float64{90, 91, 100}, runs: 2, wantDrop: 10, wantConfirmed: true},
+		{name: "drop only in the newest run", prices: []float64{90, 100, 100}, runs: 2, wantDrop: 0},
+		{name: "one-scrape glitch recovered", prices: []float64{100, 90, 100}, runs: 2, wantDrop: 0},
+		{name: "not enough samples", prices: []float64{90, 100}, runs: 2, wantDrop: 0},
+		{name: "exactly the threshold", prices: []float64{92, 100}, runs: 1, wantDrop: 8, wantConfirmed: true},
+	}
+
+	tr := &Tracker{config: config.Default()}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var prices []storage.PriceSample
+			for i, price := range tt.prices {
+				prices = append(prices, storage.PriceSample{ItemID: "a", Time: time.Now().Add(-time.Duration(i) * time.Hour), Price: price, Currency: "USD"})
+			}
+
+			current, _, drop, confirmed := tr.confirmedDrop(prices, tt.runs, 8)
+			if confirmed != tt.wantConfirmed || drop != tt.wantDrop {
+				t.Errorf("confirmedDrop = %v%%, %v; want %v%%, %v", drop, confirmed, tt.wantDrop, tt.wantConfirmed)
+			}
+			if confirmed && current.Price != tt.prices[0] {
+				t.Errorf("confirmed drop reports %v, want the newest price %v", current.Price, tt.prices[0])
+			}
+		})
+	}
+}
+
+func TestNoStoreKeepsAlertState(t *testing.T) {
+	tests := []struct {
+		name      string
+		price     float64
+		wantRules []string
+	}{
+		// The target alert re-arms when the price climbs back over it
+		{name: "condition cleared", price: 95, wantRules: []string{notifications.RuleTarget}},
+		// A new drop alert is sent but not remembered
+		{name: "new alert sent", price: 40, wantRules: []string{notifications.RuleTarget}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cfg := config.Default()
+			cfg.Notifications.Exec = config.ExecConfig{Enabled: true, Command: "exit 0"}
+			tr, store := newTestTracker(t, cfg)
+			tr.DisableStore()
+			item := config.ItemConfig{ID: "a", TargetPrice: float(90), PercentDrop: float(50)}
+			if err := store.SaveAlertState(ctx, storage.AlertState{ItemID: "a", Rule: notifications.RuleTarget, Price: 85, Time: time.Now()}); err != nil {
+				t.Fatalf("SaveAlertState: %v", err)
+			}
+
+			stored := storage.PriceSample{ItemID: "a", Time: time.Now().Add(-time.Hour), Price: 100, Currency: "USD"}
+			live := storage.PriceSample{ItemID: "a", Time: time.Now(), Price: tt.price, Currency: "USD"}
+			if _, _, err := tr.evaluateAlerts(ctx, item, []storage.PriceSample{live, stored}); err != nil {
+				t.Fatalf("evaluateAlerts: %v", err)
+			}
+
+			states, err := store.GetAlertStates(ctx, "a")
+			if err != nil {
+				t.Fatalf("GetAlertStates: %v", err)
+			}
+			var rules []string
+			for rule := range states {
+				rules = append(rules, rule)
+			}
+			if !reflect.DeepEqual(rules, tt.wantRules) {
+				t.Errorf("stored alert state = %v, want %v", rules, tt.wantRules)
+			}
+		})
+	}
+}
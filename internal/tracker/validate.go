@@ -0,0 +1,155 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/makalin/pricetrek/internal/config"
+	"github.com/makalin/pricetrek/internal/storage"
+	"github.com/makalin/pricetrek/internal/utils"
+)
+
+// ValidationError is a fetched sample rejected by the item's validation
+// rules
+type ValidationError struct {
+	Reason string
+	// Price and Currency are the rejected sample's
+	Price    float64
+	Currency string
+	// LevelChange marks a price that moved more than max_change_pct; it is
+	// accepted once the new level lasts validate.accept_after runs
+	LevelChange bool
+}
+
+func (e *ValidationError) Error() string {
+	return "sample rejected: " + e.Reason
+}
+
+// defaultAcceptAfter is the validate.accept_after used when it is unset
+const defaultAcceptAfter = 3
+
+// validateRules returns the item's validation rules, falling back to
+// defaults.validate
+func (t *Tracker) validateRules(item config.ItemConfig) config.ValidateConfig {
+	if item.Validate != nil {
+		return *item.Validate
+	}
+	return t.config.Defaults.Validate
+}
+
+// validateSample checks a fetched sample against the item's validation
+// rules, falling back to defaults.validate. The price must be positive;
+// bounds, the page currency and the change from the last stored sample are
+// checked when configured. A change over max_change_pct passes once the
+// same new level has been rejected in the runs before (see trackLevelChange).
+func (t *Tracker) validateSample(ctx context.Context, item config.ItemConfig, sample *Sample) error {
+	price := sample.Price
+	if price <= 0 || math.IsInf(price, 0) || math.IsNaN(price) {
+		return &ValidationError{Reason: fmt.Sprintf("price %v is not positive", price), Price: price}
+	}
+
+	rules := t.validateRules(item)
+
+	currency := sample.Currency
+	if currency == "" {
+		currency = item.Currency
+	}
+	if rules.MinPrice != nil && price < *rules.MinPrice {
+		return &ValidationError{Reason: fmt.Sprintf("price %s is below min_price %s", utils.FormatAmount(price, currency), utils.FormatAmount(*rules.MinPrice, currency)), Price: price}
+	}
+	if rules.MaxPrice != nil && price > *rules.MaxPrice {
+		return &ValidationError{Reason: fmt.Sprintf("price %s is above max_price %s", utils.FormatAmount(price, currency), utils.FormatAmount(*rules.MaxPrice, currency)), Price: price}
+	}
+	if rules.Currency && item.Currency != "" && !strings.EqualFold(currency, item.Currency) {
+		return &ValidationError{Reason: fmt.Sprintf("page currency %s differs from configured %s", currency, item.Currency), Price: price}
+	}
+
+	if rules.MaxChange > 0 && t.storage != nil {
+		last, err := t.storage.GetLatestPrice(ctx, item.ID)
+		if err != nil {
+			t.logger.Warn("Failed to get last price, skipping the change check", "item", item.ID, "error", err)
+			return nil
+		}
+		// Only prices in the same currency compare
+		if last != nil && last.Price > 0 && strings.EqualFold(last.Currency, currency) {
+			change := utils.CalculatePriceChange(last.Price, price)
+			if math.Abs(change) > rules.MaxChange && !t.levelConfirmed(ctx, item, rules, price, currency) {
+				return &ValidationError{
+					Reason:      fmt.Sprintf("price moved %+.1f%% from the last stored %s, more than max_change_pct %g", change, utils.FormatPrice(last.Price, last.Currency), rules.MaxChange),
+					Price:       price,
+					Currency:    currency,
+					LevelChange: true,
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// acceptAfter returns how many runs in a row a new price level must be seen
+func acceptAfter(rules config.ValidateConfig) int {
+	if rules.AcceptAfter > 0 {
+		return rules.AcceptAfter
+	}
+	return defaultAcceptAfter
+}
+
+// sameLevel reports whether price is within max_change_pct of a pending level
+func sameLevel(level *storage.PendingLevel, rules config.ValidateConfig, price float64, currency string) bool {
+	return level != nil && level.Price > 0 && strings.EqualFold(level.Currency, currency) &&
+		math.Abs(utils.CalculatePriceChange(level.Price, price)) <= rules.MaxChange
+}
+
+// levelConfirmed reports whether price completes a pending level that has
+// been rejected in the accept_after-1 runs before
+func (t *Tracker) levelConfirmed(ctx context.Context, item config.ItemConfig, rules config.ValidateConfig, price float64, currency string) bool {
+	if acceptAfter(rules) <= 1 {
+		return true
+	}
+	level, err := t.storage.GetPendingLevel(ctx, item.ID)
+	if err != nil {
+		t.logger.Warn("Failed to get pending price level", "item", item.ID, "error", err)
+		return false
+	}
+	if !sameLevel(level, rules, price, currency) || level.Runs+1 < acceptAfter(rules) {
+		return false
+	}
+	t.logger.Info("Accepting new price level", "item", item.ID, "price", price, "runs", level.Runs+1, "since", level.Since)
+	return true
+}
+
+// trackLevelChange records a sample rejected for moving more than
+// max_change_pct, counting the runs in a row that agree on the new level
+func (t *Tracker) trackLevelChange(ctx context.Context, item config.ItemConfig, fetchErr error) {
+	var validation *ValidationError
+	if !errors.As(fetchErr, &validation) || !validation.LevelChange || t.noStore || t.storage == nil {
+		return
+	}
+
+	rules := t.validateRules(item)
+	pending, err := t.storage.GetPendingLevel(ctx, item.ID)
+	if err != nil {
+		t.logger.Warn("Failed to get pending price level", "item", item.ID, "error", err)
+		return
+	}
+	level := storage.PendingLevel{
+		ItemID:   item.ID,
+		Price:    validation.Price,
+		Currency: validation.Currency,
+		Runs:     1,
+		Since:    time.Now(),
+	}
+	if sameLevel(pending, rules, validation.Price, validation.Currency) {
+		level.Runs = pending.Runs + 1
+		level.Since = pending.Since
+	}
+	if err := t.storage.SavePendingLevel(ctx, level); err != nil {
+		t.logger.Warn("Failed to save pending price level", "item", item.ID, "error", err)
+		return
+	}
+	t.logger.Info("New price level pending confirmation", "item", item.ID, "price", level.Price, "runs", level.Runs, "accept_after", acceptAfter(rules))
+}
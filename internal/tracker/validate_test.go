@@ -0,0 +1,127 @@
+package tracker
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/makalin/pricetrek/internal/config"
+	"github.com/makalin/pricetrek/internal/storage"
+)
+
+func TestValidateSample(t *testing.T) {
+	tests := []struct {
+		name        string
+		rules       config.ValidateConfig
+		last        float64
+		price       float64
+		currency    string
+		wantErr     bool
+		levelChange bool
+	}{
+		{name: "valid price", price: 10},
+		{name: "zero price", price: 0, wantErr: true},
+		{name: "negative price", price: -1, wantErr: true},
+		{name: "below min price", rules: config.ValidateConfig{MinPrice: float(5)}, price: 4, wantErr: true},
+		{name: "above max price", rules: config.ValidateConfig{MaxPrice: float(5)}, price: 6, wantErr: true},
+		{name: "currency mismatch", rules: config.ValidateConfig{Currency: true}, price: 10, currency: "EUR", wantErr: true},
+		{name: "change within limit", rules: config.ValidateConfig{MaxChange: 50}, last: 100, price: 140},
+		{name: "change over limit", rules: config.ValidateConfig{MaxChange: 50}, last: 100, price: 10, wantErr: true, levelChange: true},
+		{name: "change in another currency", rules: config.ValidateConfig{MaxChange: 50}, last: 100, price: 10, currency: "EUR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tr, store := newTestTracker(t, nil)
+			if tt.last > 0 {
+				if err := store.SavePrice(ctx, "a", tt.last, "USD", nil); err != nil {
+					t.Fatalf("SavePrice: %v", err)
+				}
+			}
+			currency := tt.currency
+			if currency == "" {
+				currency = "USD"
+			}
+			rules := tt.rules
+			item := config.ItemConfig{ID: "a", Currency: "USD", Validate: &rules}
+
+			err := tr.validateSample(ctx, item, &Sample{Price: tt.price, Currency: currency})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validateSample() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var validation *ValidationError
+			if err != nil && (!errors.As(err, &validation) || validation.LevelChange != tt.levelChange) {
+				t.Errorf("validateSample() error = %#v, want level change %v", err, tt.levelChange)
+			}
+		})
+	}
+}
+
+func TestLevelChangeAcceptedAfterRuns(t *testing.T) {
+	tests := []struct {
+		name        string
+		acceptAfter int
+		prices      []float64
+		// accepted is the index of the first price accepted, or -1
+		accepted int
+	}{
+		{"default needs three runs", 0, []float64{50, 50, 50}, 2},
+		{"one run accepts immediately", 1, []float64{50}, 0},
+		{"small moves still agree", 2, []float64{50, 52}, 1},
+		{"a different level restarts the count", 2, []float64{50, 20, 20}, 2},
+		{"a glitch is never accepted", 3, []float64{50, 20, 50}, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			cfg := config.Default()
+			cfg.Defaults.Validate = config.ValidateConfig{MaxChange: 20, AcceptAfter: tt.acceptAfter}
+			tr, store := newTestTracker(t, cfg)
+			if err := store.SavePrice(ctx, "a", 100, "USD", nil); err != nil {
+				t.Fatalf("SavePrice: %v", err)
+			}
+			item := config.ItemConfig{ID: "a", Currency: "USD"}
+
+			accepted := -1
+			for i, price := range tt.prices {
+				err := tr.validateSample(ctx, item, &Sample{Price: price, Currency: "USD"})
+				if err == nil {
+					accepted = i
+					break
+				}
+				tr.trackLevelChange(ctx, item, err)
+			}
+			if accepted != tt.accepted {
+				t.Errorf("first accepted sample = %d, want %d", accepted, tt.accepted)
+			}
+		})
+	}
+}
+
+func TestSameLevel(t *testing.T) {
+	rules := config.ValidateConfig{MaxChange: 10}
+	level := &storage.PendingLevel{Price: 100, Currency: "USD"}
+	tests := []struct {
+		name     string
+		level    *storage.PendingLevel
+		price    float64
+		currency string
+		want     bool
+	}{
+		{"no pending level", nil, 100, "USD", false},
+		{"same price", level, 100, "USD", true},
+		{"within max change", level, 109, "USD", true},
+		{"over max change", level, 111, "USD", false},
+		{"other currency", level, 100, "EUR", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameLevel(tt.level, rules, tt.price, tt.currency); got != tt.want {
+				t.Errorf("sameLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
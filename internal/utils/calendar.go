@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// RenderCalendar draws a month-by-day grid of daily price changes, keyed by
+// local date (2006-01-02) as percent change from the previous close. Each
+// day is marked + when pricier (red), - when cheaper (green) and = when
+// unchanged; days without data are left unmarked. Months run from the first
+// to the last day in changes.
+func RenderCalendar(changes map[string]float64) string {
+	if len(changes) == 0 {
+		return ""
+	}
+
+	dates := make([]string, 0, len(changes))
+	for date := range changes {
+		dates = append(dates, date)
+	}
+	sort.Strings(dates)
+	first, err := time.ParseInLocation("2006-01-02", dates[0], time.Local)
+	if err != nil {
+		return ""
+	}
+	last, err := time.ParseInLocation("2006-01-02", dates[len(dates)-1], time.Local)
+	if err != nil {
+		return ""
+	}
+
+	var b strings.Builder
+	month := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.Local)
+	for !month.After(last) {
+		fmt.Fprintf(&b, "%s\n", month.Format("January 2006"))
+		b.WriteString("Mo  Tu  We  Th  Fr  Sa  Su\n")
+
+		// Pad to the month's first weekday, Monday first
+		column := (int(month.Weekday()) + 6) % 7
+		if column > 0 {
+			b.WriteString(strings.Repeat(" ", column*4-1))
+		}
+
+		for day := month; day.Month() == month.Month(); day = day.AddDate(0, 0, 1) {
+			if column > 0 {
+				b.WriteString(" ")
+			}
+			b.WriteString(calendarCell(day, changes))
+			column++
+			if column == 7 {
+				b.WriteString("\n")
+				column = 0
+			}
+		}
+		if column != 0 {
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+
+		month = month.AddDate(0, 1, 0)
+	}
+
+	b.WriteString("Legend: + pricier than the previous close, - cheaper, = unchanged, unmarked: no data")
+	return b.String()
+}
+
+// calendarCell renders one 3-column day of the calendar
+func calendarCell(day time.Time, changes map[string]float64) string {
+	change, ok := changes[day.Format("2006-01-02")]
+	switch {
+	case !ok:
+		return fmt.Sprintf("%2d ", day.Day())
+	case change > 0:
+		return Colorize(ColorRed, fmt.Sprintf("%2d+", day.Day()))
+	case change < 0:
+		return Colorize(ColorGreen, fmt.Sprintf("%2d-", day.Day()))
+	default:
+		return fmt.Sprintf("%2d=", day.Day())
+	}
+}
@@ -0,0 +1,96 @@
+package utils
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// Colors of the PNG price chart
+var (
+	chartBackground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	chartAxis       = color.RGBA{0xcc, 0xcc, 0xcc, 0xff}
+	chartLine       = color.RGBA{0x1f, 0x77, 0xb4, 0xff}
+)
+
+// PriceChartPNG draws prices, oldest first, as a line chart and returns it
+// PNG encoded. It returns nil for fewer than two prices or an empty size.
+func PriceChartPNG(prices []float64, width, height int) ([]byte, error) {
+	if len(prices) < 2 || width <= 0 || height <= 0 {
+		return nil, nil
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			img.Set(x, y, chartBackground)
+		}
+	}
+
+	// Leave a margin so the line never touches the border
+	const margin = 4
+	plotWidth, plotHeight := max(width-2*margin, 1), max(height-2*margin, 1)
+	for x := 0; x < width; x++ {
+		img.Set(x, height-margin, chartAxis)
+	}
+
+	low, high := findMinMax(prices)
+	point := func(i int) (int, int) {
+		x := margin + i*(plotWidth-1)/(len(prices)-1)
+		y := margin + plotHeight/2
+		if high > low {
+			y = margin + int((high-prices[i])/(high-low)*float64(plotHeight-1))
+		}
+		return x, y
+	}
+
+	x0, y0 := point(0)
+	for i := 1; i < len(prices); i++ {
+		x1, y1 := point(i)
+		drawLine(img, x0, y0, x1, y1, chartLine)
+		x0, y0 = x1, y1
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// drawLine draws a two pixel thick line with Bresenham's algorithm
+func drawLine(img *image.RGBA, x0, y0, x1, y1 int, c color.Color) {
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := 1, 1
+	if x0 > x1 {
+		sx = -1
+	}
+	if y0 > y1 {
+		sy = -1
+	}
+	diff := dx + dy
+	for {
+		img.Set(x0, y0, c)
+		img.Set(x0, y0+1, c)
+		if x0 == x1 && y0 == y1 {
+			return
+		}
+		e2 := 2 * diff
+		if e2 >= dy {
+			diff += dy
+			x0 += sx
+		}
+		if e2 <= dx {
+			diff += dx
+			y0 += sy
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
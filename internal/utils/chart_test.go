@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+)
+
+func TestPriceChartPNG(t *testing.T) {
+	tests := []struct {
+		name          string
+		prices        []float64
+		width, height int
+		wantImage     bool
+	}{
+		{name: "falling prices", prices: []float64{120, 110, 115, 99}, width: 480, height: 160, wantImage: true},
+		{name: "flat prices", prices: []float64{10, 10, 10}, width: 100, height: 40, wantImage: true},
+		{name: "more prices than pixels", prices: make([]float64, 500), width: 50, height: 20, wantImage: true},
+		{name: "single price", prices: []float64{10}, width: 100, height: 40},
+		{name: "no size", prices: []float64{10, 12}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := PriceChartPNG(tt.prices, tt.width, tt.height)
+			if err != nil {
+				t.Fatalf("PriceChartPNG: %v", err)
+			}
+			if !tt.wantImage {
+				if data != nil {
+					t.Errorf("PriceChartPNG returned %d bytes, want none", len(data))
+				}
+				return
+			}
+			img, err := png.Decode(bytes.NewReader(data))
+			if err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if size := img.Bounds().Size(); size.X != tt.width || size.Y != tt.height {
+				t.Errorf("chart is %dx%d, want %dx%d", size.X, size.Y, tt.width, tt.height)
+			}
+		})
+	}
+}
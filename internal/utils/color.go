@@ -0,0 +1,47 @@
+package utils
+
+import "os"
+
+// ANSI colors for terminal output
+const (
+	ColorRed    = "31"
+	ColorGreen  = "32"
+	ColorYellow = "33"
+)
+
+// colorEnabled is off until SetColor turns it on, so library use and tests
+// never see escape codes
+var colorEnabled bool
+
+// SetColor turns colored output on or off for Colorize
+func SetColor(enabled bool) {
+	colorEnabled = enabled
+}
+
+// ShouldColor reports whether output to f should be colored: never with
+// --no-color or a non-empty NO_COLOR (https://no-color.org), and only when f
+// is a terminal
+func ShouldColor(noColor bool, f *os.File) bool {
+	if noColor || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	return IsTerminal(f)
+}
+
+// IsTerminal reports whether f is a terminal rather than a file or pipe
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// Colorize wraps s in the given ANSI color when color is enabled. Pad s
+// before coloring it, since escape codes count towards fmt widths.
+func Colorize(color, s string) string {
+	if !colorEnabled || color == "" {
+		return s
+	}
+	return "\x1b[" + color + "m" + s + "\x1b[0m"
+}
@@ -0,0 +1,91 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// iso4217 holds the active ISO 4217 currency codes, including the funds and
+// precious-metal codes stores occasionally quote in
+var iso4217 = map[string]bool{}
+
+func init() {
+	for _, code := range strings.Fields(`
+		AED AFN ALL AMD ANG AOA ARS AUD AWG AZN BAM BBD BDT BGN BHD BIF BMD BND
+		BOB BOV BRL BSD BTN BWP BYN BZD CAD CDF CHE CHF CHW CLF CLP CNY COP COU
+		CRC CUC CUP CVE CZK DJF DKK DOP DZD EGP ERN ETB EUR FJD FKP GBP GEL GHS
+		GIP GMD GNF GTQ GYD HKD HNL HTG HUF IDR ILS INR IQD IRR ISK JMD JOD JPY
+		KES KGS KHR KMF KPW KRW KWD KYD KZT LAK LBP LKR LRD LSL LYD MAD MDL MGA
+		MKD MMK MNT MOP MRU MUR MVR MWK MXN MXV MYR MZN NAD NGN NIO NOK NPR NZD
+		OMR PAB PEN PGK PHP PKR PLN PYG QAR RON RSD RUB RWF SAR SBD SCR SDG SEK
+		SGD SHP SLE SLL SOS SRD SSP STN SVC SYP SZL THB TJS TMT TND TOP TRY TTD
+		TWD TZS UAH UGX USD USN UYI UYU UYW UZS VED VES VND VUV WST XAF XAG XAU
+		XBA XBB XBC XBD XCD XCG XDR XOF XPD XPF XPT XSU XTS XUA XXX YER ZAR ZMW
+		ZWG ZWL
+	`) {
+		iso4217[code] = true
+	}
+}
+
+// IsISOCurrency reports whether code is an ISO 4217 currency code, in any
+// letter case
+func IsISOCurrency(code string) bool {
+	return iso4217[strings.ToUpper(code)]
+}
+
+// ValidateCurrency rejects codes outside ISO 4217, suggesting the closest
+// known codes
+func ValidateCurrency(code string) error {
+	if IsISOCurrency(code) {
+		return nil
+	}
+	if suggestions := similarCurrencies(strings.ToUpper(code)); len(suggestions) > 0 {
+		return fmt.Errorf("unknown currency %q (did you mean %s?)", code, strings.Join(suggestions, ", "))
+	}
+	return fmt.Errorf("unknown currency %q: not an ISO 4217 code", code)
+}
+
+// similarCurrencies returns up to three known codes one edit away from code,
+// or two edits away when none is closer
+func similarCurrencies(code string) []string {
+	byDistance := make(map[int][]string)
+	for known := range iso4217 {
+		if d := editDistance(code, known); d <= 2 {
+			byDistance[d] = append(byDistance[d], known)
+		}
+	}
+	for d := 1; d <= 2; d++ {
+		if matches := byDistance[d]; len(matches) > 0 {
+			slices.Sort(matches)
+			return matches[:min(len(matches), 3)]
+		}
+	}
+	return nil
+}
+
+// editDistance counts the insertions, deletions, substitutions and swaps of
+// adjacent letters turning a into b, so "UDS" is one edit from "USD"
+func editDistance(a, b string) int {
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestEditDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"USD", "USD", 0},
+		{"UDS", "USD", 1},  // swap
+		{"USS", "USD", 1},  // substitution
+		{"USDD", "USD", 1}, // insertion
+		{"US", "USD", 1},   // deletion
+		{"DSU", "USD", 2},
+		{"EURO", "EUR", 1},
+		{"", "USD", 3},
+		{"ABC", "XYZ", 3},
+	}
+
+	for _, tt := range tests {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := editDistance(tt.b, tt.a); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarCurrencies(t *testing.T) {
+	tests := []struct {
+		code string
+		want []string
+	}{
+		{"USDD", []string{"USD"}},
+		{"EURO", []string{"EUR"}},
+		{"UDS", []string{"USD", "UZS"}},
+		{"TRL", []string{"BRL", "TRY"}},
+		// At most three are listed
+		{"XBX", []string{"XBA", "XBB", "XBC"}},
+		{"QQQQQQ", nil},
+	}
+
+	for _, tt := range tests {
+		got := similarCurrencies(tt.code)
+		if len(got) > 3 {
+			t.Errorf("similarCurrencies(%q) = %v, want at most three", tt.code, got)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("similarCurrencies(%q) = %v, want %v", tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestValidateCurrency(t *testing.T) {
+	tests := []struct {
+		code    string
+		wantErr bool
+	}{
+		{code: "USD"},
+		{code: "try"},
+		{code: "XAU"},
+		{code: "USDD", wantErr: true},
+		{code: "BTC", wantErr: true},
+		{code: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		if err := ValidateCurrency(tt.code); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateCurrency(%q) error = %v, want error %v", tt.code, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// CompactJSON marshals v onto a single line, dropping object fields that are
+// null, empty strings, empty arrays or empty objects, at any depth. Zero
+// numbers, false and array elements are kept. With dropMeta, every "meta"
+// field is removed as well. Object keys come out sorted.
+func CompactJSON(v interface{}, dropMeta bool) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	// Numbers stay as written instead of round-tripping through float64
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var tree interface{}
+	if err := decoder.Decode(&tree); err != nil {
+		return nil, err
+	}
+
+	pruned, _ := pruneJSON(tree, dropMeta)
+	return json.Marshal(pruned)
+}
+
+// pruneJSON removes empty values from a decoded JSON tree, reporting whether
+// anything is left of the value itself
+func pruneJSON(v interface{}, dropMeta bool) (interface{}, bool) {
+	switch value := v.(type) {
+	case nil:
+		return nil, false
+	case string:
+		return value, value != ""
+	case []interface{}:
+		// Elements are kept so positions still line up
+		for i, elem := range value {
+			value[i], _ = pruneJSON(elem, dropMeta)
+		}
+		return value, len(value) > 0
+	case map[string]interface{}:
+		for key, elem := range value {
+			if dropMeta && key == "meta" {
+				delete(value, key)
+				continue
+			}
+			if elem, ok := pruneJSON(elem, dropMeta); ok {
+				value[key] = elem
+			} else {
+				delete(value, key)
+			}
+		}
+		return value, len(value) > 0
+	default:
+		return value, true
+	}
+}
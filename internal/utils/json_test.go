@@ -0,0 +1,70 @@
+package utils
+
+import "testing"
+
+func TestCompactJSON(t *testing.T) {
+	type sample struct {
+		Price float64           `json:"price"`
+		Note  string            `json:"note"`
+		Tags  []string          `json:"tags"`
+		Meta  map[string]string `json:"meta"`
+		Stock *bool             `json:"stock"`
+	}
+	inStock := false
+
+	tests := []struct {
+		name     string
+		value    interface{}
+		dropMeta bool
+		want     string
+	}{
+		{
+			name:  "empty fields dropped",
+			value: sample{Price: 19.99},
+			want:  `{"price":19.99}`,
+		},
+		{
+			name:  "zero numbers and false kept",
+			value: sample{Stock: &inStock},
+			want:  `{"price":0,"stock":false}`,
+		},
+		{
+			name:  "meta kept",
+			value: sample{Price: 5, Meta: map[string]string{"provider": "json", "seller": ""}},
+			want:  `{"meta":{"provider":"json"},"price":5}`,
+		},
+		{
+			name:     "meta dropped",
+			value:    sample{Price: 5, Meta: map[string]string{"provider": "json"}},
+			dropMeta: true,
+			want:     `{"price":5}`,
+		},
+		{
+			name:  "array positions kept",
+			value: []sample{{Price: 1}, {Note: ""}, {Price: 3}},
+			want:  `[{"price":1},{"price":0},{"price":3}]`,
+		},
+		{
+			name:  "empty strings in arrays kept",
+			value: sample{Tags: []string{"a", "", "b"}},
+			want:  `{"price":0,"tags":["a","","b"]}`,
+		},
+		{
+			name:  "large numbers as written",
+			value: map[string]int64{"id": 9007199254740993},
+			want:  `{"id":9007199254740993}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CompactJSON(tt.value, tt.dropMeta)
+			if err != nil {
+				t.Fatalf("CompactJSON: %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("CompactJSON = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
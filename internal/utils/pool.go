@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ForEach calls fn for every index in [0, n) on at most workers goroutines
+// and returns once all calls have finished. Indexes not yet started when
+// ctx is done are skipped. Each call gets its own timeout when timeout is
+// positive.
+func ForEach(ctx context.Context, n, workers int, timeout time.Duration, fn func(ctx context.Context, i int)) {
+	if workers < 1 {
+		workers = 1
+	}
+	if workers > n {
+		workers = n
+	}
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if timeout <= 0 {
+					fn(ctx, i)
+					continue
+				}
+				callCtx, cancel := context.WithTimeout(ctx, timeout)
+				fn(callCtx, i)
+				cancel()
+			}
+		}()
+	}
+
+feed:
+	for i := 0; i < n; i++ {
+		select {
+		case <-ctx.Done():
+			break feed
+		case indexes <- i:
+		}
+	}
+	close(indexes)
+	wg.Wait()
+}
+
+// ForEachLimited is ForEach with at most perKey calls running at once for
+// indexes that share key(i), such as items on the same host. Indexes whose
+// key is saturated wait while later ones with other keys take the free
+// workers, so one busy key doesn't hold up the rest. perKey < 1 means no
+// per-key limit.
+func ForEachLimited(ctx context.Context, n, workers, perKey int, key func(i int) string, timeout time.Duration, fn func(ctx context.Context, i int)) {
+	if perKey < 1 {
+		ForEach(ctx, n, workers, timeout, fn)
+		return
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	pending := make([]int, n)
+	for i := range pending {
+		pending[i] = i
+	}
+	active := make(map[string]int)
+	done := make(chan string)
+	running := 0
+
+	for {
+		// Indexes not yet started when ctx is done are skipped
+		if ctx.Err() != nil {
+			pending = nil
+		}
+
+		// Start every pending index that has both a worker and a key slot
+		waiting := pending[:0]
+		for _, i := range pending {
+			k := key(i)
+			if running >= workers || active[k] >= perKey {
+				waiting = append(waiting, i)
+				continue
+			}
+			active[k]++
+			running++
+			go func() {
+				callCtx, cancel := ctx, context.CancelFunc(func() {})
+				if timeout > 0 {
+					callCtx, cancel = context.WithTimeout(ctx, timeout)
+				}
+				fn(callCtx, i)
+				cancel()
+				done <- k
+			}()
+		}
+		pending = waiting
+
+		// With nothing running every pending index could have started
+		if running == 0 {
+			return
+		}
+		k := <-done
+		active[k]--
+		running--
+	}
+}
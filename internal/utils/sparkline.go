@@ -2,17 +2,26 @@ package utils
 
 import (
 	"fmt"
+	"math"
+	"strconv"
 	"strings"
 )
 
-// Sparkline characters for different price ranges
-const (
-	sparklineChars = "▁▂▃▄▅▆▇█"
+// Sparkline character sets, from the lowest price to the highest
+var (
+	SparklineBlocks = []rune("▁▂▃▄▅▆▇█")
+	SparklineASCII  = []rune("_.-:=+*#")
 )
 
 // GenerateSparkline creates a sparkline visualization from price data
 func GenerateSparkline(prices []float64, width int) string {
-	if len(prices) == 0 {
+	return GenerateSparklineChars(prices, width, SparklineBlocks)
+}
+
+// GenerateSparklineChars creates a sparkline drawn with chars, which run from
+// the lowest price to the highest
+func GenerateSparklineChars(prices []float64, width int, chars []rune) string {
+	if len(prices) == 0 || len(chars) == 0 {
 		return ""
 	}
 
@@ -20,10 +29,10 @@ func GenerateSparkline(prices []float64, width int) string {
 		width = len(prices)
 	}
 
-	// Normalize prices to 0-7 range for sparkline characters
+	// Normalize prices to the range of sparkline characters
 	min, max := findMinMax(prices)
 	if min == max {
-		return strings.Repeat("▁", width)
+		return strings.Repeat(string(chars[0]), width)
 	}
 
 	sparkline := make([]rune, 0, width)
@@ -36,15 +45,15 @@ func GenerateSparkline(prices []float64, width int) string {
 		}
 
 		normalized := (prices[index] - min) / (max - min)
-		charIndex := int(normalized * float64(len(sparklineChars) - 1))
+		charIndex := int(normalized * float64(len(chars)-1))
 		if charIndex < 0 {
 			charIndex = 0
 		}
-		if charIndex >= len(sparklineChars) {
-			charIndex = len(sparklineChars) - 1
+		if charIndex >= len(chars) {
+			charIndex = len(chars) - 1
 		}
 
-		sparkline = append(sparkline, rune(sparklineChars[charIndex]))
+		sparkline = append(sparkline, chars[charIndex])
 	}
 
 	return string(sparkline)
@@ -104,23 +113,97 @@ func GeneratePriceChart(prices []float64, width, height int) string {
 	return result.String()
 }
 
+// currencyDecimals holds user-configured display precision per currency code.
+var currencyDecimals = map[string]int{}
+
+// SetCurrencyDecimals overrides the number of decimals used when formatting
+// prices for the given currencies. Currencies not listed keep the defaults.
+func SetCurrencyDecimals(decimals map[string]int) {
+	currencyDecimals = make(map[string]int, len(decimals))
+	for currency, d := range decimals {
+		if d < 0 {
+			continue
+		}
+		currencyDecimals[strings.ToUpper(currency)] = d
+	}
+}
+
+// CurrencyDecimals returns the display precision for a currency: a configured
+// override if present, 0 for JPY and 2 for everything else.
+func CurrencyDecimals(currency string) int {
+	currency = strings.ToUpper(currency)
+	if d, ok := currencyDecimals[currency]; ok {
+		return d
+	}
+	if currency == "JPY" {
+		return 0
+	}
+	return 2
+}
+
+// RoundPrice rounds a price to the given number of decimals using half-up
+// rounding (ties round away from zero), e.g. 2.345 -> 2.35, -2.345 -> -2.35.
+// The price is rounded as written in decimal rather than as its binary
+// value, so 1.005, stored as 1.00499..., still rounds to 1.01.
+func RoundPrice(price float64, decimals int) float64 {
+	if decimals < 0 || math.IsNaN(price) || math.IsInf(price, 0) {
+		return price
+	}
+
+	// The shortest decimal that reads back as price
+	written := strconv.FormatFloat(math.Abs(price), 'f', -1, 64)
+	whole, fraction, _ := strings.Cut(written, ".")
+	if len(fraction) <= decimals {
+		return price
+	}
+
+	digits := []byte(whole + fraction[:decimals])
+	if fraction[decimals] >= '5' {
+		// Carry the rounding up through the kept digits
+		i := len(digits) - 1
+		for ; i >= 0 && digits[i] == '9'; i-- {
+			digits[i] = '0'
+		}
+		if i < 0 {
+			digits = append([]byte{'1'}, digits...)
+		} else {
+			digits[i]++
+		}
+	}
+
+	point := len(digits) - decimals
+	rounded, err := strconv.ParseFloat(string(digits[:point])+"."+string(digits[point:]), 64)
+	if err != nil {
+		return price
+	}
+	return math.Copysign(rounded, price)
+}
+
+// FormatAmount formats a price without a currency symbol, using the
+// currency's display precision.
+func FormatAmount(price float64, currency string) string {
+	decimals := CurrencyDecimals(currency)
+	return fmt.Sprintf("%.*f", decimals, RoundPrice(price, decimals))
+}
+
 // FormatPrice formats a price with currency symbol
 func FormatPrice(price float64, currency string) string {
+	amount := FormatAmount(price, currency)
 	switch currency {
 	case "USD":
-		return fmt.Sprintf("$%.2f", price)
+		return "$" + amount
 	case "EUR":
-		return fmt.Sprintf("€%.2f", price)
+		return "€" + amount
 	case "GBP":
-		return fmt.Sprintf("£%.2f", price)
+		return "£" + amount
 	case "TRY":
-		return fmt.Sprintf("₺%.2f", price)
+		return "₺" + amount
 	case "JPY":
-		return fmt.Sprintf("¥%.0f", price)
+		return "¥" + amount
 	case "INR":
-		return fmt.Sprintf("₹%.2f", price)
+		return "₹" + amount
 	default:
-		return fmt.Sprintf("%.2f %s", price, currency)
+		return fmt.Sprintf("%s %s", amount, currency)
 	}
 }
 
@@ -132,6 +215,25 @@ func CalculatePriceChange(oldPrice, newPrice float64) float64 {
 	return ((newPrice - oldPrice) / oldPrice) * 100
 }
 
+// CalculateStdDev returns the population standard deviation of prices
+func CalculateStdDev(prices []float64) float64 {
+	if len(prices) == 0 {
+		return 0
+	}
+
+	sum := 0.0
+	for _, price := range prices {
+		sum += price
+	}
+	mean := sum / float64(len(prices))
+
+	variance := 0.0
+	for _, price := range prices {
+		variance += (price - mean) * (price - mean)
+	}
+	return math.Sqrt(variance / float64(len(prices)))
+}
+
 // CalculateMovingAverage calculates moving average for a slice of prices
 func CalculateMovingAverage(prices []float64, window int) []float64 {
 	if len(prices) < window || window <= 0 {
@@ -0,0 +1,56 @@
+package utils
+
+import "testing"
+
+func TestRoundPrice(t *testing.T) {
+	tests := []struct {
+		price    float64
+		decimals int
+		want     float64
+	}{
+		{1.005, 2, 1.01},
+		{-1.005, 2, -1.01},
+		{2.345, 2, 2.35},
+		{-2.345, 2, -2.35},
+		{1.004, 2, 1},
+		{0.125, 2, 0.13},
+		{9.995, 2, 10},
+		{99.5, 0, 100},
+		{1234.5, 0, 1235},
+		{0.123456785, 8, 0.12345679},
+		{19.99, 2, 19.99},
+		{19.99, 4, 19.99},
+		{3.7, -1, 3.7},
+	}
+
+	for _, tt := range tests {
+		if got := RoundPrice(tt.price, tt.decimals); got != tt.want {
+			t.Errorf("RoundPrice(%v, %d) = %v, want %v", tt.price, tt.decimals, got, tt.want)
+		}
+	}
+}
+
+func TestCurrencyDecimals(t *testing.T) {
+	SetCurrencyDecimals(map[string]int{"btc": 8, "KWD": 3})
+	defer SetCurrencyDecimals(nil)
+
+	tests := []struct {
+		currency string
+		want     int
+	}{
+		{"USD", 2},
+		{"usd", 2},
+		{"JPY", 0},
+		{"jpy", 0},
+		{"BTC", 8},
+		{"btc", 8},
+		{"kwd", 3},
+		{"", 2},
+	}
+
+	for _, tt := range tests {
+		if got := CurrencyDecimals(tt.currency); got != tt.want {
+			t.Errorf("CurrencyDecimals(%q) = %d, want %d", tt.currency, got, tt.want)
+		}
+	}
+}
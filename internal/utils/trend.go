@@ -0,0 +1,30 @@
+package utils
+
+// LinearTrend fits y = intercept + slope*x by least squares. It returns
+// ok=false with fewer than two points or when all x values are equal.
+func LinearTrend(xs, ys []float64) (slope, intercept float64, ok bool) {
+	n := float64(len(xs))
+	if len(xs) < 2 || len(xs) != len(ys) {
+		return 0, 0, false
+	}
+
+	var sumX, sumY float64
+	for i := range xs {
+		sumX += xs[i]
+		sumY += ys[i]
+	}
+	meanX, meanY := sumX/n, sumY/n
+
+	var sxx, sxy float64
+	for i := range xs {
+		dx := xs[i] - meanX
+		sxx += dx * dx
+		sxy += dx * (ys[i] - meanY)
+	}
+	if sxx == 0 {
+		return 0, 0, false
+	}
+
+	slope = sxy / sxx
+	return slope, meanY - slope*meanX, true
+}
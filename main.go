@@ -2,32 +2,61 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"os/signal"
+	"syscall"
 
+	"github.com/makalin/pricetrek/internal/buildinfo"
 	"github.com/makalin/pricetrek/internal/cli"
 	"github.com/makalin/pricetrek/internal/config"
+	"github.com/makalin/pricetrek/internal/httpclient"
 	"github.com/makalin/pricetrek/internal/logger"
+	"github.com/makalin/pricetrek/internal/utils"
 )
 
-const version = "0.1.0"
-
 func main() {
 	var (
 		configPath = flag.String("config", "pricetrek.yaml", "Path to configuration file")
 		verbose    = flag.Bool("verbose", false, "Enable verbose logging")
 		versionFlag = flag.Bool("version", false, "Show version information")
+		debugHTTP   = flag.Bool("debug-http", false, "Log HTTP requests and responses (implies --verbose)")
+		dumpDir     = flag.String("debug-http-dump", "", "Dump HTTP response bodies into this directory (implies --debug-http)")
+		forceLock   = flag.Bool("force-lock", false, "Run write commands even if another instance holds the lock")
+		noColor     = flag.Bool("no-color", false, "Disable colored output (also NO_COLOR)")
+		dataDir     = flag.String("data-dir", "", "Directory for the database, backups and default config (default $"+config.DataDirEnv+", then XDG dirs on Linux)")
 	)
 	flag.Parse()
 
+	utils.SetColor(utils.ShouldColor(*noColor, os.Stdout))
+
+	// The data directory supplies the config location unless --config is given
+	paths := config.ResolvePaths(*dataDir)
+	configSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			configSet = true
+		}
+	})
+	if configSet {
+		paths.Config = *configPath
+	} else {
+		*configPath = paths.Config
+	}
+	config.SetDefaultStoragePath(paths.DB)
+
 	if *versionFlag {
-		fmt.Printf("PriceTrek v%s\n", version)
+		fmt.Println(buildinfo.Get())
 		os.Exit(0)
 	}
 
 	// Initialize logger
-	log := logger.New(*verbose)
+	if *dumpDir != "" {
+		*debugHTTP = true
+	}
+	log := logger.New(*verbose || *debugHTTP)
 
 	// Parse command line arguments
 	args := flag.Args()
@@ -38,9 +67,10 @@ func main() {
 		os.Exit(1)
 	}
 
-	// Handle init command without requiring config
-	if args[0] == "init" {
+	// Handle init and version commands without requiring config
+	if args[0] == "init" || args[0] == "version" {
 		cli := cli.New(nil, log)
+		cli.SetPaths(paths)
 		ctx := context.Background()
 		if err := cli.Execute(ctx, args); err != nil {
 			log.Error("Command failed", "error", err)
@@ -52,16 +82,51 @@ func main() {
 	// Load configuration for other commands
 	cfg, err := config.Load(*configPath)
 	if err != nil {
-		log.Fatal("Failed to load configuration", "error", err)
+		if cli.RequiresConfig(args) {
+			log.Fatal("Failed to load configuration", "error", err)
+		}
+		// fetch and quick-track work without a config file, using built-in defaults
+		cfg = config.Default()
+	}
+	if err := httpclient.Configure(httpclient.Options{
+		Timeout:      cfg.Defaults.HTTPTimeout,
+		UserAgent:    cfg.Defaults.UserAgent,
+		Proxy:        cfg.Defaults.Proxy,
+		MaxRedirects: cfg.Defaults.MaxRedirects,
+		TLS: httpclient.TLSOptions{
+			InsecureSkipVerify: cfg.Defaults.TLS.InsecureSkipVerify,
+			CAFile:             cfg.Defaults.TLS.CAFile,
+		},
+		Logger:       log,
+		Debug:        *debugHTTP,
+		DumpDir:      *dumpDir,
+		Timing:       *verbose || *debugHTTP || cfg.Defaults.StoreHTTPTiming,
+	}); err != nil {
+		log.Fatal("Failed to configure HTTP client", "error", err)
 	}
 
 	// Create CLI instance
 	cli := cli.New(cfg, log)
+	cli.SetConfigPath(*configPath)
+	cli.SetPaths(paths)
+	cli.SetForceLock(*forceLock)
 
-	// Execute command
-	ctx := context.Background()
+	// Execute command; SIGINT/SIGTERM cancel the context so deferred
+	// cleanup (instance lock, storage) still runs
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 	if err := cli.Execute(ctx, args); err != nil {
+		if errors.Is(err, context.Canceled) {
+			return
+		}
+		// Commands that already reported their outcome just set the status
+		var exit interface{ ExitCode() int }
+		if errors.As(err, &exit) {
+			stop()
+			os.Exit(exit.ExitCode())
+		}
 		log.Error("Command failed", "error", err)
+		stop()
 		os.Exit(1)
 	}
 }
\ No newline at end of file